@@ -1,14 +1,22 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io/fs"
+	"maps"
 	"os"
-	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Bparsons0904/ripper/internal/config"
 	"github.com/Bparsons0904/ripper/internal/drives"
+	"github.com/Bparsons0904/ripper/internal/naming"
 	"github.com/Bparsons0904/ripper/internal/ripper"
+	"github.com/Bparsons0904/ripper/internal/sanitize"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -90,8 +98,13 @@ type rippingCompleteMsg struct {
 }
 
 type metadataLookupMsg struct {
-	success bool
-	error   error
+	candidates []ripper.MetadataCandidate
+	err        error
+}
+
+type duplicateCheckMsg struct {
+	existing []ripper.ExistingRip
+	err      error
 }
 
 type Screen int
@@ -100,6 +113,9 @@ const (
 	WelcomeScreen Screen = iota
 	CDRippingScreen
 	RippingSuccessScreen
+	MetadataPickerScreen
+	MetadataEditorScreen
+	DuplicateScreen
 	SettingsMenuScreen
 	DrivesSettingsScreen
 	PathsSettingsScreen
@@ -115,19 +131,27 @@ type model struct {
 	selectedItem    int
 	isEditing       bool
 	editValue       string
+	settingsError   string // Why the last settings change was refused
 	availableDrives []drives.DriveInfo
 	isRipping       bool
 	rippingProgress int
 	rippingStatus   string
+	rippingTrack    ripper.ProgressInfo // Latest update, for per-track detail
+	readWarnings    []string            // Suspicious sectors found by secure mode
 	cdRipper        *ripper.CDRipper
 	cdInfo          *ripper.CDInfo
 	spinnerFrame    int
-	abcdeCmd        *exec.Cmd
+
+	// Metadata candidates awaiting a choice on the picker screen
+	metadataCandidates []ripper.MetadataCandidate
+
+	// Rips of the disc already in the library, awaiting a choice
+	existingRips []ripper.ExistingRip
 	
 	// Success screen data
 	lastRipSuccess  bool
 	lastRipError    error
-	lastRippedCD    *ripper.CDInfo // Track the running command
+	lastRippedCD    *ripper.CDInfo
 }
 
 func initialModel() model {
@@ -138,16 +162,16 @@ func initialModel() model {
 		cfg = config.DefaultConfig() // fallback to defaults
 	}
 
+	// Initialize CD ripper
+	cdRipper := ripper.NewCDRipper(cfg)
+
 	// Detect available drives
-	availableDrives, err := drives.DetectDrives()
+	availableDrives, err := cdRipper.ScanDrives()
 	if err != nil {
 		fmt.Printf("Warning: Could not detect drives: %v\n", err)
 		availableDrives = []drives.DriveInfo{}
 	}
 
-	// Initialize CD ripper
-	cdRipper := ripper.NewCDRipper(cfg)
-
 	return model{
 		ready:           true,
 		config:          cfg,
@@ -162,7 +186,6 @@ func initialModel() model {
 		cdRipper:        cdRipper,
 		cdInfo:          nil,
 		spinnerFrame:    0,
-		abcdeCmd:        nil,
 	}
 }
 
@@ -180,24 +203,23 @@ func detectCDCmd(cdRipper *ripper.CDRipper) tea.Cmd {
 
 func metadataLookupCmd(cdRipper *ripper.CDRipper, cdInfo *ripper.CDInfo) tea.Cmd {
 	return tea.Cmd(func() tea.Msg {
-		err := cdRipper.LookupMetadata(cdInfo)
-		return metadataLookupMsg{success: err == nil, error: err}
+		candidates, err := cdRipper.LookupCandidates(cdInfo)
+		return metadataLookupMsg{candidates: candidates, err: err}
+	})
+}
+
+func duplicateCheckCmd(cdRipper *ripper.CDRipper, cdInfo *ripper.CDInfo) tea.Cmd {
+	return tea.Cmd(func() tea.Msg {
+		existing, err := cdRipper.FindExistingRips(cdInfo)
+		return duplicateCheckMsg{existing: existing, err: err}
 	})
 }
 
 func startRippingCmd(cdRipper *ripper.CDRipper, cdInfo *ripper.CDInfo) tea.Cmd {
 	return tea.Cmd(func() tea.Msg {
-		go func() {
-			fmt.Printf("DEBUG: RipCD goroutine started\n")
-			if err := cdRipper.RipCD(cdInfo); err != nil {
-				fmt.Printf("DEBUG: RipCD failed: %v\n", err)
-			}
-		}()
-		// Return a message to indicate ripping started
-		return rippingProgressMsg(ripper.ProgressInfo{
-			Status:   "Ripping started...",
-			Progress: 0,
-		})
+		// Runs until the rip finishes; progress arrives on the progress channel
+		err := cdRipper.RipCD(cdInfo)
+		return rippingCompleteMsg{success: err == nil, error: err}
 	})
 }
 
@@ -214,27 +236,6 @@ func spinnerCmd() tea.Cmd {
 	})
 }
 
-func rippingCmd(cmd *exec.Cmd, musicPath string) tea.Cmd {
-	return func() tea.Msg {
-		// Create output directory if it doesn't exist
-		if err := os.MkdirAll(musicPath, 0755); err != nil {
-			return rippingCompleteMsg{success: false, error: err}
-		}
-
-		// Clean up any previous abcde working directories to avoid version conflicts
-		cleanupCmd := exec.Command("sh", "-c", fmt.Sprintf("rm -rf '%s'/abcde.* 2>/dev/null || true", musicPath))
-		cleanupCmd.Run()
-
-		// Run abcde and capture result
-		_, err := cmd.CombinedOutput()
-		if err != nil {
-			return rippingCompleteMsg{success: false, error: err}
-		}
-		
-		return rippingCompleteMsg{success: true, error: nil}
-	}
-}
-
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case spinnerTickMsg:
@@ -244,8 +245,11 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 	case rippingCompleteMsg:
+		if !m.isRipping {
+			// The rip was cancelled from the UI, which already moved on
+			return m, nil
+		}
 		m.isRipping = false
-		m.abcdeCmd = nil
 		
 		// Store completion details for success screen
 		m.lastRipSuccess = msg.success
@@ -257,11 +261,42 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.selectedItem = 0
 		return m, nil
 	case metadataLookupMsg:
-		if msg.success {
-			m.rippingStatus = "✅ Metadata lookup completed!"
+		if msg.err != nil {
+			m.rippingStatus = fmt.Sprintf("❌ Metadata lookup failed: %v", msg.err)
+			return m, nil
+		}
+		if m.cdInfo == nil || len(msg.candidates) == 0 {
+			return m, nil
+		}
+		// Ask the user to pick unless there's only one match or a remembered choice
+		if len(msg.candidates) > 1 && !msg.candidates[0].Remembered {
+			m.metadataCandidates = msg.candidates
+			m.currentScreen = MetadataPickerScreen
+			m.selectedItem = 0
+			m.rippingStatus = ""
+			return m, nil
+		}
+		if err := msg.candidates[0].ApplyTo(m.cdInfo); err != nil {
+			m.rippingStatus = fmt.Sprintf("❌ Metadata lookup failed: %v", err)
 		} else {
-			m.rippingStatus = fmt.Sprintf("❌ Metadata lookup failed: %v", msg.error)
+			m.rippingStatus = "✅ Metadata lookup completed!"
+		}
+		return m, nil
+	case duplicateCheckMsg:
+		if m.cdInfo == nil || m.currentScreen != CDRippingScreen || m.isRipping {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.rippingStatus = fmt.Sprintf("❌ Library check failed: %v", msg.err)
+			return m, nil
 		}
+		if len(msg.existing) == 0 {
+			return m.startRipping()
+		}
+		m.existingRips = msg.existing
+		m.currentScreen = DuplicateScreen
+		m.selectedItem = 0
+		m.rippingStatus = ""
 		return m, nil
 	case cdDetectedMsg:
 		if msg.err != nil {
@@ -270,19 +305,24 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		} else {
 			m.cdInfo = msg.cdInfo
 			m.rippingStatus = "" // Clear status once CD is detected successfully
+			if m.config.CDRipping.CDDBMethod != "none" {
+				m.rippingStatus = "🔍 Looking up metadata..."
+				return m, metadataLookupCmd(m.cdRipper, m.cdInfo)
+			}
 		}
 		return m, nil
 	case rippingProgressMsg:
 		progress := ripper.ProgressInfo(msg)
 		m.rippingProgress = progress.Progress
 		m.rippingStatus = progress.Status
-		if progress.Error != nil {
-			m.isRipping = false
-			m.rippingStatus = fmt.Sprintf("Error: %v", progress.Error)
-			return m, nil
+		m.rippingTrack = progress
+		// Updates are dropped when the screen falls behind, so these are a live
+		// preview; the summary lists every track from the disc's SecureReads
+		if progress.SecureRead != nil && !progress.SecureRead.Secure() {
+			m.readWarnings = append(m.readWarnings, progress.SecureRead.Warning())
 		}
-		if progress.Progress >= 100 {
-			m.isRipping = false
+		// The outcome arrives as rippingCompleteMsg, stop listening here
+		if progress.Error != nil || progress.Progress >= 100 {
 			return m, nil
 		}
 		// Only continue listening for progress if we're actually ripping
@@ -298,6 +338,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.updateCDRipping(msg)
 		case RippingSuccessScreen:
 			return m.updateRippingSuccess(msg)
+		case MetadataPickerScreen:
+			return m.updateMetadataPicker(msg)
+		case MetadataEditorScreen:
+			return m.updateMetadataEditor(msg)
+		case DuplicateScreen:
+			return m.updateDuplicate(msg)
 		case SettingsMenuScreen:
 			return m.updateSettingsMenu(msg)
 		case DrivesSettingsScreen:
@@ -393,14 +439,7 @@ func (m model) updateUISettings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m model) updateCDRippingSettings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	cdFields := []string{
-		"Retry Count",
-		"Retry Delay (sec)",
-		"Initial Wait (sec)",
-		"Auto Eject",
-		"Output Format",
-		"CDDB Method",
-	}
+	cdFields := m.cdRippingFields()
 
 	if m.isEditing {
 		// Handle editing mode
@@ -420,13 +459,21 @@ func (m model) updateCDRippingSettings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				if val := parseInt(m.editValue); val >= 0 && val <= 120 {
 					m.config.CDRipping.InitialWait = val
 				}
+			case 7: // Encoder Workers
+				if val := parseInt(m.editValue); val >= 0 && val <= 64 {
+					m.config.CDRipping.EncoderWorkers = val
+				}
+			case 10: // Secure Re-reads
+				if val := parseInt(m.editValue); val >= 1 && val <= config.MaxSecureRereads {
+					m.config.CDRipping.Secure.MaxRereads = val
+				}
 			case 4: // Output Format
-				validFormats := []string{"flac", "mp3", "ogg", "wav"}
-				for _, format := range validFormats {
-					if m.editValue == format {
-						m.config.CDRipping.OutputFormat = format
-						break
+				if formats, ok := parseOutputFormats(m.editValue); ok {
+					if !m.applyCDRippingEdit(func(cfg *config.Config) { cfg.CDRipping.OutputFormat = formats }) {
+						return m, nil
 					}
+					// The per-format fields follow the list
+					m.selectedItem = min(m.selectedItem, len(m.cdRippingFields())-1)
 				}
 			case 5: // CDDB Method
 				validMethods := []string{"musicbrainz", "cddb", "none"}
@@ -436,6 +483,13 @@ func (m model) updateCDRippingSettings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 						break
 					}
 				}
+			default: // Per-format output and encoder settings
+				if setting, ok := m.outputField(m.selectedItem); ok {
+					value := strings.TrimSpace(m.editValue)
+					if !m.applyCDRippingEdit(func(cfg *config.Config) { setting.set(cfg, value) }) {
+						return m, nil
+					}
+				}
 			}
 			// Save config to file
 			if err := m.config.Save(config.GetConfigPath()); err != nil {
@@ -448,10 +502,11 @@ func (m model) updateCDRippingSettings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			// Cancel editing
 			m.isEditing = false
 			m.editValue = ""
+			m.settingsError = ""
 			return m, nil
 		default:
 			// Handle text input for editable fields
-			if m.selectedItem != 3 { // Skip auto eject (it's toggled, not typed)
+			if m.selectedItem != 3 && m.selectedItem != 9 && m.selectedItem != 12 && m.selectedItem != 14 { // Skip toggles, they aren't typed
 				if msg.String() == "backspace" {
 					if len(m.editValue) > 0 {
 						m.editValue = m.editValue[:len(m.editValue)-1]
@@ -468,6 +523,7 @@ func (m model) updateCDRippingSettings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		switch msg.String() {
 		case "q", "esc":
 			m.currentScreen = SettingsMenuScreen
+			m.settingsError = ""
 			return m, nil
 		case "up", "k":
 			if m.selectedItem > 0 {
@@ -480,6 +536,7 @@ func (m model) updateCDRippingSettings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			}
 			return m, nil
 		case "enter", " ":
+			m.settingsError = ""
 			if m.selectedItem == 3 { // Auto Eject - toggle boolean
 				m.config.CDRipping.AutoEject = !m.config.CDRipping.AutoEject
 				// Save config immediately for toggles
@@ -487,40 +544,90 @@ func (m model) updateCDRippingSettings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 					fmt.Printf("Error saving config: %v\n", err)
 				}
 				return m, nil
-			} else if m.selectedItem == 4 { // Output Format - cycle through options
-				formats := []string{"flac", "mp3", "ogg", "wav"}
+			} else if m.selectedItem == 5 { // CDDB Method - cycle through options
+				methods := []string{"musicbrainz", "cddb", "none"}
 				currentIndex := -1
-				for i, format := range formats {
-					if m.config.CDRipping.OutputFormat == format {
+				for i, method := range methods {
+					if m.config.CDRipping.CDDBMethod == method {
 						currentIndex = i
 						break
 					}
 				}
-				nextIndex := (currentIndex + 1) % len(formats)
-				m.config.CDRipping.OutputFormat = formats[nextIndex]
+				nextIndex := (currentIndex + 1) % len(methods)
+				m.config.CDRipping.CDDBMethod = methods[nextIndex]
 				// Save config immediately
 				if err := m.config.Save(config.GetConfigPath()); err != nil {
 					fmt.Printf("Error saving config: %v\n", err)
 				}
 				return m, nil
-			} else if m.selectedItem == 5 { // CDDB Method - cycle through options
-				methods := []string{"musicbrainz", "cddb", "none"}
+			} else if m.selectedItem == 6 { // Extraction Backend - cycle through options
+				backends := []string{"abcde", "cdparanoia"}
 				currentIndex := -1
-				for i, method := range methods {
-					if m.config.CDRipping.CDDBMethod == method {
+				for i, backend := range backends {
+					if m.config.CDRipping.ExtractionBackend == backend {
 						currentIndex = i
 						break
 					}
 				}
-				nextIndex := (currentIndex + 1) % len(methods)
-				m.config.CDRipping.CDDBMethod = methods[nextIndex]
+				nextIndex := (currentIndex + 1) % len(backends)
+				if !m.applyCDRippingEdit(func(cfg *config.Config) { cfg.CDRipping.ExtractionBackend = backends[nextIndex] }) {
+					return m, nil
+				}
+				// Save config immediately
+				if err := m.config.Save(config.GetConfigPath()); err != nil {
+					fmt.Printf("Error saving config: %v\n", err)
+				}
+				return m, nil
+			} else if m.selectedItem == 8 { // On Duplicate - cycle through options
+				currentIndex := slices.Index(config.DuplicateActions, m.config.CDRipping.OnDuplicate)
+				nextIndex := (currentIndex + 1) % len(config.DuplicateActions)
+				m.config.CDRipping.OnDuplicate = config.DuplicateActions[nextIndex]
+				// Save config immediately
+				if err := m.config.Save(config.GetConfigPath()); err != nil {
+					fmt.Printf("Error saving config: %v\n", err)
+				}
+				return m, nil
+			} else if m.selectedItem == 9 { // Secure Rip - toggle boolean
+				m.config.CDRipping.Secure.Enabled = !m.config.CDRipping.Secure.Enabled
+				// Save config immediately for toggles
+				if err := m.config.Save(config.GetConfigPath()); err != nil {
+					fmt.Printf("Error saving config: %v\n", err)
+				}
+				return m, nil
+			} else if m.selectedItem == 11 { // Gap Policy - cycle through options
+				currentIndex := slices.Index(config.GapPolicies, m.config.CDRipping.Gaps.Policy)
+				nextIndex := (currentIndex + 1) % len(config.GapPolicies)
+				m.config.CDRipping.Gaps.Policy = config.GapPolicies[nextIndex]
+				// Save config immediately
+				if err := m.config.Save(config.GetConfigPath()); err != nil {
+					fmt.Printf("Error saving config: %v\n", err)
+				}
+				return m, nil
+			} else if m.selectedItem == 12 { // Detect Gaps - toggle boolean
+				m.config.CDRipping.Gaps.Detect = !m.config.CDRipping.Gaps.Detect
+				// Save config immediately for toggles
+				if err := m.config.Save(config.GetConfigPath()); err != nil {
+					fmt.Printf("Error saving config: %v\n", err)
+				}
+				return m, nil
+			} else if m.selectedItem == 13 { // Rip Mode - cycle through options
+				currentIndex := slices.Index(config.RipModes, m.config.CDRipping.RipMode)
+				nextIndex := (currentIndex + 1) % len(config.RipModes)
+				m.config.CDRipping.RipMode = config.RipModes[nextIndex]
 				// Save config immediately
 				if err := m.config.Save(config.GetConfigPath()); err != nil {
 					fmt.Printf("Error saving config: %v\n", err)
 				}
 				return m, nil
+			} else if m.selectedItem == 14 { // Embed Cue - toggle boolean
+				m.config.CDRipping.Image.EmbedCue = !m.config.CDRipping.Image.EmbedCue
+				// Save config immediately for toggles
+				if err := m.config.Save(config.GetConfigPath()); err != nil {
+					fmt.Printf("Error saving config: %v\n", err)
+				}
+				return m, nil
 			} else {
-				// Start editing the selected field (numeric fields only)
+				// Start editing the selected field
 				m.isEditing = true
 				// Set current value as edit value
 				switch m.selectedItem {
@@ -530,6 +637,16 @@ func (m model) updateCDRippingSettings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 					m.editValue = fmt.Sprintf("%d", m.config.CDRipping.RetryDelay)
 				case 2:
 					m.editValue = fmt.Sprintf("%d", m.config.CDRipping.InitialWait)
+				case 4:
+					m.editValue = strings.Join(m.config.CDRipping.OutputFormat, ",")
+				case 7:
+					m.editValue = fmt.Sprintf("%d", m.config.CDRipping.EncoderWorkers)
+				case 10:
+					m.editValue = fmt.Sprintf("%d", m.config.CDRipping.Secure.MaxRereads)
+				default:
+					if setting, ok := m.outputField(m.selectedItem); ok {
+						m.editValue = setting.value(m.config)
+					}
 				}
 				return m, nil
 			}
@@ -538,6 +655,204 @@ func (m model) updateCDRippingSettings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// cdRippingFields returns the labels of the CD ripping settings. Each output
+// format adds its root and encoder settings after the fixed ones.
+func (m model) cdRippingFields() []string {
+	fields := []string{
+		"Retry Count",
+		"Retry Delay (sec)",
+		"Initial Wait (sec)",
+		"Auto Eject",
+		"Output Format",
+		"CDDB Method",
+		"Extraction Backend",
+		"Encoder Workers",
+		"On Duplicate",
+		"Secure Rip",
+		"Secure Re-reads",
+		"Gap Policy",
+		"Detect Gaps",
+		"Rip Mode",
+		"Embed Cue",
+	}
+	for _, format := range m.config.CDRipping.OutputFormat {
+		for _, setting := range outputSettings(format) {
+			fields = append(fields, setting.label)
+		}
+	}
+	return fields
+}
+
+// cdRippingFixedFields is the number of CD ripping settings before the
+// per-format output fields
+const cdRippingFixedFields = 15
+
+// outputField returns the per-format setting shown at a CD ripping settings
+// index
+func (m model) outputField(index int) (outputSetting, bool) {
+	offset := index - cdRippingFixedFields
+	if offset < 0 {
+		return outputSetting{}, false
+	}
+	for _, format := range m.config.CDRipping.OutputFormat {
+		settings := outputSettings(format)
+		if offset < len(settings) {
+			return settings[offset], true
+		}
+		offset -= len(settings)
+	}
+	return outputSetting{}, false
+}
+
+// applyCDRippingEdit applies edit to the CD ripping settings unless the
+// result fails validation, in which case the settings are left as they were
+// and the validation message is shown instead. Saving an invalid setting
+// would stop the configuration from loading again.
+func (m *model) applyCDRippingEdit(edit func(cfg *config.Config)) bool {
+	previous := m.config.CDRipping
+	edit(m.config)
+	if err := m.config.ValidateCDRipping(); err != nil {
+		m.config.CDRipping = previous
+		m.settingsError = err.Error()
+		return false
+	}
+	m.settingsError = ""
+	return true
+}
+
+// outputSetting is a per-format field on the CD ripping settings screen
+type outputSetting struct {
+	label string
+	hint  string
+	value func(cfg *config.Config) string
+	// set stores an edited value, ignoring invalid input
+	set func(cfg *config.Config, value string)
+}
+
+// outputSettings returns the fields of an output format: its root, then
+// the options of its encoder profile
+func outputSettings(format string) []outputSetting {
+	name := strings.ToUpper(format)
+	settings := []outputSetting{{
+		label: name + " Root",
+		hint:  "empty for the music directory",
+		value: func(cfg *config.Config) string { return cfg.CDRipping.Outputs[format].Root },
+		set: func(cfg *config.Config, value string) {
+			// Copied so a refused edit leaves the live settings alone
+			outputs := maps.Clone(cfg.CDRipping.Outputs)
+			if outputs == nil {
+				outputs = map[string]config.OutputSettings{}
+			}
+			output := outputs[format]
+			output.Root = value
+			outputs[format] = output
+			cfg.CDRipping.Outputs = outputs
+		},
+	}}
+
+	switch format {
+	case "flac":
+		settings = append(settings, intSetting(name+" Compression Level", 0, 8,
+			func(cfg *config.Config) *int { return &cfg.CDRipping.Encoders.FLAC.CompressionLevel }))
+	case "mp3":
+		settings = append(settings,
+			choiceSetting(name+" Mode", []string{"vbr", "cbr"},
+				func(cfg *config.Config) *string { return &cfg.CDRipping.Encoders.MP3.Mode }),
+			intSetting(name+" VBR Quality", 0, 9,
+				func(cfg *config.Config) *int { return &cfg.CDRipping.Encoders.MP3.VBRQuality }),
+			outputSetting{
+				label: name + " CBR Bitrate (kbps)",
+				hint:  strings.Trim(fmt.Sprint(config.LAMEBitrates), "[]"),
+				value: func(cfg *config.Config) string {
+					return strconv.Itoa(cfg.CDRipping.Encoders.MP3.Bitrate)
+				},
+				set: func(cfg *config.Config, value string) {
+					if bitrate := parseInt(value); slices.Contains(config.LAMEBitrates, bitrate) {
+						cfg.CDRipping.Encoders.MP3.Bitrate = bitrate
+					}
+				},
+			},
+		)
+	case "ogg":
+		settings = append(settings, outputSetting{
+			label: name + " Quality",
+			hint:  "-1 to 10",
+			value: func(cfg *config.Config) string {
+				return strconv.FormatFloat(cfg.CDRipping.Encoders.Ogg.Quality, 'f', -1, 64)
+			},
+			set: func(cfg *config.Config, value string) {
+				if quality, err := strconv.ParseFloat(value, 64); err == nil && quality >= -1 && quality <= 10 {
+					cfg.CDRipping.Encoders.Ogg.Quality = quality
+				}
+			},
+		})
+	case "opus":
+		settings = append(settings, intSetting(name+" Bitrate (kbps)", 6, 256,
+			func(cfg *config.Config) *int { return &cfg.CDRipping.Encoders.Opus.Bitrate }))
+	case "m4a":
+		settings = append(settings, intSetting(name+" Bitrate (kbps)", 32, 320,
+			func(cfg *config.Config) *int { return &cfg.CDRipping.Encoders.M4A.Bitrate }))
+	case "wavpack":
+		settings = append(settings, choiceSetting(name+" Mode", config.WavPackModes,
+			func(cfg *config.Config) *string { return &cfg.CDRipping.Encoders.WavPack.Mode }))
+	}
+	return settings
+}
+
+// intSetting is an output setting holding a whole number between min and max
+func intSetting(label string, min, max int, field func(cfg *config.Config) *int) outputSetting {
+	return outputSetting{
+		label: label,
+		hint:  fmt.Sprintf("%d-%d", min, max),
+		value: func(cfg *config.Config) string { return strconv.Itoa(*field(cfg)) },
+		set: func(cfg *config.Config, value string) {
+			if val := parseInt(value); val >= min && val <= max {
+				*field(cfg) = val
+			}
+		},
+	}
+}
+
+// choiceSetting is an output setting holding one of choices
+func choiceSetting(label string, choices []string, field func(cfg *config.Config) *string) outputSetting {
+	return outputSetting{
+		label: label,
+		hint:  strings.Join(choices, ", "),
+		value: func(cfg *config.Config) string { return *field(cfg) },
+		set: func(cfg *config.Config, value string) {
+			if slices.Contains(choices, value) {
+				*field(cfg) = value
+			}
+		},
+	}
+}
+
+// parseOutputFormats parses a comma-separated list of output formats,
+// rejecting unknown and repeated formats
+func parseOutputFormats(value string) ([]string, bool) {
+	var formats []string
+	seen := map[string]bool{}
+	for _, format := range strings.Split(value, ",") {
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format == "" {
+			continue
+		}
+		valid := false
+		for _, known := range config.OutputFormats {
+			if format == known {
+				valid = true
+				break
+			}
+		}
+		if !valid || seen[format] {
+			return nil, false
+		}
+		seen[format] = true
+		formats = append(formats, format)
+	}
+	return formats, len(formats) > 0
+}
+
 // Helper function to parse integers safely
 func parseInt(s string) int {
 	val := 0
@@ -552,7 +867,7 @@ func parseInt(s string) int {
 }
 
 func (m model) updateToolsSettings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	toolsFields := []string{"ABCDE Path", "cd-discid Path", "MakeMKV Path"}
+	toolsFields := []string{"ABCDE Path", "cd-discid Path", "cdparanoia Path", "MakeMKV Path"}
 
 	if m.isEditing {
 		// Handle editing mode
@@ -565,6 +880,8 @@ func (m model) updateToolsSettings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			case 1:
 				m.config.Tools.CDDiscidPath = m.editValue
 			case 2:
+				m.config.Tools.CDParanoiaPath = m.editValue
+			case 3:
 				m.config.Tools.MakeMKVPath = m.editValue
 			}
 			// Save config to file
@@ -617,6 +934,8 @@ func (m model) updateToolsSettings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			case 1:
 				m.editValue = m.config.Tools.CDDiscidPath
 			case 2:
+				m.editValue = m.config.Tools.CDParanoiaPath
+			case 3:
 				m.editValue = m.config.Tools.MakeMKVPath
 			}
 			return m, nil
@@ -626,7 +945,19 @@ func (m model) updateToolsSettings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m model) updatePathsSettings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	pathsFields := []string{"Music Directory", "Movies Directory", "Config Directory", "Log File"}
+	pathsFields := []string{
+		"Music Directory",
+		"Movies Directory",
+		"Config Directory",
+		"Log File",
+		"Work Directory",
+		"Track Template",
+		"Compilation Template",
+		"Multi-Disc Template",
+		"Replace Spaces",
+		"Sanitize Profile",
+		"Max Path Length",
+	}
 
 	if m.isEditing {
 		// Handle editing mode
@@ -642,6 +973,26 @@ func (m model) updatePathsSettings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				m.config.Paths.Config = m.editValue
 			case 3:
 				m.config.Paths.LogFile = m.editValue
+			case 4:
+				m.config.Paths.Work = m.editValue
+			case 5, 6, 7:
+				// Keep editing until the template is usable; the preview shows why not
+				kind, _ := pathsTemplateKind(m.selectedItem)
+				if naming.CheckTemplate(kind, m.editValue, m.config.Paths.SanitizePolicy()) != nil {
+					return m, nil
+				}
+				switch kind {
+				case naming.Track:
+					m.config.Paths.TrackTemplate = m.editValue
+				case naming.Compilation:
+					m.config.Paths.CompilationTemplate = m.editValue
+				case naming.MultiDisc:
+					m.config.Paths.MultiDiscTemplate = m.editValue
+				}
+			case 10: // Max Path Length
+				if val := parseInt(m.editValue); val == 0 || val >= config.MinPathLength {
+					m.config.Paths.MaxPathLength = val
+				}
 			}
 			// Save config to file
 			if err := m.config.Save(config.GetConfigPath()); err != nil {
@@ -684,6 +1035,22 @@ func (m model) updatePathsSettings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			}
 			return m, nil
 		case "enter":
+			if m.selectedItem == 8 { // Replace Spaces - toggle boolean
+				m.config.Paths.ReplaceSpaces = !m.config.Paths.ReplaceSpaces
+				if err := m.config.Save(config.GetConfigPath()); err != nil {
+					fmt.Printf("Error saving config: %v\n", err)
+				}
+				return m, nil
+			}
+			if m.selectedItem == 9 { // Sanitize Profile - cycle through options
+				currentIndex := slices.Index(sanitize.Profiles, m.config.Paths.Sanitize)
+				nextIndex := (currentIndex + 1) % len(sanitize.Profiles)
+				m.config.Paths.Sanitize = sanitize.Profiles[nextIndex]
+				if err := m.config.Save(config.GetConfigPath()); err != nil {
+					fmt.Printf("Error saving config: %v\n", err)
+				}
+				return m, nil
+			}
 			// Start editing the selected field
 			m.isEditing = true
 			// Set current value as edit value
@@ -696,6 +1063,16 @@ func (m model) updatePathsSettings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				m.editValue = m.config.Paths.Config
 			case 3:
 				m.editValue = m.config.Paths.LogFile
+			case 4:
+				m.editValue = m.config.Paths.Work
+			case 5:
+				m.editValue = m.config.Paths.TrackTemplate
+			case 6:
+				m.editValue = m.config.Paths.CompilationTemplate
+			case 7:
+				m.editValue = m.config.Paths.MultiDiscTemplate
+			case 10:
+				m.editValue = fmt.Sprintf("%d", m.config.Paths.MaxPathLength)
 			}
 			return m, nil
 		}
@@ -703,7 +1080,59 @@ func (m model) updatePathsSettings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// pathsTemplateKind returns the naming template a Paths settings field edits
+func pathsTemplateKind(field int) (naming.Kind, bool) {
+	switch field {
+	case 5:
+		return naming.Track, true
+	case 6:
+		return naming.Compilation, true
+	case 7:
+		return naming.MultiDisc, true
+	}
+	return 0, false
+}
+
 func (m model) updateDrivesSettings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.isEditing {
+		// Editing the read offset of the selected drive's model
+		switch msg.String() {
+		case "enter":
+			drive := m.availableDrives[m.selectedItem]
+			value := strings.TrimSpace(m.editValue)
+			if value == "" {
+				// An empty offset falls back to the bundled table
+				delete(m.config.Drives.ReadOffsets, drive.Key)
+			} else if offset, err := strconv.Atoi(value); err == nil &&
+				offset >= -drives.MaxReadOffset && offset <= drives.MaxReadOffset {
+				if m.config.Drives.ReadOffsets == nil {
+					m.config.Drives.ReadOffsets = map[string]int{}
+				}
+				m.config.Drives.ReadOffsets[drive.Key] = offset
+			}
+			if err := m.config.Save(config.GetConfigPath()); err != nil {
+				fmt.Printf("Error saving config: %v\n", err)
+			}
+			m.isEditing = false
+			m.editValue = ""
+			return m, nil
+		case "esc":
+			m.isEditing = false
+			m.editValue = ""
+			return m, nil
+		case "backspace":
+			if len(m.editValue) > 0 {
+				m.editValue = m.editValue[:len(m.editValue)-1]
+			}
+			return m, nil
+		default:
+			if key := msg.String(); len(key) == 1 && strings.Contains("+-0123456789", key) {
+				m.editValue += key
+			}
+			return m, nil
+		}
+	}
+
 	switch msg.String() {
 	case "q", "esc":
 		m.currentScreen = SettingsMenuScreen
@@ -733,9 +1162,19 @@ func (m model) updateDrivesSettings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.currentScreen = SettingsMenuScreen
 			return m, nil
 		}
+	case "o":
+		// Set the read offset of the selected drive's model
+		if m.selectedItem < len(m.availableDrives) && m.availableDrives[m.selectedItem].Key != "" {
+			m.isEditing = true
+			m.editValue = ""
+			if offset, ok := m.config.Drives.ReadOffsets[m.availableDrives[m.selectedItem].Key]; ok {
+				m.editValue = strconv.Itoa(offset)
+			}
+		}
+		return m, nil
 	case "r":
 		// Refresh drive detection
-		availableDrives, err := drives.DetectDrives()
+		availableDrives, err := m.cdRipper.ScanDrives()
 		if err != nil {
 			fmt.Printf("Warning: Could not detect drives: %v\n", err)
 		} else {
@@ -828,6 +1267,12 @@ func (m model) View() string {
 		return m.renderCDRipping()
 	case RippingSuccessScreen:
 		return m.renderRippingSuccess()
+	case MetadataPickerScreen:
+		return m.renderMetadataPicker()
+	case MetadataEditorScreen:
+		return m.renderMetadataEditor()
+	case DuplicateScreen:
+		return m.renderDuplicate()
 	case SettingsMenuScreen:
 		return m.renderSettingsMenu()
 	case DrivesSettingsScreen:
@@ -986,10 +1431,11 @@ func (m model) renderToolsSettings() string {
 		"Configure external tool paths (leave empty for auto-detection)",
 	)
 
-	toolsFields := []string{"ABCDE Path", "cd-discid Path", "MakeMKV Path"}
+	toolsFields := []string{"ABCDE Path", "cd-discid Path", "cdparanoia Path", "MakeMKV Path"}
 	toolsValues := []string{
 		m.config.Tools.AbcdePath,
 		m.config.Tools.CDDiscidPath,
+		m.config.Tools.CDParanoiaPath,
 		m.config.Tools.MakeMKVPath,
 	}
 
@@ -1079,21 +1525,34 @@ func (m model) renderCDRippingSettings() string {
 	title := titleStyle.Render("💿 CD Ripping Settings")
 	subtitle := subtitleStyle.Render("Configure CD ripping behavior and formats")
 
-	cdFields := []string{
-		"Retry Count",
-		"Retry Delay (sec)",
-		"Initial Wait (sec)",
-		"Auto Eject",
-		"Output Format",
-		"CDDB Method",
-	}
+	cdFields := m.cdRippingFields()
 	cdValues := []string{
 		fmt.Sprintf("%d", m.config.CDRipping.RetryCount),
 		fmt.Sprintf("%d", m.config.CDRipping.RetryDelay),
 		fmt.Sprintf("%d", m.config.CDRipping.InitialWait),
 		fmt.Sprintf("%t", m.config.CDRipping.AutoEject),
-		m.config.CDRipping.OutputFormat,
+		strings.Join(m.config.CDRipping.OutputFormat, ", "),
 		m.config.CDRipping.CDDBMethod,
+		m.config.CDRipping.ExtractionBackend,
+		fmt.Sprintf("%d", m.config.CDRipping.EncoderWorkers),
+		m.config.CDRipping.OnDuplicate,
+		fmt.Sprintf("%t", m.config.CDRipping.Secure.Enabled),
+		fmt.Sprintf("%d", m.config.CDRipping.Secure.MaxRereads),
+		m.config.CDRipping.Gaps.Policy,
+		fmt.Sprintf("%t", m.config.CDRipping.Gaps.Detect),
+		m.config.CDRipping.RipMode,
+		fmt.Sprintf("%t", m.config.CDRipping.Image.EmbedCue),
+	}
+	for _, format := range m.config.CDRipping.OutputFormat {
+		for _, setting := range outputSettings(format) {
+			value := setting.value(m.config)
+			if value == "" {
+				value = "(" + setting.hint + ")"
+			} else {
+				value += " (" + setting.hint + ")"
+			}
+			cdValues = append(cdValues, value)
+		}
 	}
 
 	var fields string
@@ -1109,22 +1568,42 @@ func (m model) renderCDRippingSettings() string {
 			}
 		}
 
+		if i == 9 { // Secure Rip
+			if m.config.CDRipping.Secure.Enabled {
+				value = "✓ Yes (cdparanoia only)"
+			} else {
+				value = "✗ No"
+			}
+		}
+
+		if i == 12 { // Detect Gaps
+			if m.config.CDRipping.Gaps.Detect {
+				value = "✓ Yes (needs cdrdao)"
+			} else {
+				value = "✗ No (gap before track 1 only)"
+			}
+		}
+
+		if i == 14 { // Embed Cue
+			if m.config.CDRipping.Image.EmbedCue {
+				value = "✓ Yes (FLAC images)"
+			} else {
+				value = "✗ No (cue sheet file only)"
+			}
+		}
+
+		if i == 7 && m.config.CDRipping.EncoderWorkers == 0 {
+			value = "0 (one per CPU core)"
+		}
+
 		// Special handling for editing mode
-		if m.isEditing && i == m.selectedItem && i != 3 && i != 4 && i != 5 {
+		if m.isEditing && i == m.selectedItem && i != 3 && i != 5 && i != 6 && i != 9 && i != 11 && i != 12 && i != 13 && i != 14 {
 			// Show edit value with cursor (skip for boolean and selectable)
 			value = m.editValue + "█" // Block cursor
 		}
 
 		// Add cycling indicators for selectable options
-		if i == 4 { // Output Format
-			formats := []string{"flac", "mp3", "ogg", "wav"}
-			for j, format := range formats {
-				if format == value {
-					value = fmt.Sprintf("%s (%d/%d)", value, j+1, len(formats))
-					break
-				}
-			}
-		} else if i == 5 { // CDDB Method
+		if i == 5 { // CDDB Method
 			methods := []string{"musicbrainz", "cddb", "none"}
 			for j, method := range methods {
 				if method == value {
@@ -1132,6 +1611,22 @@ func (m model) renderCDRippingSettings() string {
 					break
 				}
 			}
+		} else if i == 6 { // Extraction Backend
+			backends := []string{"abcde", "cdparanoia"}
+			for j, backend := range backends {
+				if backend == value {
+					value = fmt.Sprintf("%s (%d/%d)", value, j+1, len(backends))
+					break
+				}
+			}
+		} else if i == 11 { // Gap Policy
+			if j := slices.Index(config.GapPolicies, value); j >= 0 {
+				value = fmt.Sprintf("%s (%d/%d)", value, j+1, len(config.GapPolicies))
+			}
+		} else if i == 13 { // Rip Mode
+			if j := slices.Index(config.RipModes, value); j >= 0 {
+				value = fmt.Sprintf("%s (%d/%d)", value, j+1, len(config.RipModes))
+			}
 		}
 
 		if i == m.selectedItem {
@@ -1146,13 +1641,13 @@ func (m model) renderCDRippingSettings() string {
 				Padding(0, 1).
 				Margin(0, 2)
 
-			if m.isEditing && i != 3 && i != 4 && i != 5 {
+			if m.isEditing && i != 3 && i != 5 && i != 6 && i != 9 && i != 11 && i != 12 && i != 13 && i != 14 {
 				// Editing mode styling (skip for boolean and selectable)
 				valueStyle = valueStyle.Background(accent).Foreground(lipgloss.Color("0"))
-			} else if i == 3 {
+			} else if i == 3 || i == 9 || i == 12 || i == 14 {
 				// Special styling for boolean toggle
 				valueStyle = valueStyle.Background(green).Foreground(lipgloss.Color("0"))
-			} else if i == 4 || i == 5 {
+			} else if i == 5 || i == 6 || i == 11 || i == 13 {
 				// Special styling for selectable options
 				valueStyle = valueStyle.Background(lightBlue).Foreground(lipgloss.Color("0"))
 			}
@@ -1179,9 +1674,17 @@ func (m model) renderCDRippingSettings() string {
 		Italic(true).
 		Margin(1, 2)
 	hints := hintsStyle.Render(
-		"Hints: Retry Count (0-10) • Delays in seconds • Formats: flac, mp3, ogg, wav • CDDB: musicbrainz, cddb, none",
+		"Hints: Retry Count (0-10) • Delays in seconds • Formats: comma-separated list of flac, mp3, ogg, opus, m4a, alac (cdparanoia only), wavpack, wav • CDDB: musicbrainz, cddb, none • Backend: abcde, cdparanoia • Encoder Workers (0-64, 0 = auto) • Secure Re-reads (1-20) • Gap Policy: append pregaps to the track before, prepend to their own track • Rip Mode: tracks, or image for the whole disc in one flac/wav file with a cue sheet (cdparanoia only) • Empty root uses the music directory",
 	)
 
+	// Explain why the last change was not saved
+	if m.settingsError != "" {
+		errorStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			Margin(0, 2)
+		hints += "\n" + errorStyle.Render("Not saved: "+m.settingsError)
+	}
+
 	var help string
 	if m.isEditing {
 		help = helpStyle.Render("Type to edit • Enter to save • Esc to cancel")
@@ -1202,14 +1705,33 @@ func (m model) renderCDRippingSettings() string {
 
 func (m model) renderPathsSettings() string {
 	title := titleStyle.Render("📁 Paths Settings")
-	subtitle := subtitleStyle.Render("Configure directory paths and log file location")
-
-	pathsFields := []string{"Music Directory", "Movies Directory", "Config Directory", "Log File"}
+	subtitle := subtitleStyle.Render("Configure directory paths, log file location and file naming")
+
+	pathsFields := []string{
+		"Music Directory",
+		"Movies Directory",
+		"Config Directory",
+		"Log File",
+		"Work Directory",
+		"Track Template",
+		"Compilation Template",
+		"Multi-Disc Template",
+		"Replace Spaces",
+		"Sanitize Profile",
+		"Max Path Length",
+	}
 	pathsValues := []string{
 		m.config.Paths.Music,
 		m.config.Paths.Movies,
 		m.config.Paths.Config,
 		m.config.Paths.LogFile,
+		m.config.Paths.Work,
+		m.config.Paths.TrackTemplate,
+		m.config.Paths.CompilationTemplate,
+		m.config.Paths.MultiDiscTemplate,
+		fmt.Sprintf("%t", m.config.Paths.ReplaceSpaces),
+		m.config.Paths.Sanitize,
+		fmt.Sprintf("%d", m.config.Paths.MaxPathLength),
 	}
 
 	var fields string
@@ -1253,17 +1775,39 @@ func (m model) renderPathsSettings() string {
 		}
 	}
 
+	// Preview the selected template, following the edit as it is typed
+	var preview string
+	if kind, ok := pathsTemplateKind(m.selectedItem); ok {
+		text := pathsValues[m.selectedItem]
+		if m.isEditing {
+			text = m.editValue
+		}
+		previewStyle := lipgloss.NewStyle().
+			Foreground(gray).
+			Italic(true).
+			Margin(0, 2)
+		if path, err := naming.Preview(kind, text, m.config.Paths.SanitizePolicy()); err != nil {
+			preview = previewStyle.Foreground(lipgloss.Color("196")).Render("Invalid: "+err.Error()) + "\n"
+		} else {
+			preview = previewStyle.Render("Preview: "+path) + "\n"
+		}
+		preview += previewStyle.Render(
+			"Fields: .AlbumArtist .Album .Artist .Title .Year .Genre .Track .TrackTotal .Disc .Compilation",
+		) + "\n\n"
+	}
+
 	var help string
 	if m.isEditing {
 		help = helpStyle.Render("Type to edit • Enter to save • Esc to cancel")
 	} else {
-		help = helpStyle.Render("↑/↓ or j/k to navigate • Enter to edit • Esc/q to go back")
+		help = helpStyle.Render("↑/↓ or j/k to navigate • Enter to edit/toggle • Esc/q to go back")
 	}
 
-	content := fmt.Sprintf("%s\n%s\n\n%s%s",
+	content := fmt.Sprintf("%s\n%s\n\n%s%s%s",
 		title,
 		subtitle,
 		fields,
+		preview,
 		help,
 	)
 
@@ -1314,6 +1858,17 @@ func (m model) renderSettingsMenu() string {
 	return containerStyle.Render(content)
 }
 
+// driveOffsetLabel describes the read offset used for a drive
+func driveOffsetLabel(drive drives.DriveInfo, configured map[string]int) string {
+	if offset, ok := configured[drive.Key]; ok && drive.Key != "" {
+		return fmt.Sprintf("offset %+d, set", offset)
+	}
+	if drive.OffsetKnown {
+		return fmt.Sprintf("offset %+d, known", drive.ReadOffset)
+	}
+	return "offset unknown"
+}
+
 func (m model) renderDrivesSettings() string {
 	title := titleStyle.Render("💿 Drives Settings")
 	subtitle := subtitleStyle.Render("Choose and configure optical drives")
@@ -1370,11 +1925,12 @@ func (m model) renderDrivesSettings() string {
 				readOnlyInfo = " [Read-Only]"
 			}
 
-			driveInfo = fmt.Sprintf("%s - %s%s%s",
+			driveInfo = fmt.Sprintf("%s - %s%s%s [%s]",
 				drive.Device,
 				drive.Model,
 				mediaInfo,
 				readOnlyInfo,
+				driveOffsetLabel(drive, m.config.Drives.ReadOffsets),
 			)
 
 			if i == m.selectedItem {
@@ -1401,6 +1957,18 @@ func (m model) renderDrivesSettings() string {
 			currentSelection,
 			drivesList,
 		)
+
+		if m.isEditing {
+			editStyle := lipgloss.NewStyle().
+				Foreground(accent).
+				Bold(true).
+				Margin(0, 2)
+			content += "\n" + editStyle.Render(fmt.Sprintf(
+				"Read offset for %s (samples, empty for the known value): %s_",
+				m.availableDrives[m.selectedItem].Key,
+				m.editValue,
+			))
+		}
 	}
 
 	// Help section
@@ -1408,7 +1976,10 @@ func (m model) renderDrivesSettings() string {
 	if len(m.availableDrives) == 0 {
 		help = helpStyle.Render("Press 'r' to refresh • Esc/q to go back")
 	} else {
-		help = helpStyle.Render("↑/↓ or j/k to navigate • Enter/Space to select • 'r' to refresh • Esc/q to go back")
+		help = helpStyle.Render("↑/↓ or j/k to navigate • Enter/Space to select • 'o' to set read offset • 'r' to refresh • Esc/q to go back")
+	}
+	if m.isEditing {
+		help = helpStyle.Render("Enter to save • Esc to cancel")
 	}
 
 	finalContent := fmt.Sprintf("%s\n%s", content, help)
@@ -1420,24 +1991,15 @@ func (m model) updateCDRipping(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		// During ripping, only allow quit
 		switch msg.String() {
 		case "q", "esc":
-			// Kill the running abcde process if it exists
-			if m.abcdeCmd != nil && m.abcdeCmd.Process != nil {
-				if err := m.abcdeCmd.Process.Kill(); err != nil {
-					fmt.Printf("Failed to kill abcde process: %v\n", err)
-				}
-			}
-			
+			// Cancel the rip; the ripper kills abcde and cleans up after it
+			m.cdRipper.Stop()
+
 			m.isRipping = false
 			m.rippingProgress = 0
+			m.rippingTrack = ripper.ProgressInfo{}
+			m.readWarnings = nil
 			m.rippingStatus = ""
-			m.abcdeCmd = nil
-			
-			// Clean up abcde working directories on cancellation
-			go func() {
-				cleanupCmd := exec.Command("sh", "-c", fmt.Sprintf("rm -rf '%s'/abcde.* 2>/dev/null || true", m.config.Paths.Music))
-				cleanupCmd.Run() // Ignore errors - cleanup is best effort
-			}()
-			
+
 			m.currentScreen = WelcomeScreen
 			return m, nil
 		}
@@ -1449,30 +2011,24 @@ func (m model) updateCDRipping(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "q", "esc":
 		m.currentScreen = WelcomeScreen
 		return m, nil
+	case "e":
+		// Edit metadata before ripping
+		if m.cdInfo != nil {
+			m.currentScreen = MetadataEditorScreen
+			m.selectedItem = 0
+			m.isEditing = false
+			m.editValue = ""
+		}
+		return m, nil
 	case "y":
 		// Confirm rip after CD detected
 		if m.cdInfo != nil {
-			m.isRipping = true
-			m.rippingStatus = fmt.Sprintf("Ripping Audio CD")
-			m.spinnerFrame = 0
-			
-			// Create the command first so we can track it
-			cmd := exec.Command("abcde",
-				"-d", m.config.Drives.CDDrive,
-				"-o", m.config.CDRipping.OutputFormat,
-				"-a", "default", // Use default actions (cddb,read,encode,tag,move,clean)
-			)
-			cmd.Dir = m.config.Paths.Music
-			cmd.Env = append(os.Environ(),
-				"OUTPUTDIR="+m.config.Paths.Music,
-				"OUTPUTFORMAT=${ARTISTFILE}/${ALBUMFILE}/${TRACKNUM}_${TRACKFILE}",
-			)
-			
-			// Store the command reference so we can kill it if needed
-			m.abcdeCmd = cmd
-			
-			// Start both ripping and spinner
-			return m, tea.Batch(rippingCmd(cmd, m.config.Paths.Music), spinnerCmd())
+			// Warn about a disc already in the library before ripping it again
+			if m.config.CDRipping.OnDuplicate == "ask" && m.cdInfo.OnDuplicate == "" && !m.config.CDRipping.Simulate {
+				m.rippingStatus = "🔍 Checking library..."
+				return m, duplicateCheckCmd(m.cdRipper, m.cdInfo)
+			}
+			return m.startRipping()
 		} else {
 			m.rippingStatus = "Cannot start: No drive configured or CD not detected"
 		}
@@ -1480,6 +2036,67 @@ func (m model) updateCDRipping(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// startRipping starts ripping the detected disc
+func (m model) startRipping() (tea.Model, tea.Cmd) {
+	m.isRipping = true
+	m.rippingStatus = fmt.Sprintf("Ripping Audio CD")
+	m.rippingProgress = 0
+	m.rippingTrack = ripper.ProgressInfo{}
+	m.readWarnings = nil
+	m.spinnerFrame = 0
+
+	// Start ripping, listen for progress and run the spinner
+	return m, tea.Batch(
+		startRippingCmd(m.cdRipper, m.cdInfo),
+		listenForProgressCmd(m.cdRipper.GetProgressChannel()),
+		spinnerCmd(),
+	)
+}
+
+// duplicateOptions are the choices offered for a disc already in the
+// library, as on_duplicate actions
+var duplicateOptions = []struct{ label, action string }{
+	{"Skip this disc", "skip"},
+	{"Re-rip to a new folder", "new_folder"},
+	{"Overwrite the existing rip", "overwrite"},
+}
+
+func (m model) updateDuplicate(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "esc", "s":
+		// Skip - leave the existing rip alone
+		m.existingRips = nil
+		m.rippingStatus = "Skipped - the disc is already in the library"
+		m.currentScreen = CDRippingScreen
+		return m, nil
+	case "up", "k":
+		if m.selectedItem > 0 {
+			m.selectedItem--
+		}
+		return m, nil
+	case "down", "j":
+		if m.selectedItem < len(duplicateOptions)-1 {
+			m.selectedItem++
+		}
+		return m, nil
+	case "enter", " ":
+		if m.cdInfo == nil {
+			return m, nil
+		}
+		action := duplicateOptions[m.selectedItem].action
+		m.existingRips = nil
+		m.currentScreen = CDRippingScreen
+		m.selectedItem = 0
+		if action == "skip" {
+			m.rippingStatus = "Skipped - the disc is already in the library"
+			return m, nil
+		}
+		m.cdInfo.OnDuplicate = action
+		return m.startRipping()
+	}
+	return m, nil
+}
+
 func (m model) updateRippingSuccess(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "q", "esc":
@@ -1503,6 +2120,285 @@ func (m model) updateRippingSuccess(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+func (m model) updateMetadataPicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "esc", "s":
+		// Skip - keep whatever metadata the disc already has
+		m.metadataCandidates = nil
+		m.rippingStatus = "Metadata lookup skipped"
+		m.currentScreen = CDRippingScreen
+		return m, nil
+	case "up", "k":
+		if m.selectedItem > 0 {
+			m.selectedItem--
+		}
+		return m, nil
+	case "down", "j":
+		if m.selectedItem < len(m.metadataCandidates)-1 {
+			m.selectedItem++
+		}
+		return m, nil
+	case "enter", " ":
+		if m.cdInfo == nil || m.selectedItem >= len(m.metadataCandidates) {
+			return m, nil
+		}
+		candidate := m.metadataCandidates[m.selectedItem]
+		if err := candidate.ApplyTo(m.cdInfo); err != nil {
+			m.rippingStatus = fmt.Sprintf("❌ Could not apply metadata: %v", err)
+		} else {
+			m.rippingStatus = "✅ Metadata selected"
+			if err := m.cdRipper.RememberCandidate(m.cdInfo, candidate); err != nil {
+				m.rippingStatus = fmt.Sprintf("✅ Metadata selected (could not remember choice: %v)", err)
+			}
+		}
+		m.metadataCandidates = nil
+		m.currentScreen = CDRippingScreen
+		m.selectedItem = 0
+		return m, nil
+	}
+	return m, nil
+}
+
+// metadataAlbumFields is the number of album fields before the track fields
+// in the metadata editor
+const metadataAlbumFields = 6
+
+// metadataEditorFields lists the editable fields of a disc: the album fields
+// followed by a title and an artist field for every track
+func metadataEditorFields(cdInfo *ripper.CDInfo) []string {
+	fields := []string{"Album Artist", "Album", "Year", "Genre", "Disc Number", "Cover Image"}
+	for _, track := range cdInfo.Tracks {
+		fields = append(fields,
+			fmt.Sprintf("Track %02d Title", track.Number),
+			fmt.Sprintf("Track %02d Artist", track.Number),
+		)
+	}
+	return fields
+}
+
+// metadataEditorValue returns the current value of an editor field
+func metadataEditorValue(cdInfo *ripper.CDInfo, field int) string {
+	switch field {
+	case 0:
+		return cdInfo.Artist
+	case 1:
+		return cdInfo.Album
+	case 2:
+		return cdInfo.Year
+	case 3:
+		return cdInfo.Genre
+	case 4:
+		if cdInfo.DiscNumber == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%d", cdInfo.DiscNumber)
+	case 5:
+		return cdInfo.CoverArtPath
+	}
+
+	track := &cdInfo.Tracks[(field-metadataAlbumFields)/2]
+	if (field-metadataAlbumFields)%2 == 0 {
+		return track.Title
+	}
+	return track.Artist
+}
+
+// setMetadataEditorValue stores an edited value, ignoring invalid numbers
+func setMetadataEditorValue(cdInfo *ripper.CDInfo, field int, value string) {
+	value = strings.TrimSpace(value)
+	switch field {
+	case 0:
+		cdInfo.SetAlbumArtist(value)
+	case 1:
+		cdInfo.Album = value
+	case 2:
+		if value == "" || (len(value) == 4 && parseInt(value) >= 0) {
+			cdInfo.Year = value
+		}
+	case 3:
+		cdInfo.Genre = value
+	case 4:
+		if value == "" {
+			cdInfo.DiscNumber = 0
+		} else if val := parseInt(value); val > 0 && val <= 99 {
+			cdInfo.DiscNumber = val
+		}
+	case 5:
+		cdInfo.CoverArtPath = value
+	default:
+		track := &cdInfo.Tracks[(field-metadataAlbumFields)/2]
+		if (field-metadataAlbumFields)%2 == 0 {
+			track.Title = value
+		} else {
+			track.Artist = value
+		}
+	}
+}
+
+func (m model) updateMetadataEditor(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.cdInfo == nil {
+		m.currentScreen = CDRippingScreen
+		return m, nil
+	}
+	editorFields := metadataEditorFields(m.cdInfo)
+
+	if m.isEditing {
+		// Handle editing mode
+		switch msg.String() {
+		case "enter":
+			// The manifest notes metadata changed by hand; the cover is not metadata
+			before := metadataEditorValue(m.cdInfo, m.selectedItem)
+			setMetadataEditorValue(m.cdInfo, m.selectedItem, m.editValue)
+			if m.selectedItem != 5 && metadataEditorValue(m.cdInfo, m.selectedItem) != before {
+				m.cdInfo.MetadataEdited = true
+			}
+			m.isEditing = false
+			m.editValue = ""
+			return m, nil
+		case "esc":
+			// Cancel editing
+			m.isEditing = false
+			m.editValue = ""
+			return m, nil
+		default:
+			// Handle text input
+			if msg.String() == "backspace" {
+				if len(m.editValue) > 0 {
+					runes := []rune(m.editValue)
+					m.editValue = string(runes[:len(runes)-1])
+				}
+			} else if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+				m.editValue += string(msg.Runes)
+			}
+			return m, nil
+		}
+	}
+
+	// Handle navigation mode
+	switch msg.String() {
+	case "q", "esc":
+		m.currentScreen = CDRippingScreen
+		m.selectedItem = 0
+		return m, nil
+	case "up", "k":
+		if m.selectedItem > 0 {
+			m.selectedItem--
+		}
+		return m, nil
+	case "down", "j":
+		if m.selectedItem < len(editorFields)-1 {
+			m.selectedItem++
+		}
+		return m, nil
+	case "enter":
+		m.isEditing = true
+		m.editValue = metadataEditorValue(m.cdInfo, m.selectedItem)
+		return m, nil
+	case "t":
+		m.cdInfo.TitleCaseAll()
+		m.cdInfo.MetadataEdited = true
+		return m, nil
+	case "w":
+		m.cdInfo.SwapArtistTitle()
+		m.cdInfo.MetadataEdited = true
+		return m, nil
+	case "c":
+		m.cdInfo.SetCompilation(!m.cdInfo.Compilation)
+		m.cdInfo.MetadataEdited = true
+		return m, nil
+	}
+	return m, nil
+}
+
+func (m model) renderMetadataEditor() string {
+	title := titleStyle.Render("✏️ Edit Metadata")
+	if m.cdInfo == nil {
+		return containerStyle.Render(title)
+	}
+
+	compilation := "No"
+	if m.cdInfo.Compilation {
+		compilation = "Yes"
+	}
+	subtitle := subtitleStyle.Render(
+		fmt.Sprintf("%d tracks • Compilation: %s", m.cdInfo.TrackCount, compilation),
+	)
+
+	editorFields := metadataEditorFields(m.cdInfo)
+
+	// Only show a window of fields around the selection so long discs fit
+	const visibleFields = 12
+	start := m.selectedItem - visibleFields/2
+	if start > len(editorFields)-visibleFields {
+		start = len(editorFields) - visibleFields
+	}
+	if start < 0 {
+		start = 0
+	}
+	end := min(start+visibleFields, len(editorFields))
+
+	var fields string
+	for i := start; i < end; i++ {
+		field := editorFields[i]
+		value := metadataEditorValue(m.cdInfo, i)
+		if m.isEditing && i == m.selectedItem {
+			// Show edit value with cursor
+			value = m.editValue + "█" // Block cursor
+		}
+
+		if i == m.selectedItem {
+			// Highlighted field
+			fieldStyle := lipgloss.NewStyle().
+				Foreground(accent).
+				Bold(true).
+				Margin(0, 2)
+			valueStyle := lipgloss.NewStyle().
+				Foreground(lightBlue).
+				Background(lipgloss.Color("235")).
+				Padding(0, 1)
+
+			if m.isEditing {
+				// Editing mode styling
+				valueStyle = valueStyle.Background(accent).Foreground(lipgloss.Color("0"))
+			}
+
+			fields += fieldStyle.Render("▶ "+field+":") + valueStyle.Render(value) + "\n"
+		} else {
+			// Regular field
+			fieldStyle := lipgloss.NewStyle().
+				Foreground(lipgloss.Color("255")).
+				Margin(0, 2)
+			valueStyle := lipgloss.NewStyle().
+				Foreground(gray)
+
+			fields += fieldStyle.Render("  "+field+":") + valueStyle.Render(value) + "\n"
+		}
+	}
+
+	position := descriptionStyle.Render(
+		fmt.Sprintf("Field %d of %d", m.selectedItem+1, len(editorFields)),
+	)
+
+	var help string
+	if m.isEditing {
+		help = helpStyle.Render("Type to edit • Enter to save • Esc to cancel")
+	} else {
+		help = helpStyle.Render(
+			"↑/↓ or j/k to navigate • Enter to edit • 't' title-case all • 'w' swap artist/title • 'c' toggle compilation • Esc/q when done",
+		)
+	}
+
+	content := fmt.Sprintf("%s\n%s\n\n%s\n%s\n%s",
+		title,
+		subtitle,
+		fields,
+		position,
+		help,
+	)
+
+	return containerStyle.Render(content)
+}
+
 func (m model) renderCDRipping() string {
 	title := titleStyle.Render("💿 CD Ripping")
 	
@@ -1523,16 +2419,55 @@ func (m model) renderCDRipping() string {
 		statusStyle := lipgloss.NewStyle().
 			Foreground(lightBlue).
 			Margin(1, 2)
-		statusDisplay := statusStyle.Render(m.rippingStatus)
+		statusDisplay := statusStyle.Render(fmt.Sprintf("%s (%d%%)", m.rippingStatus, m.rippingProgress))
 		
 		spinnerRow := lipgloss.JoinHorizontal(lipgloss.Center, spinnerDisplay, statusDisplay)
 
+		// Reading and encoding overlap, so show each stage
+		stageLine := ""
+		if track := m.rippingTrack; track.ReadProgress > 0 || track.EncodeProgress > 0 {
+			stageStyle := lipgloss.NewStyle().
+				Foreground(lightBlue).
+				Margin(0, 2)
+			stageLine = stageStyle.Render(fmt.Sprintf(
+				"Read %d%% • Encoded %d of %d tracks",
+				track.ReadProgress,
+				track.TracksEncoded,
+				track.TotalTracks,
+			)) + "\n\n"
+		}
+
+		// Backends that read tracks themselves report progress within the track
+		trackLine := ""
+		if track := m.rippingTrack; track.TrackName != "" {
+			trackStyle := lipgloss.NewStyle().
+				Foreground(gray).
+				Margin(0, 2)
+			detail := fmt.Sprintf("Track %d: %s", track.CurrentTrack, track.TrackName)
+			if track.TrackProgress > 0 {
+				detail += fmt.Sprintf(" (%d%%)", track.TrackProgress)
+			}
+			trackLine = trackStyle.Render(detail) + "\n\n"
+		}
+
+		// Secure mode flags tracks with sectors it could not read reliably
+		warningLines := ""
+		if len(m.readWarnings) > 0 {
+			warningStyle := lipgloss.NewStyle().
+				Foreground(lipgloss.Color("214")).
+				Margin(0, 2)
+			warningLines = warningStyle.Render("⚠ "+strings.Join(m.readWarnings, "\n⚠ ")) + "\n\n"
+		}
+
 		help := helpStyle.Render("Press 'q' or Esc to cancel ripping")
 
-		content := fmt.Sprintf("%s\n%s\n\n%s\n\n%s",
+		content := fmt.Sprintf("%s\n%s\n\n%s\n\n%s%s%s%s",
 			title,
 			subtitle,
 			spinnerRow,
+			stageLine,
+			trackLine,
+			warningLines,
 			help,
 		)
 
@@ -1568,7 +2503,18 @@ func (m model) renderCDRipping() string {
 			cdStatus = "🔄 " + m.rippingStatus // Add spinner emoji for detecting state
 		}
 	} else {
-		cdStatus = "✅ CD detected"
+		cdStatus = fmt.Sprintf("✅ CD detected: %s - %s (%d tracks)",
+			m.cdInfo.Artist,
+			m.cdInfo.Album,
+			m.cdInfo.TrackCount,
+		)
+		if pregap := m.cdInfo.Pregap(1); pregap > 0 {
+			cdStatus += fmt.Sprintf("\n⏮ %d:%02d before track 1, ripped as track 00 unless silent",
+				pregap/75/60, pregap/75%60)
+		}
+		if m.rippingStatus != "" {
+			cdStatus += "\n" + m.rippingStatus
+		}
 	}
 
 	cdStatusDisplay := cdStatusStyle.Render(cdStatus)
@@ -1580,7 +2526,7 @@ func (m model) renderCDRipping() string {
 
 	settingsInfo := settingsStyle.Render(fmt.Sprintf(
 		"Format: %s • CDDB: %s • Output: %s",
-		m.config.CDRipping.OutputFormat,
+		strings.Join(m.config.CDRipping.OutputFormat, ", "),
 		m.config.CDRipping.CDDBMethod,
 		m.config.Paths.Music,
 	))
@@ -1606,7 +2552,7 @@ func (m model) renderCDRipping() string {
 
 	var help string
 	if m.cdInfo != nil {
-		help = helpStyle.Render("'y' to start ripping • Esc/q to go back")
+		help = helpStyle.Render("'y' to start ripping • 'e' to edit metadata • Esc/q to go back")
 	} else {
 		help = helpStyle.Render("Detecting CD... • Esc/q to go back")
 	}
@@ -1624,6 +2570,181 @@ func (m model) renderCDRipping() string {
 	return containerStyle.Render(content)
 }
 
+func (m model) renderMetadataPicker() string {
+	title := titleStyle.Render("🔍 Choose Release")
+	subtitle := subtitleStyle.Render(
+		fmt.Sprintf("%d releases match this disc - pick the right one", len(m.metadataCandidates)),
+	)
+
+	var options string
+	for i, candidate := range m.metadataCandidates {
+		details := []string{}
+		if candidate.Date != "" {
+			details = append(details, candidate.Date)
+		}
+		if candidate.Country != "" {
+			details = append(details, candidate.Country)
+		}
+		if candidate.Barcode != "" {
+			details = append(details, "barcode "+candidate.Barcode)
+		}
+		trackInfo := fmt.Sprintf("%d tracks", candidate.TrackCount)
+		if m.cdInfo != nil && candidate.TrackCount != m.cdInfo.TrackCount {
+			trackInfo += " ⚠"
+		}
+		details = append(details, trackInfo, candidate.Source)
+
+		option := fmt.Sprintf("%s - %s (%s)",
+			candidate.Artist,
+			candidate.Album,
+			strings.Join(details, ", "),
+		)
+
+		if i == m.selectedItem {
+			// Highlighted option
+			selected := lipgloss.NewStyle().
+				Foreground(accent).
+				Bold(true).
+				Background(lightBlue).
+				Padding(0, 1).
+				Margin(0, 2)
+			options += selected.Render("▶ "+option) + "\n"
+		} else {
+			// Regular option
+			regular := lipgloss.NewStyle().
+				Foreground(lipgloss.Color("255")).
+				Margin(0, 2)
+			options += regular.Render("  "+option) + "\n"
+		}
+	}
+
+	help := helpStyle.Render("↑/↓ or j/k to navigate • Enter to choose • 's'/Esc to skip")
+
+	content := fmt.Sprintf("%s\n%s\n\n%s\n%s",
+		title,
+		subtitle,
+		options,
+		help,
+	)
+
+	return containerStyle.Render(content)
+}
+
+func (m model) renderDuplicate() string {
+	title := titleStyle.Render("⚠️  Already Ripped")
+	subtitle := subtitleStyle.Render("This disc is already in the library")
+
+	var rips string
+	ripStyle := lipgloss.NewStyle().
+		Foreground(gray).
+		Margin(0, 2)
+	for _, rip := range m.existingRips {
+		rips += ripStyle.Render(fmt.Sprintf("%s (%s)", rip.Dir, rip.Reason)) + "\n"
+	}
+
+	var options string
+	for i, option := range duplicateOptions {
+		if i == m.selectedItem {
+			// Highlighted option
+			selected := lipgloss.NewStyle().
+				Foreground(accent).
+				Bold(true).
+				Background(lightBlue).
+				Padding(0, 1).
+				Margin(0, 2)
+			options += selected.Render("▶ "+option.label) + "\n"
+		} else {
+			// Regular option
+			regular := lipgloss.NewStyle().
+				Foreground(lipgloss.Color("255")).
+				Margin(0, 2)
+			options += regular.Render("  "+option.label) + "\n"
+		}
+	}
+
+	help := helpStyle.Render("↑/↓ or j/k to navigate • Enter to choose • 's'/Esc to skip")
+
+	content := fmt.Sprintf("%s\n%s\n\n%s\n%s\n%s",
+		title,
+		subtitle,
+		rips,
+		options,
+		help,
+	)
+
+	return containerStyle.Render(content)
+}
+
+// secureReadSummary describes the secure mode reads, listing the tracks
+// with suspicious sectors; empty when secure mode was off
+func secureReadSummary(reports []ripper.TrackReadReport) string {
+	if len(reports) == 0 {
+		return ""
+	}
+
+	var warnings []string
+	rereads := 0
+	for _, report := range reports {
+		rereads += report.Rereads
+		if !report.Secure() {
+			warnings = append(warnings, report.Warning())
+		}
+	}
+
+	if len(warnings) == 0 {
+		return fmt.Sprintf("\nSecure rip: all %d tracks read consistently (%d re-reads)", len(reports), rereads)
+	}
+	summary := fmt.Sprintf("\nSecure rip: %d of %d tracks have suspicious sectors", len(warnings), len(reports))
+	for _, warning := range warnings {
+		summary += "\n  ⚠ " + warning
+	}
+	return summary
+}
+
+// accurateRipSummary lists the AccurateRip result of each track, empty when
+// the rip was not verified
+func accurateRipSummary(result *ripper.AccurateRipResult) string {
+	if result == nil {
+		return ""
+	}
+
+	accurate := 0
+	for _, track := range result.Tracks {
+		if track.Accurate() {
+			accurate++
+		}
+	}
+
+	var sb strings.Builder
+	switch {
+	case result.Error != "":
+		sb.WriteString(fmt.Sprintf("\nAccurateRip: lookup failed (%s)", result.Error))
+	case !result.Found:
+		sb.WriteString("\nAccurateRip: disc not in database")
+	default:
+		sb.WriteString(fmt.Sprintf("\nAccurateRip: %d of %d tracks accurate", accurate, len(result.Tracks)))
+	}
+	if result.Found {
+		for _, track := range result.Tracks {
+			sb.WriteString(fmt.Sprintf("\n  Track %02d: %s", track.Track, track))
+		}
+	}
+	return sb.String()
+}
+
+// ctdbSummary describes the CUETools Database check, empty when the rip was
+// not checked
+func ctdbSummary(result *ripper.CTDBResult) string {
+	if result == nil {
+		return ""
+	}
+	summary := fmt.Sprintf("\nCTDB: %s", result)
+	if result.Repairable {
+		summary += "\n  Parity data is available to repair the rip with CUETools"
+	}
+	return summary
+}
+
 func (m model) renderRippingSuccess() string {
 	// Define colors
 	successGreen := lipgloss.Color("34")
@@ -1676,11 +2797,24 @@ func (m model) renderRippingSuccess() string {
 		
 		// Show completion details
 		if m.lastRippedCD != nil {
+			var outputs []string
+			targets, _ := m.cdRipper.OutputTargets()
+			for _, target := range targets {
+				outputs = append(outputs, fmt.Sprintf("%s → %s", target.Format, target.Root))
+			}
+			tracks := fmt.Sprintf("%d", m.lastRippedCD.TrackCount)
+			if m.lastRippedCD.HiddenTrack {
+				tracks += " and a hidden track 00"
+			}
+			if m.cdRipper.ImageRip() {
+				tracks += ", filed as one disc image with a cue sheet"
+			}
 			details = detailStyle.Render(fmt.Sprintf(
-				"Tracks: %d\nOutput: %s\nFormat: %s",
-				m.lastRippedCD.TrackCount,
-				m.config.Paths.Music,
-				m.config.CDRipping.OutputFormat,
+				"Tracks: %s\nOutput: %s%s",
+				tracks,
+				strings.Join(outputs, "\n        "),
+				secureReadSummary(m.lastRippedCD.SecureReads)+
+					accurateRipSummary(m.lastRippedCD.AccurateRip)+ctdbSummary(m.lastRippedCD.CTDB),
 			))
 		}
 	} else {
@@ -1717,7 +2851,50 @@ func (m model) renderRippingSuccess() string {
 	return containerStyle.Render(content)
 }
 
+// sanitizeCommand prints the path of DIR/NAME/... with each name cleaned
+// with the configured policy and the whole kept within its length limits,
+// so scripts name their output the same way the ripper does
+func sanitizeCommand(args []string) {
+	flags := flag.NewFlagSet("sanitize", flag.ExitOnError)
+	ext := flags.String("ext", "", "extension for the last name, without the dot")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: media-ripper sanitize [-ext EXT] DIR NAME...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() < 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	// Only the paths settings matter here; a missing config means defaults
+	cfg, err := config.Load(config.GetConfigPath())
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		}
+		cfg = config.DefaultConfig()
+	}
+
+	policy := cfg.Paths.SanitizePolicy()
+	var names []string
+	for _, name := range flags.Args()[1:] {
+		names = append(names, policy.Name(name))
+	}
+	path, err := policy.Fit(flags.Arg(0), names, *ext)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(path)
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "sanitize" {
+		sanitizeCommand(os.Args[2:])
+		return
+	}
+
 	p := tea.NewProgram(initialModel(), tea.WithAltScreen())
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Error running program: %v", err)
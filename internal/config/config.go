@@ -8,6 +8,9 @@ import (
 	"slices"
 	"strings"
 
+	"github.com/Bparsons0904/ripper/internal/drives"
+	"github.com/Bparsons0904/ripper/internal/naming"
+	"github.com/Bparsons0904/ripper/internal/sanitize"
 	"github.com/pelletier/go-toml/v2"
 )
 
@@ -27,6 +30,11 @@ type DrivesConfig struct {
 	CDDrive    string   `toml:"cd_drive"`
 	AutoDetect bool     `toml:"auto_detect"`
 	Available  []string `toml:"available"`
+
+	// ReadOffsets sets the read offset in samples of drive models, keyed by
+	// vendor and model as shown in the Drives settings. They override the
+	// bundled table of known drives.
+	ReadOffsets map[string]int `toml:"read_offsets"`
 }
 
 // PathsConfig contains directory and file paths
@@ -35,16 +43,215 @@ type PathsConfig struct {
 	Movies  string `toml:"movies"`
 	Config  string `toml:"config"`
 	LogFile string `toml:"log_file"`
+	Work    string `toml:"work"`
+
+	// Templates naming each track's file under an output root, without the
+	// extension. Compilations and discs of a set have their own.
+	TrackTemplate       string `toml:"track_template"`
+	CompilationTemplate string `toml:"compilation_template"`
+	MultiDiscTemplate   string `toml:"multi_disc_template"`
+	// ReplaceSpaces turns spaces in names into underscores
+	ReplaceSpaces bool `toml:"replace_spaces"`
+	// Sanitize picks the filesystem rules names follow, for music and movies
+	Sanitize string `toml:"sanitize"`
+	// MaxPathLength shortens names so full paths fit, 0 for no limit
+	MaxPathLength int `toml:"max_path_length"`
+}
+
+// NamingTemplates returns the path templates
+func (p PathsConfig) NamingTemplates() naming.Templates {
+	return naming.Templates{
+		Track:       p.TrackTemplate,
+		Compilation: p.CompilationTemplate,
+		MultiDisc:   p.MultiDiscTemplate,
+	}
+}
+
+// SanitizePolicy returns the sanitisation rules for names
+func (p PathsConfig) SanitizePolicy() sanitize.Policy {
+	return sanitize.Policy{Profile: p.Sanitize, ReplaceSpaces: p.ReplaceSpaces, MaxPathLength: p.MaxPathLength}
 }
 
 // CDRippingConfig contains CD ripping specific settings
 type CDRippingConfig struct {
-	RetryCount   int    `toml:"retry_count"`
-	RetryDelay   int    `toml:"retry_delay"`
-	InitialWait  int    `toml:"initial_wait"`
-	AutoEject    bool   `toml:"auto_eject"`
-	OutputFormat string `toml:"output_format"`
-	CDDBMethod   string `toml:"cddb_method"`
+	RetryCount     int      `toml:"retry_count"`
+	RetryDelay     int      `toml:"retry_delay"`
+	InitialWait    int      `toml:"initial_wait"`
+	AutoEject      bool     `toml:"auto_eject"`
+	OutputFormat   []string `toml:"output_format"` // Every format is produced from one read
+	CDDBMethod     string   `toml:"cddb_method"`
+	MusicBrainzURL string   `toml:"musicbrainz_url"`
+	UserAgent      string   `toml:"user_agent"`
+	CDDBServer     string   `toml:"cddb_server"`
+	CDDBHello      string   `toml:"cddb_hello"`
+	Simulate       bool     `toml:"simulate"`
+
+	// ExtractionBackend selects how tracks are read: "abcde" runs the whole
+	// abcde pipeline, "cdparanoia" reads tracks directly and encodes them
+	ExtractionBackend string `toml:"extraction_backend"`
+	// EncoderWorkers is how many tracks are encoded at once, 0 for one per CPU
+	EncoderWorkers int `toml:"encoder_workers"`
+	// OnDuplicate is what happens when the disc is already in the library:
+	// "ask" lets the TUI prompt, the other actions apply without asking so
+	// unattended rips never stall
+	OnDuplicate string `toml:"on_duplicate"`
+
+	// Outputs holds per-format settings, keyed by format
+	Outputs map[string]OutputSettings `toml:"outputs"`
+	// Encoders holds the quality settings passed to each format's encoder
+	Encoders EncodersConfig `toml:"encoders"`
+	// CoverArt controls the front cover saved with each album
+	CoverArt CoverArtConfig `toml:"cover_art"`
+	// AccurateRip controls checking rips against the AccurateRip database
+	AccurateRip AccurateRipConfig `toml:"accuraterip"`
+	// CTDB controls checking rips against the CUETools Database
+	CTDB CTDBConfig `toml:"ctdb"`
+	// Secure controls reading every track more than once
+	Secure SecureRipConfig `toml:"secure"`
+	// Gaps controls how the pregaps between tracks are laid out
+	Gaps GapsConfig `toml:"gaps"`
+	// RipMode is "tracks" for a file per track or "image" for the whole
+	// disc in one file with a cue sheet, one of RipModes
+	RipMode string `toml:"rip_mode"`
+	// Image controls disc images, used in image mode
+	Image ImageConfig `toml:"image"`
+}
+
+// CoverArtConfig contains album art settings. A cover is fetched from the
+// Cover Art Archive once the disc's MusicBrainz release is known.
+type CoverArtConfig struct {
+	Enabled bool   `toml:"enabled"`  // Fetch the cover and save it as cover.jpg
+	URL     string `toml:"url"`      // Cover Art Archive server
+	Embed   bool   `toml:"embed"`    // Also embed the cover in FLAC, Ogg, Opus and MP3 files
+	MaxSize int    `toml:"max_size"` // Largest width or height in pixels, 0 keeps the original size
+}
+
+// AccurateRipConfig contains rip verification settings. Checksums are
+// computed from the extracted audio, so only the cdparanoia backend can
+// verify.
+type AccurateRipConfig struct {
+	Enabled bool   `toml:"enabled"` // Verify each track against the database
+	URL     string `toml:"url"`     // AccurateRip server
+}
+
+// CTDBConfig contains CUETools Database settings. Like AccurateRip it needs
+// the cdparanoia backend.
+type CTDBConfig struct {
+	Enabled bool   `toml:"enabled"` // Compare the disc's checksum with the database
+	URL     string `toml:"url"`     // CTDB server
+}
+
+// SecureRipConfig contains secure mode settings. Each track is read twice
+// and sectors the reads disagree on are read again until two reads agree.
+// Only the cdparanoia backend reads securely.
+type SecureRipConfig struct {
+	Enabled    bool `toml:"enabled"`
+	MaxRereads int  `toml:"max_rereads"` // Re-reads of a disagreeing range before it is flagged
+}
+
+// GapsConfig contains pregap settings. A track's pregap is the audio
+// before its start index, usually silence. Audio before the first track is
+// ripped as track 00. Only the cdparanoia backend handles gaps.
+type GapsConfig struct {
+	Policy string `toml:"policy"` // Which track a pregap is filed with, one of GapPolicies
+	Detect bool   `toml:"detect"` // Scan for the pregaps of later tracks with cdrdao
+}
+
+// GapPolicies are the ways a pregap can be filed: at the end of the track
+// before it, or at the start of its own track
+var GapPolicies = []string{"append", "prepend"}
+
+// ImageConfig contains disc image settings. An image is the whole disc read
+// into one file, with a cue sheet marking the tracks. Only the cdparanoia
+// backend rips images.
+type ImageConfig struct {
+	EmbedCue bool `toml:"embed_cue"` // Also store the cue sheet in FLAC images as a CUESHEET tag
+}
+
+// RipModes are the ways a disc can be filed: a file per track, or one image
+// of the whole disc
+var RipModes = []string{"tracks", "image"}
+
+// ImageFormats are the output formats a disc image can be written in
+var ImageFormats = []string{"flac", "wav"}
+
+// MaxSecureRereads bounds secure.max_rereads
+const MaxSecureRereads = 20
+
+// OutputSettings contains the settings for one output format
+type OutputSettings struct {
+	// Root is the library the format is filed into, paths.music if empty
+	Root string `toml:"root"`
+}
+
+// EncodersConfig contains the encoder profile of each lossy or compressed
+// format. alac and wav have no settings.
+type EncodersConfig struct {
+	FLAC    FLACEncoderConfig    `toml:"flac"`
+	MP3     MP3EncoderConfig     `toml:"mp3"`
+	Ogg     OggEncoderConfig     `toml:"ogg"`
+	Opus    OpusEncoderConfig    `toml:"opus"`
+	M4A     M4AEncoderConfig     `toml:"m4a"`
+	WavPack WavPackEncoderConfig `toml:"wavpack"`
+}
+
+// FLACEncoderConfig contains flac settings
+type FLACEncoderConfig struct {
+	CompressionLevel int `toml:"compression_level"` // 0 (fastest) to 8 (smallest)
+}
+
+// MP3EncoderConfig contains LAME settings
+type MP3EncoderConfig struct {
+	Mode       string `toml:"mode"`        // "vbr" or "cbr"
+	VBRQuality int    `toml:"vbr_quality"` // 0 (best) to 9, used in vbr mode
+	Bitrate    int    `toml:"bitrate"`     // kbps, used in cbr mode
+}
+
+// OggEncoderConfig contains oggenc settings
+type OggEncoderConfig struct {
+	Quality float64 `toml:"quality"` // -1 to 10
+}
+
+// OpusEncoderConfig contains opusenc settings
+type OpusEncoderConfig struct {
+	Bitrate int `toml:"bitrate"` // kbps, 6-256
+}
+
+// M4AEncoderConfig contains AAC settings
+type M4AEncoderConfig struct {
+	Bitrate int `toml:"bitrate"` // kbps, 32-320
+}
+
+// WavPackEncoderConfig contains wavpack settings
+type WavPackEncoderConfig struct {
+	Mode string `toml:"mode"` // fast, normal, high or very_high
+}
+
+// OutputFormats are the formats a rip can produce. m4a is AAC in an MP4
+// container; alac uses the same container and extension.
+var OutputFormats = []string{"flac", "mp3", "ogg", "opus", "m4a", "alac", "wavpack", "wav"}
+
+// DuplicateActions are the ways a disc already in the library can be
+// handled: prompt, leave the existing rip alone, rip beside it in a new
+// folder or replace it
+var DuplicateActions = []string{"ask", "skip", "new_folder", "overwrite"}
+
+// WavPackModes are the WavPack compression modes, fastest first
+var WavPackModes = []string{"fast", "normal", "high", "very_high"}
+
+// LAMEBitrates are the CBR bitrates LAME accepts for CD audio
+var LAMEBitrates = []int{32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320}
+
+// MinPathLength is the shortest path limit that leaves room for a library
+// root and a few short names
+const MinPathLength = 32
+
+// outputRoot returns the expanded library root of a format
+func (c *Config) outputRoot(format string) string {
+	if root := c.CDRipping.Outputs[format].Root; root != "" {
+		return expandPath(root)
+	}
+	return expandPath(c.Paths.Music)
 }
 
 // ExecutionConfig contains execution preferences
@@ -55,9 +262,10 @@ type ExecutionConfig struct {
 
 // ToolsConfig contains paths to external tools
 type ToolsConfig struct {
-	AbcdePath    string `toml:"abcde_path"`
-	CDDiscidPath string `toml:"cd_discid_path"`
-	MakeMKVPath  string `toml:"makemkv_path"`
+	AbcdePath      string `toml:"abcde_path"`
+	CDDiscidPath   string `toml:"cd_discid_path"`
+	CDParanoiaPath string `toml:"cdparanoia_path"`
+	MakeMKVPath    string `toml:"makemkv_path"`
 }
 
 // UIConfig contains user interface settings
@@ -82,29 +290,84 @@ func DefaultConfig() *Config {
 			CDDrive:    "/dev/sr0",
 			AutoDetect: true,
 			Available:  []string{"/dev/sr0", "/dev/sr1", "/dev/cdrom"},
+
+			ReadOffsets: map[string]int{},
 		},
 		Paths: PathsConfig{
 			Music:   "/mnt/nas/media/music",
 			Movies:  "/mnt/nas/media/movies",
 			Config:  filepath.Join(homeDir, ".config", "media-ripper"),
 			LogFile: filepath.Join(homeDir, "cd-ripper.log"),
+			Work:    filepath.Join(os.TempDir(), "media-ripper"),
+
+			TrackTemplate:       naming.DefaultTrackTemplate,
+			CompilationTemplate: naming.DefaultCompilationTemplate,
+			MultiDiscTemplate:   naming.DefaultMultiDiscTemplate,
+			ReplaceSpaces:       true,
+			Sanitize:            sanitize.Windows,
+			MaxPathLength:       0,
 		},
 		CDRipping: CDRippingConfig{
-			RetryCount:   3,
-			RetryDelay:   5,
-			InitialWait:  10,
-			AutoEject:    true,
-			OutputFormat: "flac",
-			CDDBMethod:   "musicbrainz",
+			RetryCount:     3,
+			RetryDelay:     5,
+			InitialWait:    10,
+			AutoEject:      true,
+			OutputFormat:   []string{"flac"},
+			CDDBMethod:     "musicbrainz",
+			MusicBrainzURL: "https://musicbrainz.org",
+			UserAgent:      "media-ripper/0.1 ( https://github.com/Bparsons0904/ripper )",
+			CDDBServer:     "https://gnudb.gnudb.org/~cddb/cddb.cgi",
+			CDDBHello:      "media-ripper localhost media-ripper 0.1",
+			Simulate:       false,
+
+			ExtractionBackend: "abcde",
+			EncoderWorkers:    0,
+			OnDuplicate:       "ask",
+			Outputs:           map[string]OutputSettings{},
+			Encoders: EncodersConfig{
+				FLAC:    FLACEncoderConfig{CompressionLevel: 5},
+				MP3:     MP3EncoderConfig{Mode: "vbr", VBRQuality: 2, Bitrate: 320},
+				Ogg:     OggEncoderConfig{Quality: 5},
+				Opus:    OpusEncoderConfig{Bitrate: 128},
+				M4A:     M4AEncoderConfig{Bitrate: 256},
+				WavPack: WavPackEncoderConfig{Mode: "normal"},
+			},
+			CoverArt: CoverArtConfig{
+				Enabled: true,
+				URL:     "https://coverartarchive.org",
+				Embed:   true,
+				MaxSize: 1000,
+			},
+			AccurateRip: AccurateRipConfig{
+				Enabled: true,
+				URL:     "http://www.accuraterip.com",
+			},
+			CTDB: CTDBConfig{
+				Enabled: true,
+				URL:     "http://db.cuetools.net",
+			},
+			Secure: SecureRipConfig{
+				Enabled:    false,
+				MaxRereads: 5,
+			},
+			Gaps: GapsConfig{
+				Policy: "append",
+				Detect: false,
+			},
+			RipMode: "tracks",
+			Image: ImageConfig{
+				EmbedCue: true,
+			},
 		},
 		Execution: ExecutionConfig{
 			PreferredBackend: "native",
 			VerboseLogging:   true,
 		},
 		Tools: ToolsConfig{
-			AbcdePath:    "",
-			CDDiscidPath: "",
-			MakeMKVPath:  "",
+			AbcdePath:      "",
+			CDDiscidPath:   "",
+			CDParanoiaPath: "",
+			MakeMKVPath:    "",
 		},
 		UI: UIConfig{
 			Theme:       "default",
@@ -127,6 +390,11 @@ if err != nil {
 return nil, err
 }
 
+data, err = upgradeConfig(data)
+if err != nil {
+return nil, err
+}
+
 err = toml.Unmarshal(data, config)
 if err != nil {
  return nil, err
@@ -135,6 +403,28 @@ if err != nil {
 return config, nil
 }
 
+// upgradeConfig rewrites settings from older configuration files into their
+// current form before decoding
+func upgradeConfig(data []byte) ([]byte, error) {
+	var raw map[string]any
+	if err := toml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	// output_format used to be a single format
+	cdRipping, ok := raw["cd_ripping"].(map[string]any)
+	if !ok {
+		return data, nil
+	}
+	format, ok := cdRipping["output_format"].(string)
+	if !ok {
+		return data, nil
+	}
+	cdRipping["output_format"] = []string{format}
+
+	return toml.Marshal(raw)
+}
+
 // Save writes the configuration to a TOML file
 func (c *Config) Save(configPath string) error {
 	// Ensure the directory exists
@@ -250,6 +540,15 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// Validate container settings
+	if err := c.validateContainer(); err != nil {
+		if ve, ok := err.(ValidationErrors); ok {
+			errors = append(errors, ve...)
+		} else {
+			errors = append(errors, ValidationError{"container", nil, err.Error()})
+		}
+	}
+
 	if len(errors) > 0 {
 		return errors
 	}
@@ -291,6 +590,22 @@ func (c *Config) validateDrives() error {
 		}
 	}
 
+	// Validate read offsets
+	for drive, offset := range c.Drives.ReadOffsets {
+		if strings.TrimSpace(drive) == "" {
+			errors = append(errors, ValidationError{"drives.read_offsets", drive, "drive name cannot be empty"})
+		} else if offset < -drives.MaxReadOffset || offset > drives.MaxReadOffset {
+			errors = append(
+				errors,
+				ValidationError{
+					fmt.Sprintf("drives.read_offsets.%q", drive),
+					offset,
+					fmt.Sprintf("must be between -%d and %d samples", drives.MaxReadOffset, drives.MaxReadOffset),
+				},
+			)
+		}
+	}
+
 	if len(errors) > 0 {
 		return errors
 	}
@@ -347,12 +662,72 @@ func (c *Config) validatePaths() error {
 		c.Paths.LogFile = expanded
 	}
 
+	// Expand and validate work directory
+	if c.Paths.Work == "" {
+		errors = append(errors, ValidationError{"paths.work", c.Paths.Work, "cannot be empty"})
+	} else {
+		expanded := expandPath(c.Paths.Work)
+		if !filepath.IsAbs(expanded) {
+			errors = append(errors, ValidationError{"paths.work", c.Paths.Work, "must be an absolute path"})
+		}
+		c.Paths.Work = expanded
+	}
+
+	// Validate sanitisation settings
+	if !slices.Contains(sanitize.Profiles, c.Paths.Sanitize) {
+		errors = append(
+			errors,
+			ValidationError{
+				"paths.sanitize",
+				c.Paths.Sanitize,
+				fmt.Sprintf("must be one of: %s", strings.Join(sanitize.Profiles, ", ")),
+			},
+		)
+	}
+	if c.Paths.MaxPathLength != 0 && c.Paths.MaxPathLength < MinPathLength {
+		errors = append(
+			errors,
+			ValidationError{
+				"paths.max_path_length",
+				c.Paths.MaxPathLength,
+				fmt.Sprintf("must be 0 or at least %d", MinPathLength),
+			},
+		)
+	}
+
+	// Validate naming templates, which must give every track its own path
+	templates := c.Paths.NamingTemplates()
+	for _, template := range []struct {
+		kind naming.Kind
+		text string
+	}{
+		{naming.Track, templates.Track},
+		{naming.Compilation, templates.Compilation},
+		{naming.MultiDisc, templates.MultiDisc},
+	} {
+		if err := naming.CheckTemplate(template.kind, template.text, c.Paths.SanitizePolicy()); err != nil {
+			if te, ok := err.(*naming.TemplateError); ok {
+				err = te.Err
+			}
+			errors = append(
+				errors,
+				ValidationError{fmt.Sprintf("paths.%s_template", template.kind), template.text, err.Error()},
+			)
+		}
+	}
+
 	if len(errors) > 0 {
 		return errors
 	}
 	return nil
 }
 
+// ValidateCDRipping checks the CD ripping settings on their own, for editors
+// that change one section of the configuration
+func (c *Config) ValidateCDRipping() error {
+	return c.validateCDRipping()
+}
+
 func (c *Config) validateCDRipping() error {
 	var errors ValidationErrors
 
@@ -390,18 +765,55 @@ func (c *Config) validateCDRipping() error {
 		errors = append(errors, ValidationError{"cd_ripping.initial_wait", c.CDRipping.InitialWait, "cannot exceed 120 seconds"})
 	}
 
-	// Validate output format
-	validFormats := []string{"flac", "mp3", "ogg", "wav"}
-	if !slices.Contains(validFormats, c.CDRipping.OutputFormat) {
+	// Validate output formats
+	if len(c.CDRipping.OutputFormat) == 0 {
 		errors = append(
 			errors,
-			ValidationError{
-				"cd_ripping.output_format",
-				c.CDRipping.OutputFormat,
-				fmt.Sprintf("must be one of: %s", strings.Join(validFormats, ", ")),
-			},
+			ValidationError{"cd_ripping.output_format", c.CDRipping.OutputFormat, "cannot be empty"},
 		)
 	}
+	for i, format := range c.CDRipping.OutputFormat {
+		if !slices.Contains(OutputFormats, format) {
+			errors = append(
+				errors,
+				ValidationError{
+					fmt.Sprintf("cd_ripping.output_format[%d]", i),
+					format,
+					fmt.Sprintf("must be one of: %s", strings.Join(OutputFormats, ", ")),
+				},
+			)
+		} else if slices.Index(c.CDRipping.OutputFormat, format) != i {
+			errors = append(
+				errors,
+				ValidationError{fmt.Sprintf("cd_ripping.output_format[%d]", i), format, "is listed twice"},
+			)
+		}
+	}
+
+	// Validate per-format settings
+	for format, output := range c.CDRipping.Outputs {
+		field := fmt.Sprintf("cd_ripping.outputs.%s", format)
+		if !slices.Contains(OutputFormats, format) {
+			errors = append(
+				errors,
+				ValidationError{field, format, fmt.Sprintf("must be one of: %s", strings.Join(OutputFormats, ", "))},
+			)
+			continue
+		}
+
+		if output.Root != "" {
+			expanded := expandPath(output.Root)
+			if !filepath.IsAbs(expanded) {
+				errors = append(errors, ValidationError{field + ".root", output.Root, "must be an absolute path"})
+			}
+			output.Root = expanded
+		}
+
+		c.CDRipping.Outputs[format] = output
+	}
+
+	// Validate encoder profiles
+	errors = append(errors, c.validateEncoders()...)
 
 	// Validate CDDB method
 	validMethods := []string{"musicbrainz", "cddb", "none"}
@@ -416,6 +828,192 @@ func (c *Config) validateCDRipping() error {
 		)
 	}
 
+	// Validate extraction backend
+	validExtractors := []string{"abcde", "cdparanoia"}
+	if !slices.Contains(validExtractors, c.CDRipping.ExtractionBackend) {
+		errors = append(
+			errors,
+			ValidationError{
+				"cd_ripping.extraction_backend",
+				c.CDRipping.ExtractionBackend,
+				fmt.Sprintf("must be one of: %s", strings.Join(validExtractors, ", ")),
+			},
+		)
+	}
+
+	// Validate duplicate handling
+	if !slices.Contains(DuplicateActions, c.CDRipping.OnDuplicate) {
+		errors = append(
+			errors,
+			ValidationError{
+				"cd_ripping.on_duplicate",
+				c.CDRipping.OnDuplicate,
+				fmt.Sprintf("must be one of: %s", strings.Join(DuplicateActions, ", ")),
+			},
+		)
+	}
+
+	// abcde has no ALAC encoder
+	if c.CDRipping.ExtractionBackend == "abcde" && slices.Contains(c.CDRipping.OutputFormat, "alac") {
+		errors = append(
+			errors,
+			ValidationError{"cd_ripping.output_format", "alac", "requires the cdparanoia extraction backend"},
+		)
+	}
+
+	// m4a and alac both write .m4a files
+	if slices.Contains(c.CDRipping.OutputFormat, "m4a") && slices.Contains(c.CDRipping.OutputFormat, "alac") &&
+		c.outputRoot("m4a") == c.outputRoot("alac") {
+		errors = append(
+			errors,
+			ValidationError{"cd_ripping.outputs.alac.root", c.CDRipping.Outputs["alac"].Root, "must differ from the m4a root, both write .m4a files"},
+		)
+	}
+
+	// Validate encoder workers
+	if c.CDRipping.EncoderWorkers < 0 {
+		errors = append(
+			errors,
+			ValidationError{"cd_ripping.encoder_workers", c.CDRipping.EncoderWorkers, "cannot be negative"},
+		)
+	} else if c.CDRipping.EncoderWorkers > 64 {
+		errors = append(errors, ValidationError{"cd_ripping.encoder_workers", c.CDRipping.EncoderWorkers, "cannot exceed 64"})
+	}
+
+	// Validate MusicBrainz server
+	if !strings.HasPrefix(c.CDRipping.MusicBrainzURL, "http://") &&
+		!strings.HasPrefix(c.CDRipping.MusicBrainzURL, "https://") {
+		errors = append(
+			errors,
+			ValidationError{
+				"cd_ripping.musicbrainz_url",
+				c.CDRipping.MusicBrainzURL,
+				"must be an http:// or https:// URL",
+			},
+		)
+	}
+
+	// Validate cover art settings
+	if !strings.HasPrefix(c.CDRipping.CoverArt.URL, "http://") &&
+		!strings.HasPrefix(c.CDRipping.CoverArt.URL, "https://") {
+		errors = append(
+			errors,
+			ValidationError{"cd_ripping.cover_art.url", c.CDRipping.CoverArt.URL, "must be an http:// or https:// URL"},
+		)
+	}
+	if c.CDRipping.CoverArt.MaxSize < 0 {
+		errors = append(
+			errors,
+			ValidationError{"cd_ripping.cover_art.max_size", c.CDRipping.CoverArt.MaxSize, "cannot be negative"},
+		)
+	} else if c.CDRipping.CoverArt.MaxSize > 10000 {
+		errors = append(errors, ValidationError{"cd_ripping.cover_art.max_size", c.CDRipping.CoverArt.MaxSize, "cannot exceed 10000 pixels"})
+	}
+
+	// Validate AccurateRip server
+	if !strings.HasPrefix(c.CDRipping.AccurateRip.URL, "http://") &&
+		!strings.HasPrefix(c.CDRipping.AccurateRip.URL, "https://") {
+		errors = append(
+			errors,
+			ValidationError{"cd_ripping.accuraterip.url", c.CDRipping.AccurateRip.URL, "must be an http:// or https:// URL"},
+		)
+	}
+
+	// Validate secure mode
+	if c.CDRipping.Secure.MaxRereads < 1 || c.CDRipping.Secure.MaxRereads > MaxSecureRereads {
+		errors = append(
+			errors,
+			ValidationError{
+				"cd_ripping.secure.max_rereads",
+				c.CDRipping.Secure.MaxRereads,
+				fmt.Sprintf("must be between 1 and %d", MaxSecureRereads),
+			},
+		)
+	}
+
+	// Validate gap handling
+	if !slices.Contains(GapPolicies, c.CDRipping.Gaps.Policy) {
+		errors = append(
+			errors,
+			ValidationError{
+				"cd_ripping.gaps.policy",
+				c.CDRipping.Gaps.Policy,
+				fmt.Sprintf("must be one of: %s", strings.Join(GapPolicies, ", ")),
+			},
+		)
+	}
+
+	// Validate the rip mode
+	if !slices.Contains(RipModes, c.CDRipping.RipMode) {
+		errors = append(
+			errors,
+			ValidationError{
+				"cd_ripping.rip_mode",
+				c.CDRipping.RipMode,
+				fmt.Sprintf("must be one of: %s", strings.Join(RipModes, ", ")),
+			},
+		)
+	}
+	// Lossy encoders pad the audio, which would shift the cue sheet's indexes
+	if c.CDRipping.RipMode == "image" {
+		for _, format := range c.CDRipping.OutputFormat {
+			if !slices.Contains(ImageFormats, format) {
+				errors = append(
+					errors,
+					ValidationError{
+						"cd_ripping.output_format",
+						format,
+						fmt.Sprintf("image mode writes only %s", strings.Join(ImageFormats, ", ")),
+					},
+				)
+			}
+		}
+	}
+
+	// Validate CTDB server
+	if !strings.HasPrefix(c.CDRipping.CTDB.URL, "http://") &&
+		!strings.HasPrefix(c.CDRipping.CTDB.URL, "https://") {
+		errors = append(
+			errors,
+			ValidationError{"cd_ripping.ctdb.url", c.CDRipping.CTDB.URL, "must be an http:// or https:// URL"},
+		)
+	}
+
+	// MusicBrainz rejects requests without a meaningful User-Agent
+	if strings.TrimSpace(c.CDRipping.UserAgent) == "" {
+		errors = append(
+			errors,
+			ValidationError{"cd_ripping.user_agent", c.CDRipping.UserAgent, "cannot be empty"},
+		)
+	}
+
+	// Validate CDDB server
+	validSchemes := []string{"http://", "https://", "cddbp://"}
+	if !slices.ContainsFunc(validSchemes, func(scheme string) bool {
+		return strings.HasPrefix(c.CDRipping.CDDBServer, scheme)
+	}) {
+		errors = append(
+			errors,
+			ValidationError{
+				"cd_ripping.cddb_server",
+				c.CDRipping.CDDBServer,
+				"must be an http://, https:// or cddbp:// address",
+			},
+		)
+	}
+
+	// CDDB servers expect "user host client version"
+	if len(strings.Fields(c.CDRipping.CDDBHello)) != 4 {
+		errors = append(
+			errors,
+			ValidationError{
+				"cd_ripping.cddb_hello",
+				c.CDRipping.CDDBHello,
+				"must have four fields: user host client version",
+			},
+		)
+	}
+
 	if len(errors) > 0 {
 		return errors
 	}
@@ -460,6 +1058,15 @@ func (c *Config) validateTools() error {
 		}
 	}
 
+	if c.Tools.CDParanoiaPath == "" {
+		for _, name := range []string{"cdparanoia", "cd-paranoia"} {
+			if path, err := exec.LookPath(name); err == nil {
+				c.Tools.CDParanoiaPath = path
+				break
+			}
+		}
+	}
+
 	if c.Tools.MakeMKVPath == "" {
 		if path, err := exec.LookPath("makemkvcon"); err == nil {
 			c.Tools.MakeMKVPath = path
@@ -493,6 +1100,21 @@ func (c *Config) validateTools() error {
 		}
 	}
 
+	if c.Tools.CDParanoiaPath != "" {
+		if _, err := os.Stat(c.Tools.CDParanoiaPath); os.IsNotExist(err) {
+			errors = append(
+				errors,
+				ValidationError{
+					"tools.cdparanoia_path",
+					c.Tools.CDParanoiaPath,
+					"file does not exist",
+				},
+			)
+		} else if !isExecutable(c.Tools.CDParanoiaPath) {
+			errors = append(errors, ValidationError{"tools.cdparanoia_path", c.Tools.CDParanoiaPath, "file is not executable"})
+		}
+	}
+
 	if c.Tools.MakeMKVPath != "" {
 		if _, err := os.Stat(c.Tools.MakeMKVPath); os.IsNotExist(err) {
 			errors = append(
@@ -534,6 +1156,106 @@ func (c *Config) validateUI() error {
 	return nil
 }
 
+func (c *Config) validateContainer() error {
+	var errors ValidationErrors
+
+	// Validate image
+	if c.Container.Image == "" {
+		errors = append(errors, ValidationError{"container.image", c.Container.Image, "cannot be empty"})
+	}
+
+	// Validate pull policy
+	validPolicies := []string{"always", "if_not_present", "never"}
+	if !slices.Contains(validPolicies, c.Container.PullPolicy) {
+		errors = append(
+			errors,
+			ValidationError{
+				"container.pull_policy",
+				c.Container.PullPolicy,
+				fmt.Sprintf("must be one of: %s", strings.Join(validPolicies, ", ")),
+			},
+		)
+	}
+
+	// The container backend has to be switched on explicitly
+	if c.Execution.PreferredBackend == "container" && !c.Container.Enabled {
+		errors = append(
+			errors,
+			ValidationError{
+				"execution.preferred_backend",
+				c.Execution.PreferredBackend,
+				"requires container.enabled = true",
+			},
+		)
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
+
+// validateEncoders checks every encoder profile, including those of formats
+// not currently produced
+func (c *Config) validateEncoders() ValidationErrors {
+	var errors ValidationErrors
+	encoders := c.CDRipping.Encoders
+
+	if level := encoders.FLAC.CompressionLevel; level < 0 || level > 8 {
+		errors = append(errors, ValidationError{"cd_ripping.encoders.flac.compression_level", level, "must be between 0 and 8"})
+	}
+
+	validModes := []string{"vbr", "cbr"}
+	if !slices.Contains(validModes, encoders.MP3.Mode) {
+		errors = append(
+			errors,
+			ValidationError{
+				"cd_ripping.encoders.mp3.mode",
+				encoders.MP3.Mode,
+				fmt.Sprintf("must be one of: %s", strings.Join(validModes, ", ")),
+			},
+		)
+	}
+	if quality := encoders.MP3.VBRQuality; quality < 0 || quality > 9 {
+		errors = append(errors, ValidationError{"cd_ripping.encoders.mp3.vbr_quality", quality, "must be between 0 and 9"})
+	}
+	if !slices.Contains(LAMEBitrates, encoders.MP3.Bitrate) {
+		errors = append(
+			errors,
+			ValidationError{
+				"cd_ripping.encoders.mp3.bitrate",
+				encoders.MP3.Bitrate,
+				fmt.Sprintf("must be one of: %s", strings.Trim(fmt.Sprint(LAMEBitrates), "[]")),
+			},
+		)
+	}
+
+	if quality := encoders.Ogg.Quality; quality < -1 || quality > 10 {
+		errors = append(errors, ValidationError{"cd_ripping.encoders.ogg.quality", quality, "must be between -1 and 10"})
+	}
+
+	if bitrate := encoders.Opus.Bitrate; bitrate < 6 || bitrate > 256 {
+		errors = append(errors, ValidationError{"cd_ripping.encoders.opus.bitrate", bitrate, "must be between 6 and 256 kbps"})
+	}
+
+	if bitrate := encoders.M4A.Bitrate; bitrate < 32 || bitrate > 320 {
+		errors = append(errors, ValidationError{"cd_ripping.encoders.m4a.bitrate", bitrate, "must be between 32 and 320 kbps"})
+	}
+
+	if !slices.Contains(WavPackModes, encoders.WavPack.Mode) {
+		errors = append(
+			errors,
+			ValidationError{
+				"cd_ripping.encoders.wavpack.mode",
+				encoders.WavPack.Mode,
+				fmt.Sprintf("must be one of: %s", strings.Join(WavPackModes, ", ")),
+			},
+		)
+	}
+
+	return errors
+}
+
 // expandPath expands ~ to home directory
 func expandPath(path string) string {
 	if !strings.HasPrefix(path, "~/") {
@@ -14,6 +14,13 @@ type DriveInfo struct {
 	Model      string
 	IsReadOnly bool
 	MediaType  string
+
+	// Key is the vendor and model the read offset is looked up by, see DriveKey
+	Key string
+	// ReadOffset is the drive's read offset in samples, from the bundled
+	// table when OffsetKnown
+	ReadOffset  int
+	OffsetKnown bool
 }
 
 // DetectDrives scans for available optical drives on the system
@@ -33,7 +40,9 @@ func DetectDrives() ([]DriveInfo, error) {
 				Model:      getDeviceModel(path),
 				IsReadOnly: isReadOnlyDevice(path),
 				MediaType:  detectMediaType(path),
+				Key:        DeviceKey(path),
 			}
+			drive.ReadOffset, drive.OffsetKnown = KnownReadOffset(drive.Key)
 			drives = append(drives, drive)
 		}
 	}
@@ -142,7 +151,9 @@ func scanSysBlock() ([]DriveInfo, error) {
 					Model:      getDeviceModel(device),
 					IsReadOnly: isReadOnlyDevice(device),
 					MediaType:  detectMediaType(device),
+					Key:        DeviceKey(device),
 				}
+				drive.ReadOffset, drive.OffsetKnown = KnownReadOffset(drive.Key)
 				drives = append(drives, drive)
 			}
 		}
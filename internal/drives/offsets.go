@@ -0,0 +1,69 @@
+package drives
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MaxReadOffset bounds read offsets, in samples either way. Real drives are
+// well inside ten sectors.
+const MaxReadOffset = 10 * 588
+
+// knownOffsets are the read offsets of common drives in samples, keyed like
+// DriveKey. Values follow the AccurateRip drive offset list; drives missing
+// here can be set in drives.read_offsets.
+var knownOffsets = map[string]int{
+	"ASUS - BW-16D1HT":           6,
+	"ASUS - DRW-24B1ST A":        6,
+	"ATAPI - IHAS124 Y":          6,
+	"HL-DT-ST - BD-RE BH16NS40":  6,
+	"HL-DT-ST - BD-RE WH16NS40":  6,
+	"HL-DT-ST - DVDRAM GH24NSB0": 6,
+	"OPTIARC - DVD RW AD-7240S":  48,
+	"PIONEER - BD-RW BDR-209D":   667,
+	"PIONEER - BD-RW BDR-XD05":   667,
+	"PIONEER - DVD-RW DVR-111D":  48,
+	"PLEXTOR - CD-R PREMIUM":     30,
+	"PLEXTOR - CD-R PX-W4824A":   30,
+	"PLEXTOR - DVDR PX-716A":     30,
+	"PLEXTOR - DVDR PX-760A":     30,
+	"TSSTCORP - CDDVDW SH-224DB": 6,
+	"YAMAHA - CRW-F1E":           733,
+}
+
+// DriveKey names a drive model the way offset tables do: vendor and model
+// in capitals, separated by " - ", with runs of spaces collapsed
+func DriveKey(vendor, model string) string {
+	vendor = strings.ToUpper(strings.Join(strings.Fields(vendor), " "))
+	model = strings.ToUpper(strings.Join(strings.Fields(model), " "))
+	if vendor == "" {
+		return model
+	}
+	return fmt.Sprintf("%s - %s", vendor, model)
+}
+
+// KnownReadOffset returns the read offset of a drive model from the bundled
+// table
+func KnownReadOffset(key string) (int, bool) {
+	offset, ok := knownOffsets[key]
+	return offset, ok
+}
+
+// DeviceKey returns the DriveKey of a device from the vendor and model
+// reported in sysfs, empty when they cannot be read
+func DeviceKey(device string) string {
+	deviceName := filepath.Base(device)
+	// Symlinks such as /dev/cdrom point at the real sr device
+	if target, err := filepath.EvalSymlinks(device); err == nil {
+		deviceName = filepath.Base(target)
+	}
+
+	model, err := os.ReadFile(fmt.Sprintf("/sys/block/%s/device/model", deviceName))
+	if err != nil {
+		return ""
+	}
+	vendor, _ := os.ReadFile(fmt.Sprintf("/sys/block/%s/device/vendor", deviceName))
+	return DriveKey(string(vendor), string(model))
+}
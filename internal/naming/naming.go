@@ -0,0 +1,296 @@
+// Package naming turns track metadata into library paths using
+// text/template path templates, one for regular albums, compilations and
+// multi-disc sets.
+package naming
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/Bparsons0904/ripper/internal/sanitize"
+)
+
+// Default templates, matching the layout abcde files albums in
+const (
+	DefaultTrackTemplate       = `{{.AlbumArtist}}/{{.Album}}/{{printf "%02d" .Track}}_{{.Title}}`
+	DefaultCompilationTemplate = `Various Artists/{{.Album}}/{{if .Disc}}{{.Disc}}-{{end}}{{printf "%02d" .Track}}_{{.Artist}}-{{.Title}}`
+	DefaultMultiDiscTemplate   = `{{.AlbumArtist}}/{{.Album}}/{{.Disc}}-{{printf "%02d" .Track}}_{{.Title}}`
+)
+
+// Kind selects which discs a template names
+type Kind int
+
+const (
+	Track       Kind = iota // Single discs by one artist
+	Compilation             // Various artists discs, including sets
+	MultiDisc               // Discs of a set by one artist
+)
+
+// String returns the kind's name as used in configuration keys
+func (k Kind) String() string {
+	switch k {
+	case Compilation:
+		return "compilation"
+	case MultiDisc:
+		return "multi_disc"
+	default:
+		return "track"
+	}
+}
+
+// Fields are the values a template can use. Text fields are sanitised
+// before the template sees them, so they never add directories.
+type Fields struct {
+	AlbumArtist string
+	Album       string
+	Artist      string // Track artist
+	Title       string
+	Year        string
+	Genre       string
+	Track       int
+	TrackTotal  int
+	Disc        int // Position in a set, 0 for a single disc
+	Compilation bool
+}
+
+// Templates are the template texts of a scheme. Paths are relative to an
+// output root and have no file extension.
+type Templates struct {
+	Track       string
+	Compilation string
+	MultiDisc   string
+}
+
+// text returns the template of a kind
+func (t Templates) text(kind Kind) string {
+	switch kind {
+	case Compilation:
+		return t.Compilation
+	case MultiDisc:
+		return t.MultiDisc
+	default:
+		return t.Track
+	}
+}
+
+// Scheme renders library paths from a set of templates
+type Scheme struct {
+	templates map[Kind]*template.Template
+	policy    sanitize.Policy
+}
+
+// TemplateError is a template that does not parse, render or name every
+// track uniquely
+type TemplateError struct {
+	Kind Kind
+	Err  error
+}
+
+func (e *TemplateError) Error() string {
+	return fmt.Sprintf("%s template: %v", e.Kind, e.Err)
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// New compiles and checks the templates of a scheme. Names are cleaned with
+// the given policy.
+func New(templates Templates, policy sanitize.Policy) (*Scheme, error) {
+	scheme := &Scheme{templates: map[Kind]*template.Template{}, policy: policy}
+	for _, kind := range []Kind{Track, Compilation, MultiDisc} {
+		tmpl, err := parse(kind, templates.text(kind))
+		if err != nil {
+			return nil, err
+		}
+		scheme.templates[kind] = tmpl
+		if err := scheme.check(kind); err != nil {
+			return nil, err
+		}
+	}
+	return scheme, nil
+}
+
+// CheckTemplate reports whether text is usable as the template of a kind
+func CheckTemplate(kind Kind, text string, policy sanitize.Policy) error {
+	tmpl, err := parse(kind, text)
+	if err != nil {
+		return err
+	}
+	scheme := &Scheme{templates: map[Kind]*template.Template{kind: tmpl}, policy: policy}
+	return scheme.check(kind)
+}
+
+// parse compiles one template
+func parse(kind Kind, text string) (*template.Template, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, &TemplateError{kind, fmt.Errorf("cannot be empty")}
+	}
+	tmpl, err := template.New(kind.String()).Parse(text)
+	if err != nil {
+		return nil, &TemplateError{kind, err}
+	}
+	return tmpl, nil
+}
+
+// Path returns the path of a track relative to the output root, without an
+// extension. Compilations use the compilation template, other discs of a
+// set the multi-disc template.
+func (s *Scheme) Path(fields Fields) (string, error) {
+	names, err := s.render(kindOf(fields), fields)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(names...), nil
+}
+
+// FilePath returns the full path of a track's file under root, shortened
+// to the policy's length limits
+func (s *Scheme) FilePath(root string, fields Fields, ext string) (string, error) {
+	names, err := s.render(kindOf(fields), fields)
+	if err != nil {
+		return "", err
+	}
+	return s.policy.Fit(root, names, ext)
+}
+
+// kindOf picks the template for a track
+func kindOf(fields Fields) Kind {
+	switch {
+	case fields.Compilation:
+		return Compilation
+	case fields.Disc > 0:
+		return MultiDisc
+	default:
+		return Track
+	}
+}
+
+// render executes the template of a kind and returns the names of the
+// path. Field values are cleaned first so they never add directories; the
+// names are then cleaned as a whole, literal text included.
+func (s *Scheme) render(kind Kind, fields Fields) ([]string, error) {
+	clean := fields
+	for _, value := range []*string{&clean.AlbumArtist, &clean.Album, &clean.Artist, &clean.Title, &clean.Year, &clean.Genre} {
+		*value = s.policy.Clean(*value)
+	}
+
+	var buf bytes.Buffer
+	if err := s.templates[kind].Execute(&buf, clean); err != nil {
+		return nil, &TemplateError{kind, err}
+	}
+
+	var names []string
+	for _, part := range strings.Split(buf.String(), "/") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		if part == ".." || part == "." {
+			return nil, &TemplateError{kind, fmt.Errorf("renders a path that leaves the output root")}
+		}
+		names = append(names, s.policy.Name(part))
+	}
+	if len(names) == 0 {
+		return nil, &TemplateError{kind, fmt.Errorf("renders an empty path")}
+	}
+	return names, nil
+}
+
+// check renders sample discs where only the track and disc numbers tell the
+// tracks apart, rejecting templates that give two tracks the same path.
+// Two albums must also get folders of their own, as a disc's folder is
+// what is searched and cleared for earlier rips of it.
+func (s *Scheme) check(kind Kind) error {
+	discs := []int{0}
+	switch kind {
+	case Compilation:
+		discs = []int{0, 1, 2}
+	case MultiDisc:
+		discs = []int{1, 2}
+	}
+
+	seen := map[string]Fields{}
+	for _, disc := range discs {
+		// A compilation's single disc and its sets are never filed together
+		if disc == 1 {
+			clear(seen)
+		}
+		for track := 1; track <= 2; track++ {
+			fields := Sample(disc, track)
+			fields.Compilation = kind == Compilation
+			names, err := s.render(kind, fields)
+			if err != nil {
+				return err
+			}
+			path := filepath.Join(names...)
+			if other, ok := seen[path]; ok {
+				return &TemplateError{kind, fmt.Errorf("disc %d track %d and disc %d track %d both render %q",
+					other.Disc, other.Track, disc, track, path)}
+			}
+			seen[path] = fields
+		}
+	}
+
+	var folders [2]string
+	for i, album := range []string{"The Wall", "Animals"} {
+		fields := Sample(discs[0], 1)
+		fields.Album = album
+		fields.Compilation = kind == Compilation
+		names, err := s.render(kind, fields)
+		if err != nil {
+			return err
+		}
+		folders[i] = filepath.Join(names[:len(names)-1]...)
+	}
+	if folders[0] == folders[1] {
+		folder := fmt.Sprintf("%q", folders[0])
+		if folders[0] == "" {
+			folder = "the output root"
+		}
+		return &TemplateError{kind, fmt.Errorf("different albums share the folder %s; put .Album in the folder names", folder)}
+	}
+	return nil
+}
+
+// Sample returns made-up fields for previews. Every track has the same
+// title and artist so templates are judged on the numbers alone.
+func Sample(disc, track int) Fields {
+	return Fields{
+		AlbumArtist: "Pink Floyd",
+		Album:       "The Wall",
+		Artist:      "Pink Floyd",
+		Title:       "Another Brick in the Wall",
+		Year:        "1979",
+		Genre:       "Rock",
+		Track:       track,
+		TrackTotal:  13,
+		Disc:        disc,
+	}
+}
+
+// Preview renders a template of a kind with sample fields, for showing
+// while a template is edited
+func Preview(kind Kind, text string, policy sanitize.Policy) (string, error) {
+	if err := CheckTemplate(kind, text, policy); err != nil {
+		return "", err
+	}
+	tmpl, _ := parse(kind, text)
+	scheme := &Scheme{templates: map[Kind]*template.Template{kind: tmpl}, policy: policy}
+
+	fields := Sample(0, 3)
+	switch kind {
+	case Compilation:
+		fields.AlbumArtist = "Various Artists"
+		fields.Compilation = true
+	case MultiDisc:
+		fields.Disc = 2
+	}
+	names, err := scheme.render(kind, fields)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(names...), nil
+}
@@ -0,0 +1,164 @@
+package naming
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Bparsons0904/ripper/internal/sanitize"
+)
+
+// testPolicy is the default sanitisation policy
+var testPolicy = sanitize.Policy{Profile: sanitize.Windows}
+
+func TestCheckTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    Kind
+		text    string
+		wantErr string // Empty for a valid template
+	}{
+		{"default track", Track, DefaultTrackTemplate, ""},
+		{"default compilation", Compilation, DefaultCompilationTemplate, ""},
+		{"default multi-disc", MultiDisc, DefaultMultiDiscTemplate, ""},
+		{"album in folder name", Track, `{{.AlbumArtist}} - {{.Album}}/{{printf "%02d" .Track}}`, ""},
+		{"flat track", Track, `{{.AlbumArtist}} - {{.Album}} - {{printf "%02d" .Track}}`, "share the folder the output root"},
+		{"album in file name", Track, `{{.AlbumArtist}}/{{.Album}} - {{printf "%02d" .Track}} {{.Title}}`, `share the folder "Pink Floyd"`},
+		{"compilation without album", Compilation, `Various Artists/{{if .Disc}}{{.Disc}}-{{end}}{{.Track}}`, `share the folder "Various Artists"`},
+		{"title only", Track, `{{.AlbumArtist}}/{{.Album}}/{{.Title}}`, "disc 0 track 1 and disc 0 track 2"},
+		{"set without disc", MultiDisc, `{{.AlbumArtist}}/{{.Album}}/{{printf "%02d" .Track}}`, "disc 1 track 1 and disc 2 track 1"},
+		{"compilation set without disc", Compilation, `Various Artists/{{.Album}}/{{.Track}}`, "disc 1 track 1 and disc 2 track 1"},
+		{"unknown field", Track, `{{.AlbumArtist}}/{{.Label}}/{{.Track}}`, "can't evaluate field Label"},
+		{"unclosed action", Track, `{{.AlbumArtist}}/{{.Track`, "unclosed action"},
+		{"empty", MultiDisc, "  ", "cannot be empty"},
+		{"parent directory", Track, `../{{.Album}}/{{.Track}}`, "leaves the output root"},
+		{"current directory", Track, `{{.Album}}/./{{.Track}}`, "leaves the output root"},
+		{"renders nothing", Track, `{{if false}}{{.Track}}{{end}}`, "renders an empty path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckTemplate(tt.kind, tt.text, testPolicy)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("CheckTemplate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("CheckTemplate() error = %v, want one containing %q", err, tt.wantErr)
+			}
+			var templateErr *TemplateError
+			if !errors.As(err, &templateErr) || templateErr.Kind != tt.kind {
+				t.Errorf("CheckTemplate() error = %#v, want a %s TemplateError", err, tt.kind)
+			}
+		})
+	}
+}
+
+func TestNewRejectsInvalidTemplate(t *testing.T) {
+	templates := Templates{
+		Track:       DefaultTrackTemplate,
+		Compilation: DefaultCompilationTemplate,
+		MultiDisc:   `{{.AlbumArtist}}/{{.Album}}/{{.Track}}`,
+	}
+	_, err := New(templates, testPolicy)
+	var templateErr *TemplateError
+	if !errors.As(err, &templateErr) || templateErr.Kind != MultiDisc {
+		t.Errorf("New() error = %v, want a multi_disc TemplateError", err)
+	}
+}
+
+func TestPath(t *testing.T) {
+	scheme, err := New(Templates{
+		Track:       DefaultTrackTemplate,
+		Compilation: DefaultCompilationTemplate,
+		MultiDisc:   DefaultMultiDiscTemplate,
+	}, testPolicy)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		fields Fields
+		want   string
+	}{
+		{
+			name:   "single disc",
+			fields: Fields{AlbumArtist: "Band", Album: "Album", Artist: "Band", Title: "Song", Track: 3},
+			want:   "Band/Album/03_Song",
+		},
+		{
+			name:   "set",
+			fields: Fields{AlbumArtist: "Band", Album: "Album", Artist: "Band", Title: "Song", Track: 3, Disc: 2},
+			want:   "Band/Album/2-03_Song",
+		},
+		{
+			name:   "compilation",
+			fields: Fields{AlbumArtist: "Various Artists", Album: "Hits", Artist: "Singer", Title: "Song", Track: 1, Compilation: true},
+			want:   "Various Artists/Hits/01_Singer-Song",
+		},
+		{
+			name:   "compilation set",
+			fields: Fields{AlbumArtist: "Various Artists", Album: "Hits", Artist: "Singer", Title: "Song", Track: 1, Disc: 2, Compilation: true},
+			want:   "Various Artists/Hits/2-01_Singer-Song",
+		},
+		{
+			name:   "slashes and quotes",
+			fields: Fields{AlbumArtist: "AC/DC", Album: `"Live" at Donington?`, Title: "Rock 'n' Roll", Track: 1},
+			want:   "AC_DC/'Live' at Donington/01_Rock 'n' Roll",
+		},
+		{
+			name:   "dots",
+			fields: Fields{AlbumArtist: "Band", Album: "...And Justice for All", Title: "Etc...", Track: 1},
+			want:   "Band/...And Justice for All/01_Etc",
+		},
+		{
+			name:   "reserved name",
+			fields: Fields{AlbumArtist: "Con", Album: "Album", Title: "Song", Track: 1},
+			want:   "_Con/Album/01_Song",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := scheme.Path(tt.fields)
+			if err != nil {
+				t.Fatalf("Path() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Path() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreview(t *testing.T) {
+	tests := []struct {
+		name   string
+		kind   Kind
+		text   string
+		policy sanitize.Policy
+		want   string
+	}{
+		{"track", Track, DefaultTrackTemplate, testPolicy, "Pink Floyd/The Wall/03_Another Brick in the Wall"},
+		{"compilation", Compilation, DefaultCompilationTemplate, testPolicy, "Various Artists/The Wall/03_Pink Floyd-Another Brick in the Wall"},
+		{"multi-disc", MultiDisc, DefaultMultiDiscTemplate, testPolicy, "Pink Floyd/The Wall/2-03_Another Brick in the Wall"},
+		{"replace spaces", Track, DefaultTrackTemplate, sanitize.Policy{Profile: sanitize.Windows, ReplaceSpaces: true}, "Pink_Floyd/The_Wall/03_Another_Brick_in_the_Wall"},
+		{"year and genre", Track, `{{.Genre}}/{{.Year}} - {{.Album}}/{{.Track}} of {{.TrackTotal}}`, testPolicy, "Rock/1979 - The Wall/3 of 13"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Preview(tt.kind, tt.text, tt.policy)
+			if err != nil {
+				t.Fatalf("Preview() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Preview() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Preview(Track, `{{.Album}}`, testPolicy); err == nil {
+		t.Error("Preview() of a colliding template succeeded")
+	}
+}
@@ -0,0 +1,360 @@
+package ripper
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Bparsons0904/ripper/internal/tagging"
+)
+
+// abcdeExtractor rips with abcde, which reads, encodes, tags and files the
+// tracks itself
+type abcdeExtractor struct {
+	r   *CDRipper
+	run toolRunner
+}
+
+// Rip runs abcde for the whole disc. abcde encodes every format from one
+// read into the work directory; each track is then retagged and filed under
+// its target's root by the naming templates.
+func (e *abcdeExtractor) Rip(ctx context.Context, cdInfo *CDInfo, targets []OutputTarget) error {
+	r := e.r
+
+	// A fresh work directory also keeps abcde from resuming a stale rip
+	stageDir, err := r.workDir(cdInfo)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(stageDir)
+
+	// abcde removes its WAV files as it encodes, leaving nothing to checksum
+	if r.config.CDRipping.AccurateRip.Enabled {
+		r.sendProgress(ProgressInfo{
+			TotalTracks: cdInfo.TrackCount,
+			Status:      "AccurateRip verification needs the cdparanoia backend, ripping unverified",
+		})
+	}
+	if r.config.CDRipping.Secure.Enabled {
+		r.sendProgress(ProgressInfo{
+			TotalTracks: cdInfo.TrackCount,
+			Status:      "Secure mode needs the cdparanoia backend, reading each track once",
+		})
+	}
+	if r.config.CDRipping.RipMode == "image" {
+		r.sendProgress(ProgressInfo{
+			TotalTracks: cdInfo.TrackCount,
+			Status:      "Image mode needs the cdparanoia backend, ripping a file per track",
+		})
+	}
+	if cdInfo.Pregap(1) > 0 {
+		r.sendProgress(ProgressInfo{
+			TotalTracks: cdInfo.TrackCount,
+			Status:      "The disc has a pregap before track 1, which only the cdparanoia backend rips",
+		})
+	}
+
+	// Prepare abcde command
+	cmd, err := r.prepareAbcdeCommand(ctx, e.run, cdInfo, targets, stageDir)
+	if err != nil {
+		return err
+	}
+
+	// Start the command
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stderr pipe: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start abcde: %w", err)
+	}
+
+	// Monitor progress until abcde closes its output, then collect the exit status
+	lastError := r.monitorAbcdeProgress(stdout, stderr, cdInfo.TrackCount)
+	err = cmd.Wait()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		if lastError != "" {
+			return fmt.Errorf("abcde failed: %w: %s", err, lastError)
+		}
+		return fmt.Errorf("abcde failed: %w", err)
+	}
+
+	for _, target := range targets {
+		if err := fileAbcdeOutput(stageDir, cdInfo, target); err != nil {
+			return fmt.Errorf("failed to file %s output: %w", target.Format, err)
+		}
+	}
+
+	return nil
+}
+
+// fileAbcdeOutput retags the tracks abcde staged for a target and moves
+// them into the library. abcde tags from the CDDB record, which cannot
+// carry fields such as MusicBrainz IDs and ISRCs.
+func fileAbcdeOutput(stageDir string, cdInfo *CDInfo, target OutputTarget) error {
+	// abcde names each format's directory after its output type
+	dir := filepath.Join(stageDir, abcdeOutputs[target.Format].outputType)
+
+	for n := 1; n <= cdInfo.TrackCount; n++ {
+		track := cdInfo.Track(n)
+		staged := filepath.Join(dir, fmt.Sprintf("%02d.%s", n, fileExtension(target.Format)))
+		if _, err := os.Stat(staged); err != nil {
+			return fmt.Errorf("abcde did not write track %d", n)
+		}
+
+		if tagging.Supported(staged) {
+			if err := tagging.WriteFile(staged, trackTags(cdInfo, track)); err != nil {
+				return err
+			}
+		}
+
+		path, err := target.trackPath(cdInfo, track)
+		if err != nil {
+			return err
+		}
+		if err := moveFile(staged, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PrepareAbcdeConfig writes an abcde configuration that makes abcde use our
+// metadata for cdInfo instead of doing its own lookup. The disc is stored as
+// an xmcd record in a local CDDB cache which abcde is told to always use.
+// Output is staged under stageDir, one directory per format holding a file
+// per track number, with the encoder options set from each format's encoder
+// profile. Tracks are named by our own templates when they are filed.
+// It returns the path of the configuration file to pass with -c.
+func (r *CDRipper) PrepareAbcdeConfig(cdInfo *CDInfo, targets []OutputTarget, stageDir string) (string, error) {
+	abcdeDir := filepath.Join(r.config.Paths.Config, "abcde")
+	cddbDir := filepath.Join(abcdeDir, "cddb")
+	if err := os.MkdirAll(cddbDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create abcde directory: %w", err)
+	}
+
+	discID := cdInfo.CDDBDiscID
+	if discID == "" {
+		id, err := CDDBDiscID(cdInfo.Offsets)
+		if err != nil {
+			return "", err
+		}
+		discID = id
+	}
+
+	recordFile, err := os.Create(filepath.Join(cddbDir, discID))
+	if err != nil {
+		return "", fmt.Errorf("failed to write CDDB record: %w", err)
+	}
+	if err := WriteXMCD(recordFile, cdInfo); err != nil {
+		recordFile.Close()
+		return "", fmt.Errorf("failed to write CDDB record: %w", err)
+	}
+	if err := recordFile.Close(); err != nil {
+		return "", fmt.Errorf("failed to write CDDB record: %w", err)
+	}
+
+	settings := []struct{ name, value string }{
+		{"CDDBMETHOD", "cddb"},
+		{"CDDBUSELOCAL", "y"},
+		{"CDDBLOCALPOLICY", "always"},
+		{"CDDBLOCALDIR", cddbDir},
+		{"CDDBCOPYLOCAL", "n"},
+		{"INTERACTIVE", "n"},
+		// abcde runs its own encoder pool alongside the read
+		{"MAXPROCS", strconv.Itoa(r.encoderWorkers())},
+		// Two-digit track numbers on every disc, as the staged files are found by them
+		{"PADTRACKS", "y"},
+		{"OUTPUTDIR", stageDir},
+		// abcde keeps its working directory here rather than the current directory
+		{"WAVOUTPUTDIR", stageDir},
+		{"OUTPUTFORMAT", "${OUTPUT}/${TRACKNUM}"},
+		{"VAOUTPUTFORMAT", "${OUTPUT}/${TRACKNUM}"},
+	}
+	for _, target := range targets {
+		output := abcdeOutputs[target.Format]
+		if options := r.abcdeEncoderOptions(target); output.options != "" && options != "" {
+			settings = append(settings, struct{ name, value string }{output.options, options})
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# Generated by media-ripper - changes will be overwritten\n")
+	for _, setting := range settings {
+		sb.WriteString(fmt.Sprintf("%s=%s\n", setting.name, shellQuote(setting.value)))
+	}
+
+	configPath := filepath.Join(abcdeDir, "abcde.conf")
+	if err := os.WriteFile(configPath, []byte(sb.String()), 0644); err != nil {
+		return "", fmt.Errorf("failed to write abcde config: %w", err)
+	}
+
+	return configPath, nil
+}
+
+// abcdeOutputs maps formats to abcde's output type, which also names the
+// format's directory, and the abcde.conf variable holding its encoder
+// options. abcde has no ALAC encoder.
+var abcdeOutputs = map[string]struct{ outputType, options string }{
+	"flac":    {"flac", "FLACOPTS"},
+	"mp3":     {"mp3", "LAMEOPTS"},
+	"ogg":     {"ogg", "OGGENCOPTS"},
+	"opus":    {"opus", "OPUSENCOPTS"},
+	"m4a":     {"m4a", "FDKAACENCOPTS"},
+	"wavpack": {"wv", "WVENCOPTS"},
+	"wav":     {"wav", ""},
+}
+
+// abcdeEncoderOptions returns the encoder options abcde passes for a
+// target. abcde encodes AAC with fdkaac rather than ffmpeg.
+func (r *CDRipper) abcdeEncoderOptions(target OutputTarget) string {
+	if target.Format == "m4a" {
+		return fmt.Sprintf("--bitrate %d", r.config.CDRipping.Encoders.M4A.Bitrate)
+	}
+	return strings.Join(target.encoderArgs, " ")
+}
+
+// shellQuote single-quotes a value for a shell script such as abcde.conf
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// prepareAbcdeCommand prepares the abcde command with proper configuration
+func (r *CDRipper) prepareAbcdeCommand(ctx context.Context, run toolRunner, cdInfo *CDInfo, targets []OutputTarget, stageDir string) (*exec.Cmd, error) {
+	formats := make([]string, len(targets))
+	for i, target := range targets {
+		output, ok := abcdeOutputs[target.Format]
+		if !ok {
+			return nil, fmt.Errorf("abcde cannot produce %s output, use the cdparanoia extraction backend", target.Format)
+		}
+		formats[i] = output.outputType
+	}
+
+	// The generated config carries our metadata and output layout
+	abcdeConfig, err := r.PrepareAbcdeConfig(cdInfo, targets, stageDir)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{
+		"-c", abcdeConfig,
+		"-N", // Non-interactive, the metadata was already confirmed
+		"-o", strings.Join(formats, ","),
+		"-d", r.config.Drives.CDDrive,
+		"-a", "cddb,read,encode,tag,move,clean",
+	}
+
+	// Add other options
+	if r.config.CDRipping.AutoEject {
+		args = append(args, "-x")
+	}
+
+	return run.command(ctx, "abcde", args...)
+}
+
+// monitorAbcdeProgress turns abcde output into progress updates until both
+// streams are closed. It returns the last error line abcde printed, if any.
+func (r *CDRipper) monitorAbcdeProgress(stdout, stderr io.ReadCloser, totalTracks int) string {
+	// Regex patterns to match abcde output
+	trackPattern := regexp.MustCompile(`Grabbing track (\d+)`)
+	encodePattern := regexp.MustCompile(`Encoding track (\d+)`)
+
+	if totalTracks <= 0 {
+		totalTracks = 1
+	}
+
+	// Both streams update the stages, so count tracks rather than trusting
+	// the order of the lines. abcde only says when an encode starts, once per
+	// format; a track counts as encoded once the next one starts.
+	var stageMu sync.Mutex
+	tracksRead := 0
+	encodesStarted := map[int]bool{}
+
+	handleLine := func(line string) {
+		stageMu.Lock()
+		defer stageMu.Unlock()
+
+		var status string
+		var track int
+		if matches := trackPattern.FindStringSubmatch(line); len(matches) > 1 {
+			if n, err := strconv.Atoi(matches[1]); err == nil {
+				// Grabbing a track means the ones before it are read
+				track = n
+				tracksRead = max(tracksRead, n-1)
+				status = fmt.Sprintf("Ripping track %d of %d...", n, totalTracks)
+			}
+		}
+
+		if matches := encodePattern.FindStringSubmatch(line); len(matches) > 1 {
+			if n, err := strconv.Atoi(matches[1]); err == nil {
+				track = n
+				encodesStarted[n] = true
+				status = fmt.Sprintf("Encoding track %d of %d...", n, totalTracks)
+			}
+		}
+
+		if status == "" {
+			return
+		}
+
+		// A track being encoded has been read
+		tracksRead = max(tracksRead, len(encodesStarted))
+		encoded := max(len(encodesStarted)-1, 0)
+		readProgress := tracksRead * 100 / totalTracks
+		encodeProgress := encoded * 100 / totalTracks
+		r.sendProgress(ProgressInfo{
+			CurrentTrack:   track,
+			TotalTracks:    totalTracks,
+			Status:         status,
+			ReadProgress:   readProgress,
+			EncodeProgress: encodeProgress,
+			TracksEncoded:  encoded,
+			Progress:       combinedProgress(readProgress, encodeProgress),
+		})
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	lastError := ""
+
+	// abcde reports progress on both streams depending on the step
+	for _, stream := range []io.Reader{stdout, stderr} {
+		wg.Add(1)
+		go func(stream io.Reader) {
+			defer wg.Done()
+			scanner := bufio.NewScanner(stream)
+			for scanner.Scan() {
+				line := scanner.Text()
+				handleLine(line)
+
+				// Remember errors for the failure message but let the exit status decide
+				if strings.Contains(strings.ToLower(line), "error") {
+					mu.Lock()
+					lastError = line
+					mu.Unlock()
+				}
+			}
+		}(stream)
+	}
+
+	wg.Wait()
+	return lastError
+}
@@ -0,0 +1,123 @@
+package ripper
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Bparsons0904/ripper/internal/config"
+	"github.com/Bparsons0904/ripper/internal/tagging"
+)
+
+// newAbcdeTestRipper returns a ripper that runs the abcde stub in testdata,
+// with every directory under a temporary one and no online lookups
+func newAbcdeTestRipper(t *testing.T) *CDRipper {
+	t.Helper()
+	stub, err := filepath.Abs(filepath.Join("testdata", "abcde"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.Paths.Music = filepath.Join(dir, "music")
+	cfg.Paths.Config = filepath.Join(dir, "config")
+	cfg.Paths.Work = filepath.Join(dir, "work")
+	cfg.Drives.CDDrive = "/dev/null"
+	cfg.Tools.AbcdePath = stub
+	cfg.CDRipping.OutputFormat = []string{"flac", "mp3"}
+	cfg.CDRipping.AutoEject = false
+	cfg.CDRipping.CoverArt.Enabled = false
+	return NewCDRipper(cfg)
+}
+
+func TestAbcdeRip(t *testing.T) {
+	r := newAbcdeTestRipper(t)
+
+	offsets := []int{150, 15000, 30000, 45000}
+	discID, err := CDDBDiscID(offsets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cdInfo := &CDInfo{
+		CDDBDiscID: discID,
+		TrackCount: 3,
+		Offsets:    offsets,
+		Artist:     "Band",
+		Album:      "Album",
+		Year:       "1999",
+		Tracks: []TrackInfo{
+			{Number: 1, Title: "One", Artist: "Band"},
+			{Number: 2, Title: "Two", Artist: "Band"},
+			{Number: 3, Title: "Three", Artist: "Band"},
+		},
+	}
+
+	// A leftover work directory would make abcde resume a stale rip
+	stale := filepath.Join(r.config.Paths.Work, discID, "abcde."+discID)
+	if err := os.MkdirAll(stale, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.RipCD(cdInfo); err != nil {
+		t.Fatalf("RipCD() error = %v", err)
+	}
+
+	albumDir := filepath.Join(r.config.Paths.Music, "Band", "Album")
+	for _, format := range []string{"flac", "mp3"} {
+		for _, track := range cdInfo.Tracks {
+			path := filepath.Join(albumDir, fmt.Sprintf("%02d_%s.%s", track.Number, track.Title, format))
+			tags, err := tagging.ReadFile(path)
+			if err != nil {
+				t.Errorf("track %d %s: %v", track.Number, format, err)
+				continue
+			}
+			// abcde's own tags are replaced by ours
+			if tags.Title != track.Title || tags.Album != "Album" || tags.TrackNumber != track.Number ||
+				tags.TrackTotal != 3 || tags.Year != "1999" || tags.CDDBDiscID != discID {
+				t.Errorf("track %d %s tags = %+v", track.Number, format, tags)
+			}
+		}
+	}
+
+	// The work directory does not outlive the rip
+	if entries, _ := os.ReadDir(r.config.Paths.Work); len(entries) != 0 {
+		t.Errorf("work directory left behind: %v", entries)
+	}
+}
+
+func TestPrepareAbcdeConfigEncoderOptions(t *testing.T) {
+	r := newAbcdeTestRipper(t)
+	r.config.CDRipping.OutputFormat = []string{"flac", "mp3", "wavpack", "m4a"}
+	r.config.CDRipping.Encoders.WavPack.Mode = "high"
+	targets, err := r.OutputTargets()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offsets := []int{150, 15000}
+	discID, _ := CDDBDiscID(offsets)
+	cdInfo := &CDInfo{CDDBDiscID: discID, TrackCount: 1, Offsets: offsets, Tracks: []TrackInfo{{Number: 1}}}
+	path, err := r.PrepareAbcdeConfig(cdInfo, targets, t.TempDir())
+	if err != nil {
+		t.Fatalf("PrepareAbcdeConfig() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each format's options go in the variable abcde reads them from
+	for _, want := range []string{
+		"FLACOPTS='-5'",
+		"LAMEOPTS='-V 2'",
+		"WVENCOPTS='-h'",
+		"FDKAACENCOPTS='--bitrate 256'",
+	} {
+		if !strings.Contains(string(data), "\n"+want+"\n") {
+			t.Errorf("abcde.conf does not set %s:\n%s", want, data)
+		}
+	}
+}
@@ -0,0 +1,385 @@
+package ripper
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotInAccurateRip is returned when the database has no rips of a disc
+var ErrNotInAccurateRip = errors.New("disc not in the AccurateRip database")
+
+// accurateRipSkipSamples is how much of the disc's first and last track the
+// checksums leave out: five sectors of stereo samples, which drives with
+// different read offsets cannot all read
+const accurateRipSkipSamples = 5 * 588
+
+// maxAccurateRipBytes bounds a database response; a popular disc with many
+// pressings is a few kilobytes
+const maxAccurateRipBytes = 1 << 20
+
+// AccurateRipID identifies a disc in the AccurateRip database
+type AccurateRipID struct {
+	TrackCount int
+	ID1        uint32 // Sum of the track offsets and the lead-out
+	ID2        uint32 // Sum of the track offsets weighted by track number
+	CDDB       uint32
+}
+
+// AccurateRipDiscID computes the AccurateRip IDs of a disc from a table of
+// contents laid out like CDInfo.Offsets
+func AccurateRipDiscID(offsets []int) (AccurateRipID, error) {
+	cddb, err := CDDBDiscID(offsets)
+	if err != nil {
+		return AccurateRipID{}, err
+	}
+	cddbID, err := strconv.ParseUint(cddb, 16, 32)
+	if err != nil {
+		return AccurateRipID{}, fmt.Errorf("invalid CDDB disc ID %q: %w", cddb, err)
+	}
+
+	// AccurateRip counts sectors from the start of the program area, without
+	// the two second lead-in included in the offsets
+	id := AccurateRipID{TrackCount: len(offsets) - 1, CDDB: uint32(cddbID)}
+	for i, offset := range offsets {
+		sector := uint32(offset - 150)
+		id.ID1 += sector
+		id.ID2 += max(sector, 1) * uint32(i+1)
+	}
+	return id, nil
+}
+
+// String returns the name of the disc's database file
+func (id AccurateRipID) String() string {
+	return fmt.Sprintf("dBAR-%03d-%08x-%08x-%08x", id.TrackCount, id.ID1, id.ID2, id.CDDB)
+}
+
+// path returns where the disc's file lives on the server, filed by the low
+// digits of the first ID
+func (id AccurateRipID) path() string {
+	return fmt.Sprintf("accuraterip/%x/%x/%x/%s.bin", id.ID1&0xf, id.ID1>>4&0xf, id.ID1>>8&0xf, id)
+}
+
+// AccurateRipEntry is one set of checksums submitted for a disc, usually one
+// pressing. Tracks are in disc order.
+type AccurateRipEntry struct {
+	Tracks []AccurateRipTrack
+}
+
+// AccurateRipTrack is a track's checksum and how many rips agree with it.
+// The checksum is a v1 or a v2 checksum; the database does not say which.
+type AccurateRipTrack struct {
+	Confidence int
+	CRC        uint32
+	FrameCRC   uint32 // Checksum of one frame, used to find a drive's read offset
+}
+
+// AccurateRipDatabase looks up the submitted checksums of a disc
+type AccurateRipDatabase interface {
+	// Lookup returns every entry for the disc, or ErrNotInAccurateRip
+	Lookup(ctx context.Context, id AccurateRipID) ([]AccurateRipEntry, error)
+}
+
+// AccurateRipClient reads the AccurateRip database over HTTP
+type AccurateRipClient struct {
+	baseURL    string
+	userAgent  string
+	httpClient *http.Client
+}
+
+// NewAccurateRipClient creates a client for the given server, e.g. http://www.accuraterip.com
+func NewAccurateRipClient(baseURL, userAgent string) *AccurateRipClient {
+	return &AccurateRipClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		userAgent:  userAgent,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Lookup downloads and parses the disc's database file
+func (c *AccurateRipClient) Lookup(ctx context.Context, id AccurateRipID) ([]AccurateRipEntry, error) {
+	endpoint := fmt.Sprintf("%s/%s", c.baseURL, id.path())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AccurateRip request: %w", err)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("AccurateRip request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%s: %w", id, ErrNotInAccurateRip)
+	default:
+		return nil, fmt.Errorf("AccurateRip returned status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxAccurateRipBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download AccurateRip results: %w", err)
+	}
+	if len(data) > maxAccurateRipBytes {
+		return nil, fmt.Errorf("AccurateRip response is larger than %d bytes", maxAccurateRipBytes)
+	}
+	return ParseAccurateRip(data, id)
+}
+
+// ParseAccurateRip parses a database file: a run of entries, each a header
+// with the track count and the disc's three IDs followed by the confidence,
+// checksum and frame checksum of every track, little-endian. Entries for a
+// different disc are dropped.
+func ParseAccurateRip(data []byte, id AccurateRipID) ([]AccurateRipEntry, error) {
+	const headerSize, trackSize = 13, 9
+
+	var entries []AccurateRipEntry
+	for len(data) > 0 {
+		if len(data) < headerSize {
+			return nil, fmt.Errorf("AccurateRip entry header is truncated")
+		}
+		count := int(data[0])
+		entryID := AccurateRipID{
+			TrackCount: count,
+			ID1:        binary.LittleEndian.Uint32(data[1:]),
+			ID2:        binary.LittleEndian.Uint32(data[5:]),
+			CDDB:       binary.LittleEndian.Uint32(data[9:]),
+		}
+		data = data[headerSize:]
+		if len(data) < count*trackSize {
+			return nil, fmt.Errorf("AccurateRip entry for %s is truncated", entryID)
+		}
+
+		entry := AccurateRipEntry{Tracks: make([]AccurateRipTrack, count)}
+		for i := range entry.Tracks {
+			track := data[i*trackSize:]
+			entry.Tracks[i] = AccurateRipTrack{
+				Confidence: int(track[0]),
+				CRC:        binary.LittleEndian.Uint32(track[1:]),
+				FrameCRC:   binary.LittleEndian.Uint32(track[5:]),
+			}
+		}
+		data = data[count*trackSize:]
+
+		if entryID == id {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// AccurateRipResult is the verification of a rip against the database
+type AccurateRipResult struct {
+	ID     AccurateRipID
+	Found  bool   // The database has rips of the disc
+	Error  string // Why the database could not be asked, if it could not
+	Tracks []TrackVerification
+}
+
+// Accurate reports whether every track matched the database
+func (r *AccurateRipResult) Accurate() bool {
+	if r == nil || len(r.Tracks) == 0 {
+		return false
+	}
+	for _, track := range r.Tracks {
+		if !track.Accurate() {
+			return false
+		}
+	}
+	return true
+}
+
+// TrackVerification is a track's checksums and what the database says about
+// them
+type TrackVerification struct {
+	Track      int
+	CRCv1      uint32
+	CRCv2      uint32
+	Found      bool // The database has rips of the disc
+	Version    int  // Checksum version that matched, 0 for no match
+	Confidence int  // Rips in the database that agree with this one
+}
+
+// Accurate reports whether the track matched a rip in the database
+func (v TrackVerification) Accurate() bool {
+	return v.Version > 0
+}
+
+// String describes the result, e.g. "accurately ripped (confidence 12, v2)"
+func (v TrackVerification) String() string {
+	switch {
+	case v.Accurate():
+		return fmt.Sprintf("accurately ripped (confidence %d, v%d)", v.Confidence, v.Version)
+	case v.Found:
+		return "no match"
+	default:
+		return "not in database"
+	}
+}
+
+// verifyTrack compares a track's checksums with the database entries. The
+// v2 checksum is preferred; entries are separate pressings, so the best
+// matching one gives the confidence.
+func verifyTrack(track int, v1, v2 uint32, entries []AccurateRipEntry) TrackVerification {
+	result := TrackVerification{Track: track, CRCv1: v1, CRCv2: v2, Found: len(entries) > 0}
+	for _, entry := range entries {
+		if track < 1 || track > len(entry.Tracks) {
+			continue
+		}
+		submitted := entry.Tracks[track-1]
+		version := 0
+		switch submitted.CRC {
+		case v2:
+			version = 2
+		case v1:
+			version = 1
+		}
+		if version == 0 || submitted.Confidence == 0 {
+			continue
+		}
+		if submitted.Confidence > result.Confidence ||
+			(submitted.Confidence == result.Confidence && version > result.Version) {
+			result.Confidence = submitted.Confidence
+			result.Version = version
+		}
+	}
+	return result
+}
+
+// accurateRipSum computes the v1 and v2 checksums of a track as its 16-bit
+// stereo PCM is written to it. The first five sectors of the disc's first
+// track and the last five of its last track are left out.
+type accurateRipSum struct {
+	position   uint32 // Samples are counted from one
+	checkStart uint32
+	checkEnd   uint32
+	v1, v2     uint32
+	partial    []byte // Start of a sample split across writes
+}
+
+// newAccurateRipSum starts the checksums of a track samples long
+func newAccurateRipSum(samples int, first, last bool) *accurateRipSum {
+	s := &accurateRipSum{position: 1, checkEnd: uint32(samples)}
+	if first {
+		s.checkStart = accurateRipSkipSamples - 1
+	}
+	if last {
+		s.checkEnd = uint32(max(samples-accurateRipSkipSamples, 0))
+	}
+	return s
+}
+
+// Write adds audio to the checksums
+func (s *accurateRipSum) Write(p []byte) (int, error) {
+	n := len(p)
+	if len(s.partial) > 0 {
+		fill := min(4-len(s.partial), len(p))
+		s.partial = append(s.partial, p[:fill]...)
+		p = p[fill:]
+		if len(s.partial) < 4 {
+			return n, nil
+		}
+		s.add(binary.LittleEndian.Uint32(s.partial))
+		s.partial = s.partial[:0]
+	}
+	for ; len(p) >= 4; p = p[4:] {
+		s.add(binary.LittleEndian.Uint32(p))
+	}
+	s.partial = append(s.partial, p...)
+	return n, nil
+}
+
+// add adds one stereo sample
+func (s *accurateRipSum) add(sample uint32) {
+	if s.position >= s.checkStart && s.position <= s.checkEnd {
+		s.v1 += s.position * sample
+		product := uint64(s.position) * uint64(sample)
+		s.v2 += uint32(product) + uint32(product>>32)
+	}
+	s.position++
+}
+
+// wavDataSize reads a WAV header up to its audio, returning the size of the
+// audio in bytes. Only CD audio is accepted: 16-bit stereo PCM at 44.1kHz.
+func wavDataSize(r io.Reader) (uint32, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return 0, fmt.Errorf("failed to read WAV header: %w", err)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return 0, fmt.Errorf("not a WAV file")
+	}
+
+	format := false
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return 0, fmt.Errorf("WAV file has no audio: %w", err)
+		}
+		size := binary.LittleEndian.Uint32(header[4:])
+
+		switch string(header[0:4]) {
+		case "fmt ":
+			chunk := make([]byte, size+size%2)
+			if _, err := io.ReadFull(r, chunk); err != nil || size < 16 {
+				return 0, fmt.Errorf("WAV format is truncated")
+			}
+			pcm := binary.LittleEndian.Uint16(chunk[0:]) == 1
+			channels := binary.LittleEndian.Uint16(chunk[2:])
+			rate := binary.LittleEndian.Uint32(chunk[4:])
+			bits := binary.LittleEndian.Uint16(chunk[14:])
+			if !pcm || channels != 2 || rate != 44100 || bits != 16 {
+				return 0, fmt.Errorf("WAV file is not CD audio")
+			}
+			format = true
+		case "data":
+			if !format {
+				return 0, fmt.Errorf("WAV audio comes before its format")
+			}
+			return size, nil
+		default:
+			// Chunks are padded to an even size
+			if _, err := io.CopyN(io.Discard, r, int64(size+size%2)); err != nil {
+				return 0, fmt.Errorf("WAV file is truncated")
+			}
+		}
+	}
+}
+
+// lookupAccurateRip starts the disc's verification by fetching its rips from
+// the database. A failed lookup is recorded in the result; the checksums
+// are still worth keeping.
+func (r *CDRipper) lookupAccurateRip(ctx context.Context, cdInfo *CDInfo) (*AccurateRipResult, []AccurateRipEntry) {
+	id, err := AccurateRipDiscID(cdInfo.Offsets)
+	if err != nil {
+		return &AccurateRipResult{Error: err.Error()}, nil
+	}
+	result := &AccurateRipResult{ID: id}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	entries, err := r.accurateRip.Lookup(ctx, id)
+	switch {
+	case errors.Is(err, ErrNotInAccurateRip):
+	case err != nil:
+		result.Error = err.Error()
+	default:
+		result.Found = len(entries) > 0
+	}
+	return result, entries
+}
+
+// SetAccurateRipDatabase replaces the database rips are verified against
+func (r *CDRipper) SetAccurateRipDatabase(db AccurateRipDatabase) {
+	r.accurateRip = db
+}
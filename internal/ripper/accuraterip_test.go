@@ -0,0 +1,200 @@
+package ripper
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Bparsons0904/ripper/internal/config"
+)
+
+// accurateRipTestOffsets is a short disc of 40, 60 and 30 sectors. Its
+// database file in testdata holds two pressings and an entry for another
+// disc:
+//
+//	track  pressing 1          pressing 2
+//	1      v2, confidence 12   v1, confidence 3
+//	2      v1, confidence 7    v2, confidence 7
+//	3      wrong checksum      v2, confidence 0
+var accurateRipTestOffsets = []int{150, 190, 250, 280}
+
+// accurateRipTestSums are the v1 and v2 checksums of each track of the disc
+// holding accurateRipTestAudio, worked out independently of this package
+var accurateRipTestSums = [][2]uint32{
+	{0x48afb09f, 0x50ccd9da},
+	{0x29257838, 0x3bb18b27},
+	{0x4f44120a, 0x527c5bce},
+}
+
+// accurateRipTestAudio returns a track's stereo samples, numbered across the
+// disc so no two tracks are alike
+func accurateRipTestAudio(track int) []byte {
+	first := (accurateRipTestOffsets[track-1] - 150) * 588
+	samples := (accurateRipTestOffsets[track] - accurateRipTestOffsets[track-1]) * 588
+	audio := make([]byte, 0, samples*4)
+	for i := range samples {
+		audio = binary.LittleEndian.AppendUint32(audio, uint32(first+i)*2654435761+12345)
+	}
+	return audio
+}
+
+func TestAccurateRipDiscID(t *testing.T) {
+	id, err := AccurateRipDiscID(accurateRipTestOffsets)
+	if err != nil {
+		t.Fatalf("AccurateRipDiscID() error = %v", err)
+	}
+	if got, want := id.String(), "dBAR-003-0000010e-00000385-07000103"; got != want {
+		t.Errorf("AccurateRipDiscID() = %s, want %s", got, want)
+	}
+	if got, want := id.path(), "accuraterip/e/0/1/dBAR-003-0000010e-00000385-07000103.bin"; got != want {
+		t.Errorf("path() = %s, want %s", got, want)
+	}
+}
+
+func TestParseAccurateRip(t *testing.T) {
+	id, _ := AccurateRipDiscID(accurateRipTestOffsets)
+	data, err := os.ReadFile(filepath.Join("testdata", id.String()+".bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ParseAccurateRip(data, id)
+	if err != nil {
+		t.Fatalf("ParseAccurateRip() error = %v", err)
+	}
+	// The entry for another disc is dropped
+	if len(entries) != 2 {
+		t.Fatalf("ParseAccurateRip() = %d entries, want 2", len(entries))
+	}
+	want := AccurateRipTrack{Confidence: 12, CRC: accurateRipTestSums[0][1], FrameCRC: 0x1badd00d}
+	if got := entries[0].Tracks[0]; got != want {
+		t.Errorf("first track = %+v, want %+v", got, want)
+	}
+
+	for _, size := range []int{5, 13 + 9} {
+		if _, err := ParseAccurateRip(data[:size], id); err == nil {
+			t.Errorf("ParseAccurateRip() of %d bytes succeeded", size)
+		}
+	}
+}
+
+// newAccurateRipTestRipper returns a ripper verifying against a stand-in
+// database serving the testdata files, and the disc with a WAV file per track
+func newAccurateRipTestRipper(t *testing.T) (*CDRipper, *CDInfo, []string) {
+	t.Helper()
+	server := httptest.NewServer(http.StripPrefix("/accuraterip/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, filepath.Join("testdata", filepath.Base(r.URL.Path)))
+	})))
+	t.Cleanup(server.Close)
+
+	cfg := config.DefaultConfig()
+	cfg.CDRipping.AccurateRip.URL = server.URL
+	cfg.CDRipping.CTDB.Enabled = false
+	r := &CDRipper{config: cfg}
+	r.SetAccurateRipDatabase(NewAccurateRipClient(cfg.CDRipping.AccurateRip.URL, "media-ripper-test/1.0"))
+
+	cdInfo := &CDInfo{TrackCount: len(accurateRipTestOffsets) - 1, Offsets: accurateRipTestOffsets}
+	var wavs []string
+	for n := 1; n <= cdInfo.TrackCount; n++ {
+		audio := accurateRipTestAudio(n)
+		path := filepath.Join(t.TempDir(), fmt.Sprintf("track%02d.wav", n))
+		writeTestWAV(t, path, audio)
+		wavs = append(wavs, path)
+	}
+	return r, cdInfo, wavs
+}
+
+// writeTestWAV writes CD audio as a WAV file
+func writeTestWAV(t *testing.T, path string, audio []byte) {
+	t.Helper()
+	if err := writeWAV(path, uint32(len(audio)), bytes.NewReader(audio)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAccurateRipVerify(t *testing.T) {
+	r, cdInfo, wavs := newAccurateRipTestRipper(t)
+
+	v := r.newRipVerifier(context.Background(), cdInfo)
+	if cdInfo.AccurateRip.Error != "" || !cdInfo.AccurateRip.Found {
+		t.Fatalf("lookup = %+v, want the disc found", cdInfo.AccurateRip)
+	}
+
+	// Each track takes the best pressing's confidence, preferring v2
+	want := []TrackVerification{
+		{Track: 1, Found: true, Version: 2, Confidence: 12},
+		{Track: 2, Found: true, Version: 2, Confidence: 7},
+		{Track: 3, Found: true},
+	}
+	for i, path := range wavs {
+		got, err := v.addTrack(i+1, path)
+		if err != nil {
+			t.Fatalf("addTrack(%d) error = %v", i+1, err)
+		}
+		want[i].CRCv1, want[i].CRCv2 = accurateRipTestSums[i][0], accurateRipTestSums[i][1]
+		if *got != want[i] {
+			t.Errorf("track %d = %+v, want %+v", i+1, *got, want[i])
+		}
+	}
+	if cdInfo.AccurateRip.Accurate() {
+		t.Error("Accurate() = true with track 3 unmatched")
+	}
+	if got := cdInfo.AccurateRip.Tracks[2].String(); got != "no match" {
+		t.Errorf("track 3 String() = %q", got)
+	}
+}
+
+func TestAccurateRipVerifyV1(t *testing.T) {
+	r, cdInfo, wavs := newAccurateRipTestRipper(t)
+
+	// A database that only knows the v1 checksum of track 1
+	id, _ := AccurateRipDiscID(cdInfo.Offsets)
+	r.SetAccurateRipDatabase(accurateRipStub{id: {{Tracks: []AccurateRipTrack{
+		{Confidence: 4, CRC: accurateRipTestSums[0][0]},
+		{Confidence: 4, CRC: 1},
+		{Confidence: 4, CRC: 2},
+	}}}})
+
+	v := r.newRipVerifier(context.Background(), cdInfo)
+	got, err := v.addTrack(1, wavs[0])
+	if err != nil {
+		t.Fatalf("addTrack() error = %v", err)
+	}
+	if got.Version != 1 || got.Confidence != 4 || got.String() != "accurately ripped (confidence 4, v1)" {
+		t.Errorf("track 1 = %+v, want a v1 match", *got)
+	}
+}
+
+func TestAccurateRipNotInDatabase(t *testing.T) {
+	r, cdInfo, wavs := newAccurateRipTestRipper(t)
+	cdInfo.Offsets = []int{150, 190, 250, 281} // Not in testdata
+
+	v := r.newRipVerifier(context.Background(), cdInfo)
+	if cdInfo.AccurateRip.Found || cdInfo.AccurateRip.Error != "" {
+		t.Fatalf("lookup = %+v, want not found without an error", cdInfo.AccurateRip)
+	}
+	got, err := v.addTrack(1, wavs[0])
+	if err != nil {
+		t.Fatalf("addTrack() error = %v", err)
+	}
+	if got.Accurate() || got.String() != "not in database" {
+		t.Errorf("track 1 = %+v, want not in database", *got)
+	}
+}
+
+// accurateRipStub is a database holding the entries of each disc
+type accurateRipStub map[AccurateRipID][]AccurateRipEntry
+
+func (s accurateRipStub) Lookup(ctx context.Context, id AccurateRipID) ([]AccurateRipEntry, error) {
+	entries, ok := s[id]
+	if !ok {
+		return nil, ErrNotInAccurateRip
+	}
+	return entries, nil
+}
@@ -0,0 +1,182 @@
+package ripper
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// MetadataCandidate is one possible release for a disc returned by a lookup
+type MetadataCandidate struct {
+	Source     string // "musicbrainz" or "cddb"
+	ID         string // MusicBrainz release ID or "category/discid" for CDDB
+	Artist     string
+	Album      string
+	Date       string
+	Country    string
+	Barcode    string
+	Genre      string
+	TrackCount int
+	Exact      bool // the disc ID matched exactly
+	Score      int
+	Remembered bool // the user picked this candidate for the disc before
+
+	release *MusicBrainzRelease
+	record  *XMCDRecord
+}
+
+// Key identifies the candidate across lookups
+func (c *MetadataCandidate) Key() string {
+	return c.Source + ":" + c.ID
+}
+
+// ApplyTo copies the candidate's metadata onto cdInfo
+func (c *MetadataCandidate) ApplyTo(cdInfo *CDInfo) error {
+	switch {
+	case c.release != nil:
+		if err := c.release.ApplyTo(cdInfo); err != nil {
+			return err
+		}
+	case c.record != nil:
+		c.record.ApplyTo(cdInfo)
+	default:
+		return fmt.Errorf("candidate %s has no metadata attached", c.Key())
+	}
+	cdInfo.MetadataSource = c.Source
+	cdInfo.MetadataEdited = false
+	return nil
+}
+
+// newMusicBrainzCandidate builds a candidate from a MusicBrainz release
+func newMusicBrainzCandidate(release *MusicBrainzRelease, cdInfo *CDInfo) MetadataCandidate {
+	candidate := MetadataCandidate{
+		Source:  "musicbrainz",
+		ID:      release.ID,
+		Artist:  joinCredits(release.ArtistCredit),
+		Album:   release.Title,
+		Date:    release.Date,
+		Country: release.Country,
+		Barcode: release.Barcode,
+		release: release,
+	}
+
+	if medium := release.findMedium(cdInfo); medium != nil {
+		candidate.TrackCount = len(medium.Tracks)
+		for _, disc := range medium.Discs {
+			if disc.ID == cdInfo.MusicBrainzDiscID {
+				candidate.Exact = true
+			}
+		}
+	}
+
+	return candidate
+}
+
+// newCDDBCandidate builds a candidate from a CDDB match and its xmcd record
+func newCDDBCandidate(match CDDBMatch, record *XMCDRecord) MetadataCandidate {
+	return MetadataCandidate{
+		Source:     "cddb",
+		ID:         match.Category + "/" + match.DiscID,
+		Artist:     record.Artist,
+		Album:      record.Album,
+		Date:       record.Year,
+		Genre:      record.Genre,
+		TrackCount: len(record.Tracks),
+		Exact:      match.Exact,
+		record:     record,
+	}
+}
+
+// rankCandidates scores candidates against the disc and sorts them best first.
+// Ties are broken by release date, earliest first.
+func rankCandidates(candidates []MetadataCandidate, cdInfo *CDInfo) {
+	for i := range candidates {
+		candidate := &candidates[i]
+		candidate.Score = 0
+		if candidate.TrackCount == cdInfo.TrackCount {
+			candidate.Score += 40
+		}
+		if candidate.Exact {
+			candidate.Score += 30
+		}
+		if candidate.Date != "" {
+			candidate.Score += 10
+		}
+		if candidate.Country != "" {
+			candidate.Score += 5
+		}
+		if candidate.Barcode != "" {
+			candidate.Score += 5
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		// Undated releases sort last
+		if (candidates[i].Date == "") != (candidates[j].Date == "") {
+			return candidates[j].Date == ""
+		}
+		return candidates[i].Date < candidates[j].Date
+	})
+}
+
+// MetadataChoices remembers which candidate the user picked for each disc
+type MetadataChoices struct {
+	path    string
+	Choices map[string]string `toml:"choices"`
+}
+
+// LoadMetadataChoices reads remembered choices, returning an empty set if the file is missing
+func LoadMetadataChoices(path string) (*MetadataChoices, error) {
+	choices := &MetadataChoices{path: path, Choices: make(map[string]string)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return choices, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := toml.Unmarshal(data, choices); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if choices.Choices == nil {
+		choices.Choices = make(map[string]string)
+	}
+	return choices, nil
+}
+
+// Get returns the remembered candidate key for a disc
+func (m *MetadataChoices) Get(discID string) (string, bool) {
+	key, ok := m.Choices[discID]
+	return key, ok
+}
+
+// Remember stores the candidate key for a disc and saves the file
+func (m *MetadataChoices) Remember(discID, key string) error {
+	m.Choices[discID] = key
+
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return err
+	}
+
+	data, err := toml.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(m.path, data, 0644)
+}
+
+// choiceDiscID returns the ID used to remember choices for a disc
+func choiceDiscID(cdInfo *CDInfo) string {
+	if cdInfo.MusicBrainzDiscID != "" {
+		return cdInfo.MusicBrainzDiscID
+	}
+	return cdInfo.CDDBDiscID
+}
@@ -0,0 +1,115 @@
+package ripper
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRankCandidates(t *testing.T) {
+	cdInfo := &CDInfo{TrackCount: 10}
+
+	candidates := []MetadataCandidate{
+		{ID: "wrong-count", TrackCount: 12, Exact: true, Date: "1990", Country: "US", Barcode: "1"},
+		{ID: "undated", TrackCount: 10, Exact: true, Country: "GB", Barcode: "2"},
+		{ID: "later", TrackCount: 10, Exact: true, Date: "1999"},
+		{ID: "earlier", TrackCount: 10, Exact: true, Date: "1995"},
+		{ID: "inexact", TrackCount: 10, Date: "1980", Country: "US", Barcode: "3"},
+		{ID: "bare", TrackCount: 10},
+	}
+
+	rankCandidates(candidates, cdInfo)
+
+	want := []struct {
+		id    string
+		score int
+	}{
+		{"earlier", 80},
+		{"later", 80},
+		{"undated", 80},
+		{"inexact", 60},
+		{"wrong-count", 50},
+		{"bare", 40},
+	}
+
+	if len(candidates) != len(want) {
+		t.Fatalf("got %d candidates, want %d", len(candidates), len(want))
+	}
+	for i, w := range want {
+		if candidates[i].ID != w.id || candidates[i].Score != w.score {
+			t.Errorf("candidate %d = %s (%d), want %s (%d)", i, candidates[i].ID, candidates[i].Score, w.id, w.score)
+		}
+	}
+}
+
+func TestRankCandidatesResetsScore(t *testing.T) {
+	candidates := []MetadataCandidate{{ID: "a", TrackCount: 3, Score: 500}}
+
+	rankCandidates(candidates, &CDInfo{TrackCount: 3})
+
+	if candidates[0].Score != 40 {
+		t.Errorf("score = %d, want 40", candidates[0].Score)
+	}
+}
+
+func TestMetadataChoices(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config", "metadata_choices.toml")
+
+	choices, err := LoadMetadataChoices(path)
+	if err != nil {
+		t.Fatalf("LoadMetadataChoices on missing file: %v", err)
+	}
+	if _, ok := choices.Get("disc"); ok {
+		t.Error("missing file should load an empty set")
+	}
+
+	if err := choices.Remember("mbid-disc", "musicbrainz:release-1"); err != nil {
+		t.Fatalf("Remember: %v", err)
+	}
+	if err := choices.Remember("370fce16", "cddb:rock/370fce16"); err != nil {
+		t.Fatalf("Remember: %v", err)
+	}
+	if err := choices.Remember("mbid-disc", "musicbrainz:release-2"); err != nil {
+		t.Fatalf("Remember: %v", err)
+	}
+
+	loaded, err := LoadMetadataChoices(path)
+	if err != nil {
+		t.Fatalf("LoadMetadataChoices: %v", err)
+	}
+
+	tests := []struct {
+		discID string
+		want   string
+		ok     bool
+	}{
+		{"mbid-disc", "musicbrainz:release-2", true},
+		{"370fce16", "cddb:rock/370fce16", true},
+		{"unknown", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.discID, func(t *testing.T) {
+			got, ok := loaded.Get(tt.discID)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("Get(%q) = %q, %v; want %q, %v", tt.discID, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestChoiceDiscID(t *testing.T) {
+	tests := []struct {
+		name   string
+		cdInfo CDInfo
+		want   string
+	}{
+		{"prefers musicbrainz", CDInfo{MusicBrainzDiscID: "mb", CDDBDiscID: "370fce16"}, "mb"},
+		{"falls back to cddb", CDInfo{CDDBDiscID: "370fce16"}, "370fce16"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := choiceDiscID(&tt.cdInfo); got != tt.want {
+				t.Errorf("choiceDiscID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,472 @@
+package ripper
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cddbProtocolLevel is the CDDB protocol level we speak; level 6 is UTF-8
+const cddbProtocolLevel = 6
+
+// CDDBMatch is a single disc returned by a CDDB query
+type CDDBMatch struct {
+	Category string
+	DiscID   string
+	Title    string // "Artist / Album" as stored in DTITLE
+	Exact    bool
+}
+
+// XMCDRecord holds the fields of an xmcd disc record read from a CDDB server
+type XMCDRecord struct {
+	Category string
+	DiscID   string
+	Artist   string
+	Album    string
+	Year     string
+	Genre    string
+	Extended string
+	Tracks   []XMCDTrack
+}
+
+// XMCDTrack is a single TTITLEn entry of an xmcd record
+type XMCDTrack struct {
+	Artist string
+	Title  string
+}
+
+// cddbResponse is a parsed CDDB server response
+type cddbResponse struct {
+	Code   int
+	Header string
+	Lines  []string
+}
+
+// cddbTransport sends a single CDDB command and returns the server's response
+type cddbTransport interface {
+	command(ctx context.Context, cmd string) (*cddbResponse, error)
+}
+
+// CDDBClient speaks the CDDB protocol over HTTP (cddb.cgi) or CDDBP (TCP)
+type CDDBClient struct {
+	transport cddbTransport
+}
+
+// NewCDDBClient creates a client for server, which is either an http(s)://
+// URL of a cddb.cgi endpoint or a cddbp://host:port address. hello is the
+// "user host client version" string sent to identify ourselves.
+func NewCDDBClient(server, hello string) (*CDDBClient, error) {
+	helloFields := strings.Fields(hello)
+	if len(helloFields) != 4 {
+		return nil, fmt.Errorf("CDDB hello must be \"user host client version\", got %q", hello)
+	}
+
+	u, err := url.Parse(server)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CDDB server %q: %w", server, err)
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		return &CDDBClient{transport: &cddbHTTPTransport{
+			endpoint:   server,
+			hello:      helloFields,
+			httpClient: &http.Client{Timeout: 30 * time.Second},
+		}}, nil
+	case "cddbp":
+		address := u.Host
+		if u.Port() == "" {
+			address = net.JoinHostPort(u.Hostname(), "8880")
+		}
+		return &CDDBClient{transport: &cddbpTransport{
+			address: address,
+			hello:   helloFields,
+		}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported CDDB server scheme %q (use http, https or cddbp)", u.Scheme)
+	}
+}
+
+// Query looks up the matches for a disc's table of contents
+func (c *CDDBClient) Query(ctx context.Context, cdInfo *CDInfo) ([]CDDBMatch, error) {
+	if len(cdInfo.Offsets) != cdInfo.TrackCount+1 {
+		return nil, fmt.Errorf("CDDB query needs %d offsets (tracks plus lead-out), got %d", cdInfo.TrackCount+1, len(cdInfo.Offsets))
+	}
+
+	discID := cdInfo.CDDBDiscID
+	if discID == "" {
+		id, err := CDDBDiscID(cdInfo.Offsets)
+		if err != nil {
+			return nil, err
+		}
+		discID = id
+	}
+
+	args := []string{"cddb", "query", discID, strconv.Itoa(cdInfo.TrackCount)}
+	for _, offset := range cdInfo.Offsets[:cdInfo.TrackCount] {
+		args = append(args, strconv.Itoa(offset))
+	}
+	args = append(args, strconv.Itoa(cdInfo.Offsets[cdInfo.TrackCount]/75))
+
+	resp, err := c.transport.command(ctx, strings.Join(args, " "))
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.Code {
+	case 200:
+		// 200 categ discid dtitle
+		match, err := parseCDDBMatch(resp.Header)
+		if err != nil {
+			return nil, err
+		}
+		match.Exact = true
+		return []CDDBMatch{match}, nil
+	case 210, 211:
+		// 210 exact matches or 211 inexact matches, one per line
+		var matches []CDDBMatch
+		for _, line := range resp.Lines {
+			match, err := parseCDDBMatch(line)
+			if err != nil {
+				continue
+			}
+			match.Exact = resp.Code == 210
+			matches = append(matches, match)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("CDDB disc %s: %w", discID, ErrDiscNotFound)
+		}
+		return matches, nil
+	case 202:
+		return nil, fmt.Errorf("CDDB disc %s: %w", discID, ErrDiscNotFound)
+	default:
+		return nil, fmt.Errorf("CDDB query failed: %d %s", resp.Code, resp.Header)
+	}
+}
+
+// Read fetches the full xmcd record for a match
+func (c *CDDBClient) Read(ctx context.Context, match CDDBMatch) (*XMCDRecord, error) {
+	resp, err := c.transport.command(ctx, fmt.Sprintf("cddb read %s %s", match.Category, match.DiscID))
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.Code {
+	case 210:
+		record := parseXMCD(resp.Lines)
+		record.Category = match.Category
+		record.DiscID = match.DiscID
+		return record, nil
+	case 401:
+		return nil, fmt.Errorf("CDDB entry %s/%s: %w", match.Category, match.DiscID, ErrDiscNotFound)
+	default:
+		return nil, fmt.Errorf("CDDB read failed: %d %s", resp.Code, resp.Header)
+	}
+}
+
+// parseCDDBMatch parses "categ discid dtitle"
+func parseCDDBMatch(line string) (CDDBMatch, error) {
+	parts := strings.SplitN(strings.TrimSpace(line), " ", 3)
+	if len(parts) < 2 {
+		return CDDBMatch{}, fmt.Errorf("invalid CDDB match line: %q", line)
+	}
+
+	match := CDDBMatch{Category: parts[0], DiscID: parts[1]}
+	if len(parts) == 3 {
+		match.Title = parts[2]
+	}
+	return match, nil
+}
+
+// parseXMCD parses the body of an xmcd record. Keys may be repeated, in which
+// case their values are concatenated.
+func parseXMCD(lines []string) *XMCDRecord {
+	values := make(map[string]string)
+	for _, line := range lines {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		values[key] += value
+	}
+
+	record := &XMCDRecord{
+		Year:     values["DYEAR"],
+		Genre:    values["DGENRE"],
+		Extended: unescapeXMCD(values["EXTD"]),
+	}
+	record.Artist, record.Album = splitXMCDTitle(unescapeXMCD(values["DTITLE"]))
+
+	for i := 0; ; i++ {
+		title, ok := values[fmt.Sprintf("TTITLE%d", i)]
+		if !ok {
+			break
+		}
+		title = unescapeXMCD(title)
+
+		// Compilations store "Artist / Title" per track; elsewhere a slash
+		// belongs to the title, as in "Face / Off"
+		track := XMCDTrack{Title: title}
+		if artist, name, found := strings.Cut(title, " / "); found && isVariousArtists(record.Artist) {
+			track.Artist = strings.TrimSpace(artist)
+			track.Title = strings.TrimSpace(name)
+		}
+		record.Tracks = append(record.Tracks, track)
+	}
+
+	return record
+}
+
+// isVariousArtists reports whether an album artist denotes a compilation
+func isVariousArtists(artist string) bool {
+	switch strings.ToLower(strings.TrimSpace(artist)) {
+	case "various", "various artists", "va":
+		return true
+	}
+	return false
+}
+
+// splitXMCDTitle splits DTITLE into artist and album. Without a separator
+// the xmcd spec says the value is used for both.
+func splitXMCDTitle(title string) (string, string) {
+	if artist, album, found := strings.Cut(title, " / "); found {
+		return strings.TrimSpace(artist), strings.TrimSpace(album)
+	}
+	title = strings.TrimSpace(title)
+	return title, title
+}
+
+// unescapeXMCD expands the \n, \t and \\ escapes used in xmcd values
+func unescapeXMCD(value string) string {
+	replacer := strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\\`, `\`)
+	return replacer.Replace(value)
+}
+
+// ApplyTo copies the record's metadata onto cdInfo
+func (rec *XMCDRecord) ApplyTo(cdInfo *CDInfo) {
+	cdInfo.Artist = rec.Artist
+	cdInfo.Album = rec.Album
+	cdInfo.Year = rec.Year
+	cdInfo.Genre = rec.Genre
+	if cdInfo.Genre == "" {
+		cdInfo.Genre = rec.Category
+	}
+	cdInfo.Comment = rec.Extended
+	cdInfo.Compilation = isVariousArtists(rec.Artist)
+
+	for i, track := range rec.Tracks {
+		if i >= len(cdInfo.Tracks) {
+			break
+		}
+		cdInfo.Tracks[i].Title = track.Title
+		cdInfo.Tracks[i].Artist = track.Artist
+		if track.Artist == "" {
+			cdInfo.Tracks[i].Artist = rec.Artist
+		}
+	}
+}
+
+// readCDDBResponse reads a status line and, for x1x codes, the data lines up
+// to the terminating "."
+func readCDDBResponse(reader *bufio.Reader) (*cddbResponse, error) {
+	status, err := reader.ReadString('\n')
+	if err != nil && status == "" {
+		return nil, fmt.Errorf("failed to read CDDB response: %w", err)
+	}
+	status = strings.TrimRight(status, "\r\n")
+
+	codeStr, header, _ := strings.Cut(status, " ")
+	code, err := strconv.Atoi(codeStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CDDB status line: %q", status)
+	}
+
+	resp := &cddbResponse{Code: code, Header: header}
+	if (code/10)%10 != 1 {
+		return resp, nil
+	}
+
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		if line == "." {
+			return resp, nil
+		}
+		if err != nil {
+			if err == io.EOF {
+				// Some HTTP servers omit the terminator at end of body
+				if line != "" {
+					resp.Lines = append(resp.Lines, line)
+				}
+				return resp, nil
+			}
+			return nil, fmt.Errorf("failed to read CDDB response: %w", err)
+		}
+		resp.Lines = append(resp.Lines, line)
+	}
+}
+
+// cddbHTTPTransport sends commands to a cddb.cgi endpoint
+type cddbHTTPTransport struct {
+	endpoint   string
+	hello      []string
+	httpClient *http.Client
+}
+
+func (t *cddbHTTPTransport) command(ctx context.Context, cmd string) (*cddbResponse, error) {
+	query := url.Values{}
+	query.Set("cmd", cmd)
+	query.Set("hello", strings.Join(t.hello, " "))
+	query.Set("proto", strconv.Itoa(cddbProtocolLevel))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CDDB request: %w", err)
+	}
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("CDDB request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("CDDB server returned HTTP status %d", resp.StatusCode)
+	}
+
+	return readCDDBResponse(bufio.NewReader(resp.Body))
+}
+
+// cddbpTransport sends commands over a CDDBP TCP session
+type cddbpTransport struct {
+	address string
+	hello   []string
+}
+
+func (t *cddbpTransport) command(ctx context.Context, cmd string) (*cddbResponse, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", t.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to CDDB server %s: %w", t.address, err)
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(30 * time.Second)
+	}
+	conn.SetDeadline(deadline)
+
+	reader := bufio.NewReader(conn)
+
+	// Server banner: 200 (read/write) or 201 (read only)
+	banner, err := readCDDBResponse(reader)
+	if err != nil {
+		return nil, err
+	}
+	if banner.Code != 200 && banner.Code != 201 {
+		return nil, fmt.Errorf("CDDB server refused connection: %d %s", banner.Code, banner.Header)
+	}
+
+	send := func(line string) (*cddbResponse, error) {
+		if _, err := fmt.Fprintf(conn, "%s\r\n", line); err != nil {
+			return nil, fmt.Errorf("failed to send CDDB command: %w", err)
+		}
+		return readCDDBResponse(reader)
+	}
+
+	resp, err := send("cddb hello " + strings.Join(t.hello, " "))
+	if err != nil {
+		return nil, err
+	}
+	// 402 means we already said hello, which is harmless
+	if resp.Code != 200 && resp.Code != 402 {
+		return nil, fmt.Errorf("CDDB handshake failed: %d %s", resp.Code, resp.Header)
+	}
+
+	resp, err = send(fmt.Sprintf("proto %d", cddbProtocolLevel))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Code != 200 && resp.Code != 201 && resp.Code != 502 {
+		return nil, fmt.Errorf("CDDB server does not support protocol level %d: %d %s", cddbProtocolLevel, resp.Code, resp.Header)
+	}
+
+	result, err := send(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(conn, "quit\r\n")
+	return result, nil
+}
+
+// WriteXMCD writes cdInfo as an xmcd record. This is the format CDDB servers
+// return and the one abcde reads from its local CDDB cache.
+func WriteXMCD(w io.Writer, cdInfo *CDInfo) error {
+	if len(cdInfo.Offsets) != cdInfo.TrackCount+1 {
+		return fmt.Errorf("xmcd record needs %d offsets (tracks plus lead-out), got %d", cdInfo.TrackCount+1, len(cdInfo.Offsets))
+	}
+
+	discID := cdInfo.CDDBDiscID
+	if discID == "" {
+		id, err := CDDBDiscID(cdInfo.Offsets)
+		if err != nil {
+			return err
+		}
+		discID = id
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# xmcd\n#\n# Track frame offsets:\n")
+	for _, offset := range cdInfo.Offsets[:cdInfo.TrackCount] {
+		sb.WriteString(fmt.Sprintf("#\t%d\n", offset))
+	}
+	sb.WriteString(fmt.Sprintf("#\n# Disc length: %d seconds\n#\n", cdInfo.Offsets[cdInfo.TrackCount]/75))
+
+	writeField := func(key, value string) {
+		sb.WriteString(key + "=" + escapeXMCD(value) + "\n")
+	}
+
+	writeField("DISCID", discID)
+	writeField("DTITLE", cdInfo.Artist+" / "+cdInfo.Album)
+	writeField("DYEAR", cdInfo.Year)
+	writeField("DGENRE", cdInfo.Genre)
+	for i := 0; i < cdInfo.TrackCount; i++ {
+		title := fmt.Sprintf("Track %02d", i+1)
+		artist := ""
+		if i < len(cdInfo.Tracks) {
+			title = cdInfo.Tracks[i].Title
+			artist = cdInfo.Tracks[i].Artist
+		}
+		// Compilations carry the artist in each track title
+		if cdInfo.Compilation && artist != "" {
+			title = artist + " / " + title
+		}
+		writeField(fmt.Sprintf("TTITLE%d", i), title)
+	}
+	writeField("EXTD", cdInfo.Comment)
+	for i := 0; i < cdInfo.TrackCount; i++ {
+		writeField(fmt.Sprintf("EXTT%d", i), "")
+	}
+	writeField("PLAYORDER", "")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// escapeXMCD is the inverse of unescapeXMCD
+func escapeXMCD(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\t", `\t`)
+	return replacer.Replace(value)
+}
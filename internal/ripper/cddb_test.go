@@ -0,0 +1,248 @@
+package ripper
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const cddbTestHello = "user localhost media-ripper-test 1.0"
+
+// cddbTestRecords are the xmcd records the fake CDDB server holds, keyed by
+// "category discid"
+var cddbTestRecords = map[string]string{
+	"rock 370fce16": "# xmcd\n" +
+		"DISCID=370fce16\n" +
+		"DTITLE=Band / Album\n" +
+		"DYEAR=1999\n" +
+		"DGENRE=Rock\n" +
+		"TTITLE0=Face / Off\n" +
+		"TTITLE1=Long title that goes \n" +
+		"TTITLE1=on and on\n" +
+		"EXTD=Line one\\nLine two\n",
+	"misc a70de90d": "# xmcd\n" +
+		"DISCID=a70de90d\n" +
+		"DTITLE=Various Artists / Hits\n" +
+		"TTITLE0=Singer / Song\n" +
+		"TTITLE1=Other Singer / Other Song\n",
+}
+
+// fakeCDDBServer answers CDDB commands the way freedb servers did and
+// records the commands it was sent
+type fakeCDDBServer struct {
+	mu       sync.Mutex
+	commands []string
+}
+
+// respond returns the server's response to a command, with CRLF line ends
+func (s *fakeCDDBServer) respond(cmd string) string {
+	s.mu.Lock()
+	s.commands = append(s.commands, cmd)
+	s.mu.Unlock()
+
+	fields := strings.Fields(cmd)
+	switch {
+	case len(fields) >= 3 && fields[0] == "cddb" && fields[1] == "query":
+		switch fields[2] {
+		case "370fce16":
+			return "200 rock 370fce16 Band / Album\r\n"
+		case "a70de90c":
+			return "211 Found inexact matches, list follows (until terminating `.')\r\n" +
+				"rock a70de90c Band / Other Album\r\n" +
+				"misc a70de90d Various Artists / Hits\r\n" +
+				".\r\n"
+		}
+		return "202 No match found\r\n"
+	case len(fields) == 4 && fields[0] == "cddb" && fields[1] == "read":
+		record, ok := cddbTestRecords[fields[2]+" "+fields[3]]
+		if !ok {
+			return "401 Specified CDDB entry not found.\r\n"
+		}
+		return fmt.Sprintf("210 %s %s CD database entry follows (until terminating `.')\r\n", fields[2], fields[3]) +
+			strings.ReplaceAll(record, "\n", "\r\n") + ".\r\n"
+	}
+	return "500 Unrecognized command.\r\n"
+}
+
+// sent returns the commands the server was sent
+func (s *fakeCDDBServer) sent() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+// serveHTTP starts a cddb.cgi stand-in and returns its URL
+func (s *fakeCDDBServer) serveHTTP(t *testing.T) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("hello") != cddbTestHello || query.Get("proto") != "6" {
+			w.Write([]byte("500 Command syntax error: incorrect number of arguments.\r\n"))
+			return
+		}
+		w.Write([]byte(s.respond(query.Get("cmd"))))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/~cddb/cddb.cgi"
+}
+
+// serveCDDBP starts a CDDBP stand-in and returns its address. Commands are
+// only answered after the hello and proto handshake.
+func (s *fakeCDDBServer) serveCDDBP(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				fmt.Fprint(conn, "201 fake CDDBP server v1.0 ready at now\r\n")
+				hello, proto := false, false
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					line := scanner.Text()
+					switch {
+					case line == "cddb hello "+cddbTestHello:
+						hello = true
+						fmt.Fprint(conn, "200 Hello and welcome user@localhost running media-ripper-test 1.0.\r\n")
+					case line == "proto 6":
+						proto = true
+						fmt.Fprint(conn, "201 OK, CDDB protocol level now: 6\r\n")
+					case line == "quit":
+						fmt.Fprint(conn, "230 Goodbye.\r\n")
+						return
+					case !hello || !proto:
+						fmt.Fprint(conn, "409 No handshake.\r\n")
+					default:
+						fmt.Fprint(conn, s.respond(line))
+					}
+				}
+			}()
+		}
+	}()
+	return "cddbp://" + listener.Addr().String()
+}
+
+// cddbTestClients returns a client for each transport, sharing one fake
+// server
+func cddbTestClients(t *testing.T) (*fakeCDDBServer, map[string]*CDDBClient) {
+	server := &fakeCDDBServer{}
+	clients := map[string]*CDDBClient{}
+	for name, url := range map[string]string{"http": server.serveHTTP(t), "cddbp": server.serveCDDBP(t)} {
+		client, err := NewCDDBClient(url, cddbTestHello)
+		if err != nil {
+			t.Fatalf("NewCDDBClient(%s) error = %v", url, err)
+		}
+		clients[name] = client
+	}
+	return server, clients
+}
+
+func TestCDDBQuery(t *testing.T) {
+	server, clients := cddbTestClients(t)
+	for name, client := range clients {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			matches, err := client.Query(ctx, &CDInfo{TrackCount: 22, Offsets: libdiscidTOC})
+			if err != nil {
+				t.Fatalf("Query() exact error = %v", err)
+			}
+			want := CDDBMatch{Category: "rock", DiscID: "370fce16", Title: "Band / Album", Exact: true}
+			if len(matches) != 1 || matches[0] != want {
+				t.Errorf("Query() exact = %+v, want %+v", matches, want)
+			}
+
+			matches, err = client.Query(ctx, &CDInfo{TrackCount: 12, Offsets: musicBrainzDocsTOC})
+			if err != nil {
+				t.Fatalf("Query() inexact error = %v", err)
+			}
+			wantMatches := []CDDBMatch{
+				{Category: "rock", DiscID: "a70de90c", Title: "Band / Other Album"},
+				{Category: "misc", DiscID: "a70de90d", Title: "Various Artists / Hits"},
+			}
+			if len(matches) != len(wantMatches) {
+				t.Fatalf("Query() inexact = %+v, want %+v", matches, wantMatches)
+			}
+			for i := range wantMatches {
+				if matches[i] != wantMatches[i] {
+					t.Errorf("Query() inexact match %d = %+v, want %+v", i, matches[i], wantMatches[i])
+				}
+			}
+
+			_, err = client.Query(ctx, &CDInfo{TrackCount: 1, Offsets: []int{150, 150 + 180*75}})
+			if !errors.Is(err, ErrDiscNotFound) {
+				t.Errorf("Query() unknown disc error = %v, want ErrDiscNotFound", err)
+			}
+		})
+	}
+
+	// The query carries the disc ID, track count, offsets and length in seconds
+	wantQuery := "cddb query 370fce16 22 " + strings.Trim(fmt.Sprint(libdiscidTOC[:22]), "[]") + " 4048"
+	found := false
+	for _, cmd := range server.sent() {
+		found = found || cmd == wantQuery
+	}
+	if !found {
+		t.Errorf("server never got %q, got %q", wantQuery, server.sent())
+	}
+}
+
+func TestCDDBRead(t *testing.T) {
+	_, clients := cddbTestClients(t)
+	for name, client := range clients {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			record, err := client.Read(ctx, CDDBMatch{Category: "rock", DiscID: "370fce16"})
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if record.Category != "rock" || record.DiscID != "370fce16" || record.Artist != "Band" ||
+				record.Album != "Album" || record.Year != "1999" || record.Genre != "Rock" ||
+				record.Extended != "Line one\nLine two" {
+				t.Errorf("Read() = %+v", record)
+			}
+			// A slash in a single artist disc's title is part of the title
+			wantTracks := []XMCDTrack{{Title: "Face / Off"}, {Title: "Long title that goes on and on"}}
+			if len(record.Tracks) != len(wantTracks) {
+				t.Fatalf("Read() tracks = %+v, want %+v", record.Tracks, wantTracks)
+			}
+			for i := range wantTracks {
+				if record.Tracks[i] != wantTracks[i] {
+					t.Errorf("track %d = %+v, want %+v", i+1, record.Tracks[i], wantTracks[i])
+				}
+			}
+
+			record, err = client.Read(ctx, CDDBMatch{Category: "misc", DiscID: "a70de90d"})
+			if err != nil {
+				t.Fatalf("Read() compilation error = %v", err)
+			}
+			wantTracks = []XMCDTrack{{Artist: "Singer", Title: "Song"}, {Artist: "Other Singer", Title: "Other Song"}}
+			for i := range wantTracks {
+				if i >= len(record.Tracks) || record.Tracks[i] != wantTracks[i] {
+					t.Errorf("compilation tracks = %+v, want %+v", record.Tracks, wantTracks)
+					break
+				}
+			}
+
+			_, err = client.Read(ctx, CDDBMatch{Category: "jazz", DiscID: "370fce16"})
+			if !errors.Is(err, ErrDiscNotFound) {
+				t.Errorf("Read() missing entry error = %v, want ErrDiscNotFound", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package ripper
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Bparsons0904/ripper/internal/drives"
+)
+
+// containerStopTimeout is how long a cancelled container gets to shut down
+// before the docker client is killed
+const containerStopTimeout = 30 * time.Second
+
+// containerBackend runs the same steps as the native backend, but each tool
+// runs in a throwaway container with the drive passed through. Host
+// directories are mounted at the same paths so no paths need translating.
+type containerBackend struct {
+	r *CDRipper
+
+	mu         sync.Mutex
+	imageReady bool
+}
+
+// Detect reads the disc with cd-discid inside the container
+func (b *containerBackend) Detect(ctx context.Context) (*CDInfo, error) {
+	return b.r.readDisc(ctx, b)
+}
+
+// Lookup queries the configured metadata service. Lookups are plain HTTP
+// requests and need no tools, so they are made from the host.
+func (b *containerBackend) Lookup(ctx context.Context, cdInfo *CDInfo) ([]MetadataCandidate, error) {
+	return b.r.lookupCDDB(ctx, cdInfo)
+}
+
+// Rip runs the configured extraction backend inside the container
+func (b *containerBackend) Rip(ctx context.Context, cdInfo *CDInfo, targets []OutputTarget) error {
+	extractor, err := b.r.newExtractor(b)
+	if err != nil {
+		return err
+	}
+	return extractor.Rip(ctx, cdInfo, targets)
+}
+
+// Scan lists the host's drives, which are the ones that can be passed through
+func (b *containerBackend) Scan(ctx context.Context) ([]drives.DriveInfo, error) {
+	return drives.DetectDrives()
+}
+
+// Eject runs eject inside the container
+func (b *containerBackend) Eject(ctx context.Context) error {
+	return b.r.ejectDisc(ctx, b)
+}
+
+// command wraps the tool in a docker run invocation
+func (b *containerBackend) command(ctx context.Context, tool string, args ...string) (*exec.Cmd, error) {
+	docker, err := exec.LookPath("docker")
+	if err != nil {
+		return nil, fmt.Errorf("docker: %w", errToolNotFound)
+	}
+
+	if err := b.ensureImage(ctx, docker); err != nil {
+		return nil, err
+	}
+
+	cfg := b.r.config
+	runArgs := []string{
+		"run", "--rm", "--init",
+		"--entrypoint", tool,
+		"--device", cfg.Drives.CDDrive,
+		// Files in the library should belong to the user, not root
+		"--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()),
+		"--env", "HOME=/tmp",
+	}
+	if gid, ok := deviceGroup(cfg.Drives.CDDrive); ok {
+		runArgs = append(runArgs, "--group-add", strconv.Itoa(gid))
+	}
+
+	volumes, err := b.volumes()
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range volumes {
+		runArgs = append(runArgs, "--volume", dir+":"+dir)
+	}
+
+	runArgs = append(runArgs, cfg.Container.Image)
+	runArgs = append(runArgs, args...)
+
+	cmd := exec.CommandContext(ctx, docker, runArgs...)
+	// Killing the docker client would leave the container running; an
+	// interrupt is forwarded to the container, which is then removed
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = containerStopTimeout
+	return cmd, nil
+}
+
+// volumes returns the host directories mounted into the container, creating
+// them first so docker does not create them owned by root
+func (b *containerBackend) volumes() ([]string, error) {
+	paths := b.r.config.Paths
+	dirs := []string{paths.Music, paths.Movies, paths.Config, paths.Work}
+	targets, err := b.r.OutputTargets()
+	if err != nil {
+		return nil, err
+	}
+	for _, target := range targets {
+		dirs = append(dirs, target.Root)
+	}
+
+	// docker refuses the same mount point twice
+	seen := map[string]bool{}
+	var volumes []string
+	for _, dir := range dirs {
+		if dir == "" || seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+		volumes = append(volumes, dir)
+	}
+	return volumes, nil
+}
+
+// ensureImage makes the configured image available according to the pull
+// policy. The check runs once per ripper.
+func (b *containerBackend) ensureImage(ctx context.Context, docker string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.imageReady {
+		return nil
+	}
+
+	image := b.r.config.Container.Image
+	present := exec.CommandContext(ctx, docker, "image", "inspect", image).Run() == nil
+
+	switch b.r.config.Container.PullPolicy {
+	case "always":
+		if err := pullImage(ctx, docker, image); err != nil {
+			return err
+		}
+	case "never":
+		if !present {
+			return fmt.Errorf("container image %s is not present and pull_policy is never", image)
+		}
+	default: // if_not_present
+		if !present {
+			if err := pullImage(ctx, docker, image); err != nil {
+				return err
+			}
+		}
+	}
+
+	b.imageReady = true
+	return nil
+}
+
+// pullImage pulls image with docker pull
+func pullImage(ctx context.Context, docker, image string) error {
+	output, err := exec.CommandContext(ctx, docker, "pull", image).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to pull %s: %w: %s", image, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// deviceGroup returns the group owning a device node, typically cdrom, so
+// the unprivileged container user can open the drive
+func deviceGroup(device string) (int, bool) {
+	info, err := os.Stat(device)
+	if err != nil {
+		return 0, false
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return int(stat.Gid), true
+}
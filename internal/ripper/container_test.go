@@ -0,0 +1,117 @@
+package ripper
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Bparsons0904/ripper/internal/config"
+)
+
+// fakeDocker puts a docker stand-in first in PATH that logs its arguments and
+// reports the image as present when present is true
+func fakeDocker(t *testing.T, present bool) (logPath string) {
+	t.Helper()
+	dir := t.TempDir()
+	logPath = filepath.Join(dir, "docker.log")
+
+	inspect := "exit 1"
+	if present {
+		inspect = "exit 0"
+	}
+	script := fmt.Sprintf("#!/bin/sh\necho \"$*\" >>%s\n[ \"$1 $2\" = \"image inspect\" ] && %s\nexit 0\n", logPath, inspect)
+	if err := os.WriteFile(filepath.Join(dir, "docker"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+	return logPath
+}
+
+// newContainerTestBackend returns a container backend whose drive and
+// library directories live under a temporary directory
+func newContainerTestBackend(t *testing.T) (*containerBackend, *config.Config) {
+	t.Helper()
+	dir := t.TempDir()
+
+	// Any file will do as the device; its group is passed to the container
+	device := filepath.Join(dir, "sr0")
+	if err := os.WriteFile(device, nil, 0660); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Drives.CDDrive = device
+	cfg.Paths.Music = filepath.Join(dir, "music")
+	cfg.Paths.Movies = ""
+	cfg.Paths.Config = filepath.Join(dir, "config")
+	cfg.Paths.Work = filepath.Join(dir, "work")
+	cfg.Container.Image = "media-ripper:test"
+	cfg.Container.PullPolicy = "never"
+	return &containerBackend{r: NewCDRipper(cfg)}, cfg
+}
+
+func TestContainerCommand(t *testing.T) {
+	logPath := fakeDocker(t, true)
+	b, cfg := newContainerTestBackend(t)
+
+	cmd, err := b.command(context.Background(), "cdparanoia", "-d", cfg.Drives.CDDrive, "-e", "1")
+	if err != nil {
+		t.Fatalf("command() error = %v", err)
+	}
+
+	gid, ok := deviceGroup(cfg.Drives.CDDrive)
+	if !ok {
+		t.Fatal("deviceGroup() found no group for the device")
+	}
+	want := []string{
+		"run", "--rm", "--init",
+		"--entrypoint", "cdparanoia",
+		"--device", cfg.Drives.CDDrive,
+		"--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()),
+		"--env", "HOME=/tmp",
+		"--group-add", strconv.Itoa(gid),
+		// The unset movies directory is not mounted
+		"--volume", cfg.Paths.Music + ":" + cfg.Paths.Music,
+		"--volume", cfg.Paths.Config + ":" + cfg.Paths.Config,
+		"--volume", cfg.Paths.Work + ":" + cfg.Paths.Work,
+		"media-ripper:test",
+		"-d", cfg.Drives.CDDrive, "-e", "1",
+	}
+	if cmd.Args[0] != filepath.Join(filepath.Dir(logPath), "docker") {
+		t.Errorf("command runs %s, want the docker in PATH", cmd.Args[0])
+	}
+	if !reflect.DeepEqual(cmd.Args[1:], want) {
+		t.Errorf("docker arguments:\n got %q\nwant %q", cmd.Args[1:], want)
+	}
+
+	// Mounted directories are created up front so docker does not create them as root
+	for _, dir := range []string{cfg.Paths.Music, cfg.Paths.Config, cfg.Paths.Work} {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			t.Errorf("volume %s was not created", dir)
+		}
+	}
+
+	// The image is checked once per ripper
+	if _, err := b.command(context.Background(), "eject", cfg.Drives.CDDrive); err != nil {
+		t.Fatalf("second command() error = %v", err)
+	}
+	log, _ := os.ReadFile(logPath)
+	if got := strings.Count(string(log), "image inspect media-ripper:test"); got != 1 {
+		t.Errorf("image inspected %d times, want 1:\n%s", got, log)
+	}
+}
+
+func TestContainerCommandMissingImage(t *testing.T) {
+	fakeDocker(t, false)
+	b, cfg := newContainerTestBackend(t)
+
+	_, err := b.command(context.Background(), "eject", cfg.Drives.CDDrive)
+	if err == nil || !strings.Contains(err.Error(), "pull_policy is never") {
+		t.Errorf("command() error = %v, want a missing image error", err)
+	}
+}
@@ -0,0 +1,200 @@
+package ripper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	_ "image/png" // Covers on the Cover Art Archive or on disk may be PNG
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrCoverNotFound is returned when a release has no front cover
+var ErrCoverNotFound = errors.New("cover art not found")
+
+// coverFileName is the name covers are saved under in each album directory
+const coverFileName = "cover.jpg"
+
+// coverJPEGQuality is used when a cover has to be re-encoded
+const coverJPEGQuality = 90
+
+// maxCoverBytes bounds the download; the archive's originals are rarely
+// more than a few megabytes
+const maxCoverBytes = 32 << 20
+
+// CoverArtClient fetches covers from the Cover Art Archive
+type CoverArtClient struct {
+	baseURL    string
+	userAgent  string
+	httpClient *http.Client
+}
+
+// NewCoverArtClient creates a client for the given server, e.g. https://coverartarchive.org
+func NewCoverArtClient(baseURL, userAgent string) *CoverArtClient {
+	return &CoverArtClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		userAgent:  userAgent,
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// FrontCover downloads the front cover of a MusicBrainz release. The archive
+// answers with a redirect to the image, which the HTTP client follows.
+func (c *CoverArtClient) FrontCover(ctx context.Context, releaseID string) ([]byte, error) {
+	if releaseID == "" {
+		return nil, fmt.Errorf("no MusicBrainz release ID available")
+	}
+
+	endpoint := fmt.Sprintf("%s/release/%s/front", c.baseURL, url.PathEscape(releaseID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Cover Art Archive request: %w", err)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Cover Art Archive request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("release %s: %w", releaseID, ErrCoverNotFound)
+	default:
+		return nil, fmt.Errorf("Cover Art Archive returned status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCoverBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download cover: %w", err)
+	}
+	if len(data) > maxCoverBytes {
+		return nil, fmt.Errorf("cover is larger than %d bytes", maxCoverBytes)
+	}
+	return data, nil
+}
+
+// fetchCover returns the disc's front cover as a JPEG no larger than the
+// configured size. The image chosen for the disc wins over the archive; nil
+// is returned when there is no cover to use.
+func (r *CDRipper) fetchCover(ctx context.Context, cdInfo *CDInfo) ([]byte, error) {
+	coverCfg := r.config.CDRipping.CoverArt
+
+	var data []byte
+	switch {
+	case cdInfo.CoverArtPath != "":
+		var err error
+		data, err = os.ReadFile(cdInfo.CoverArtPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cover image: %w", err)
+		}
+	case coverCfg.Enabled && cdInfo.MusicBrainzReleaseID != "":
+		ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+		defer cancel()
+
+		var err error
+		data, err = r.coverArt.FrontCover(ctx, cdInfo.MusicBrainzReleaseID)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, nil
+	}
+
+	return prepareCover(data, coverCfg.MaxSize)
+}
+
+// prepareCover converts a cover to JPEG, scaling it down so neither side
+// exceeds maxSize. A JPEG that already fits is kept byte for byte.
+func prepareCover(data []byte, maxSize int) ([]byte, error) {
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode cover image: %w", err)
+	}
+
+	bounds := img.Bounds()
+	fits := maxSize == 0 || (bounds.Dx() <= maxSize && bounds.Dy() <= maxSize)
+	if fits && format == "jpeg" {
+		return data, nil
+	}
+	if !fits {
+		img = scaleImage(img, maxSize)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: coverJPEGQuality}); err != nil {
+		return nil, fmt.Errorf("failed to encode cover: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// scaleImage shrinks img so its longer side is maxSize, keeping the aspect
+// ratio. Each output pixel averages the source pixels it covers.
+func scaleImage(img image.Image, maxSize int) image.Image {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	dstW, dstH := maxSize, maxSize
+	if srcW > srcH {
+		dstH = max(1, srcH*maxSize/srcW)
+	} else {
+		dstW = max(1, srcW*maxSize/srcH)
+	}
+
+	src := image.NewRGBA(image.Rect(0, 0, srcW, srcH))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0, y1 := y*srcH/dstH, max((y+1)*srcH/dstH, y*srcH/dstH+1)
+		for x := 0; x < dstW; x++ {
+			x0, x1 := x*srcW/dstW, max((x+1)*srcW/dstW, x*srcW/dstW+1)
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride+x0*4 : sy*src.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+
+			n := (y1 - y0) * (x1 - x0)
+			offset := y*dst.Stride + x*4
+			for i := range sum {
+				dst.Pix[offset+i] = uint8(sum[i] / n)
+			}
+		}
+	}
+	return dst
+}
+
+// saveCover writes the cover into every directory the disc's tracks were
+// filed in
+func saveCover(cover []byte, cdInfo *CDInfo, targets []OutputTarget) error {
+	dirs, err := albumDirs(cdInfo, targets)
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create album directory: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, coverFileName), cover, 0644); err != nil {
+			return fmt.Errorf("failed to save cover: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package ripper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Bparsons0904/ripper/internal/config"
+)
+
+const coverTestReleaseID = "5b4e8a4e-0000-4000-8000-000000000001"
+
+// transparentGIF is a 1x1 GIF, a format covers are not accepted in
+var transparentGIF = []byte("GIF89a\x01\x00\x01\x00\x80\x00\x00\x00\x00\x00\xff\xff\xff" +
+	"!\xf9\x04\x01\x00\x00\x00\x00,\x00\x00\x00\x00\x01\x00\x01\x00\x00\x02\x02D\x01\x00;")
+
+// newCoverTestRipper returns a ripper fetching covers no larger than maxSize
+// from a stand-in archive. The archive redirects the front cover of
+// coverTestReleaseID to an image served with status and body.
+func newCoverTestRipper(t *testing.T, maxSize, status int, body []byte) *CDRipper {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/release/"+coverTestReleaseID+"/front", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("User-Agent"); got != "media-ripper-test/1.0" {
+			t.Errorf("User-Agent = %q", got)
+		}
+		if status == http.StatusNotFound {
+			http.NotFound(w, r)
+			return
+		}
+		http.Redirect(w, r, "/images/front", http.StatusTemporaryRedirect)
+	})
+	mux.HandleFunc("/images/front", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write(body)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	cfg := config.DefaultConfig()
+	cfg.CDRipping.CoverArt.MaxSize = maxSize
+	return &CDRipper{
+		config:   cfg,
+		coverArt: NewCoverArtClient(server.URL+"/", "media-ripper-test/1.0"),
+	}
+}
+
+// testImage encodes a width by height image, left half red and right half
+// blue, as JPEG or PNG
+func testImage(t *testing.T, format string, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= width/2 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+
+	var buf bytes.Buffer
+	var err error
+	if format == "png" {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// decodeCover checks a cover is a JPEG and decodes it
+func decodeCover(t *testing.T, cover []byte) image.Image {
+	t.Helper()
+	img, format, err := image.Decode(bytes.NewReader(cover))
+	if err != nil {
+		t.Fatalf("cover does not decode: %v", err)
+	}
+	if format != "jpeg" {
+		t.Errorf("cover format = %s, want jpeg", format)
+	}
+	return img
+}
+
+func TestFetchCoverFound(t *testing.T) {
+	original := testImage(t, "jpeg", 300, 300)
+	r := newCoverTestRipper(t, 1000, http.StatusOK, original)
+
+	cover, err := r.fetchCover(context.Background(), &CDInfo{MusicBrainzReleaseID: coverTestReleaseID})
+	if err != nil {
+		t.Fatalf("fetchCover() error = %v", err)
+	}
+	// A JPEG that fits is kept as downloaded
+	if !bytes.Equal(cover, original) {
+		t.Error("fetchCover() re-encoded a JPEG that fits")
+	}
+}
+
+func TestFetchCoverPNG(t *testing.T) {
+	r := newCoverTestRipper(t, 1000, http.StatusOK, testImage(t, "png", 200, 100))
+
+	cover, err := r.fetchCover(context.Background(), &CDInfo{MusicBrainzReleaseID: coverTestReleaseID})
+	if err != nil {
+		t.Fatalf("fetchCover() error = %v", err)
+	}
+	if size := decodeCover(t, cover).Bounds().Size(); size != image.Pt(200, 100) {
+		t.Errorf("cover size = %v, want 200x100", size)
+	}
+}
+
+func TestFetchCoverNotFound(t *testing.T) {
+	r := newCoverTestRipper(t, 1000, http.StatusNotFound, nil)
+
+	_, err := r.fetchCover(context.Background(), &CDInfo{MusicBrainzReleaseID: coverTestReleaseID})
+	if !errors.Is(err, ErrCoverNotFound) {
+		t.Errorf("fetchCover() error = %v, want ErrCoverNotFound", err)
+	}
+}
+
+func TestFetchCoverResized(t *testing.T) {
+	r := newCoverTestRipper(t, 500, http.StatusOK, testImage(t, "jpeg", 2000, 1000))
+
+	cover, err := r.fetchCover(context.Background(), &CDInfo{MusicBrainzReleaseID: coverTestReleaseID})
+	if err != nil {
+		t.Fatalf("fetchCover() error = %v", err)
+	}
+	img := decodeCover(t, cover)
+	if size := img.Bounds().Size(); size != image.Pt(500, 250) {
+		t.Fatalf("cover size = %v, want 500x250", size)
+	}
+	// Scaling keeps the picture: red on the left, blue on the right
+	left, right := color.RGBAModel.Convert(img.At(100, 125)).(color.RGBA), color.RGBAModel.Convert(img.At(400, 125)).(color.RGBA)
+	if left.R < 200 || left.B > 55 || right.B < 200 || right.R > 55 {
+		t.Errorf("scaled colours = %v and %v, want red and blue", left, right)
+	}
+}
+
+func TestFetchCoverUnsupportedFormat(t *testing.T) {
+	r := newCoverTestRipper(t, 1000, http.StatusOK, transparentGIF)
+
+	if _, err := r.fetchCover(context.Background(), &CDInfo{MusicBrainzReleaseID: coverTestReleaseID}); err == nil {
+		t.Error("fetchCover() accepted a GIF cover")
+	}
+}
+
+func TestFetchCoverServerError(t *testing.T) {
+	r := newCoverTestRipper(t, 1000, http.StatusServiceUnavailable, nil)
+
+	_, err := r.fetchCover(context.Background(), &CDInfo{MusicBrainzReleaseID: coverTestReleaseID})
+	if err == nil || errors.Is(err, ErrCoverNotFound) {
+		t.Errorf("fetchCover() error = %v, want a server error", err)
+	}
+}
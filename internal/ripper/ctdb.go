@@ -0,0 +1,279 @@
+package ripper
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotInCTDB is returned when the CUETools Database has no rips of a disc
+var ErrNotInCTDB = errors.New("disc not in the CUETools Database")
+
+// ctdbSkipBytes is how much audio the CTDB checksum leaves out at each end
+// of the disc: five sectors, as AccurateRip does
+const ctdbSkipBytes = 5 * 2352
+
+// maxCTDBBytes bounds a lookup response
+const maxCTDBBytes = 1 << 20
+
+// CTDBTOC returns the disc's table of contents as CTDB writes it: the start
+// sector of every track and the lead-out, counted from the start of the
+// program area and separated by colons
+func CTDBTOC(offsets []int) (string, error) {
+	if len(offsets) < 2 {
+		return "", fmt.Errorf("need at least one track offset and the lead-out, got %d values", len(offsets))
+	}
+	sectors := make([]string, len(offsets))
+	for i, offset := range offsets {
+		sectors[i] = strconv.Itoa(offset - 150)
+	}
+	return strings.Join(sectors, ":"), nil
+}
+
+// CTDBEntry is one rip of a disc submitted to the database. Submissions
+// that agree are counted together as the entry's confidence.
+type CTDBEntry struct {
+	ID         string
+	Confidence int
+	CRC        uint32   // CRC32 of the whole disc
+	TrackCRCs  []uint32 // CRC32 of each track, in disc order
+	HasParity  bool     // The entry has parity data CUETools can repair rips with
+}
+
+// CTDBDatabase looks up the submitted rips of a disc
+type CTDBDatabase interface {
+	// Lookup returns every entry for the disc's table of contents, or
+	// ErrNotInCTDB
+	Lookup(ctx context.Context, toc string) ([]CTDBEntry, error)
+}
+
+// CTDBClient reads the CUETools Database over HTTP
+type CTDBClient struct {
+	baseURL    string
+	userAgent  string
+	httpClient *http.Client
+}
+
+// NewCTDBClient creates a client for the given server, e.g. http://db.cuetools.net
+func NewCTDBClient(baseURL, userAgent string) *CTDBClient {
+	return &CTDBClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		userAgent:  userAgent,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// ctdbResponse is the XML answer to a lookup
+type ctdbResponse struct {
+	Entries []struct {
+		ID         string `xml:"id,attr"`
+		Confidence int    `xml:"confidence,attr"`
+		CRC32      string `xml:"crc32,attr"`
+		TrackCRCs  string `xml:"trackcrcs,attr"`
+		TOC        string `xml:"toc,attr"`
+		HasParity  string `xml:"hasparity,attr"`
+	} `xml:"entry"`
+}
+
+// Lookup asks the database for the rips of a table of contents
+func (c *CTDBClient) Lookup(ctx context.Context, toc string) ([]CTDBEntry, error) {
+	params := url.Values{}
+	params.Set("version", "3")
+	params.Set("ctdb", "1")
+	params.Set("toc", toc)
+
+	endpoint := fmt.Sprintf("%s/lookup2.php?%s", c.baseURL, params.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CTDB request: %w", err)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("CTDB request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%s: %w", toc, ErrNotInCTDB)
+	default:
+		return nil, fmt.Errorf("CTDB returned status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCTDBBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download CTDB results: %w", err)
+	}
+	if len(data) > maxCTDBBytes {
+		return nil, fmt.Errorf("CTDB response is larger than %d bytes", maxCTDBBytes)
+	}
+
+	entries, err := ParseCTDB(data, toc)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%s: %w", toc, ErrNotInCTDB)
+	}
+	return entries, nil
+}
+
+// ParseCTDB parses a lookup response. Entries for a different table of
+// contents, which a fuzzy lookup returns, are dropped.
+func ParseCTDB(data []byte, toc string) ([]CTDBEntry, error) {
+	var response ctdbResponse
+	if err := xml.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse CTDB response: %w", err)
+	}
+
+	var entries []CTDBEntry
+	for _, raw := range response.Entries {
+		if raw.TOC != "" && raw.TOC != toc {
+			continue
+		}
+		crc, err := strconv.ParseUint(raw.CRC32, 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CTDB checksum %q: %w", raw.CRC32, err)
+		}
+		entry := CTDBEntry{
+			ID:         raw.ID,
+			Confidence: raw.Confidence,
+			CRC:        uint32(crc),
+			HasParity:  raw.HasParity != "" && raw.HasParity != "0",
+		}
+		for _, field := range strings.Fields(raw.TrackCRCs) {
+			trackCRC, err := strconv.ParseUint(field, 16, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CTDB track checksum %q: %w", field, err)
+			}
+			entry.TrackCRCs = append(entry.TrackCRCs, uint32(trackCRC))
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// CTDBResult is the verification of a rip against the CUETools Database
+type CTDBResult struct {
+	TOC        string
+	CRC        uint32
+	TrackCRCs  []uint32
+	Found      bool   // The database has rips of the disc
+	Error      string // Why the database could not be asked, if it could not
+	Confidence int    // Submissions that match the whole disc
+	Entries    int    // Distinct rips submitted for the disc
+
+	// When nothing matches, the closest submission tells which tracks to
+	// re-rip: those whose checksum differs. Track checksums cannot say which
+	// of a track's sectors are off, so the tracks' length bounds the damage.
+	DifferingTracks       []int
+	DifferingTrackSectors int  // Length of the differing tracks in sectors
+	Repairable            bool // The closest submission has parity data for a repair
+}
+
+// Accurate reports whether the rip matched a submission
+func (r *CTDBResult) Accurate() bool {
+	return r != nil && r.Confidence > 0
+}
+
+// String describes the result, e.g. "accurately ripped (confidence 8)"
+func (r *CTDBResult) String() string {
+	switch {
+	case r.Error != "":
+		return fmt.Sprintf("lookup failed (%s)", r.Error)
+	case r.Accurate():
+		return fmt.Sprintf("accurately ripped (confidence %d)", r.Confidence)
+	case !r.Found:
+		return "not in database"
+	case len(r.DifferingTracks) == 0:
+		return fmt.Sprintf("no match among %d submissions", r.Entries)
+	default:
+		tracks := make([]string, len(r.DifferingTracks))
+		for i, track := range r.DifferingTracks {
+			tracks[i] = strconv.Itoa(track)
+		}
+		noun := "track"
+		if len(tracks) > 1 {
+			noun = "tracks"
+		}
+		return fmt.Sprintf("differs from the closest submission in %s %s totalling %d sectors",
+			noun, strings.Join(tracks, ", "), r.DifferingTrackSectors)
+	}
+}
+
+// compare matches the rip's checksums against the database entries. Without
+// a match on the whole disc the entry agreeing on the most tracks is taken
+// as the closest, with confidence breaking ties.
+func (r *CTDBResult) compare(entries []CTDBEntry, offsets []int) {
+	r.Entries = len(entries)
+	r.Found = len(entries) > 0
+	for _, entry := range entries {
+		if entry.CRC == r.CRC {
+			r.Confidence += entry.Confidence
+		}
+	}
+	if r.Confidence > 0 || !r.Found {
+		return
+	}
+
+	closest, agreeing := -1, -1
+	for i, entry := range entries {
+		if len(entry.TrackCRCs) != len(r.TrackCRCs) {
+			continue
+		}
+		n := 0
+		for track, crc := range entry.TrackCRCs {
+			if crc == r.TrackCRCs[track] {
+				n++
+			}
+		}
+		if n > agreeing || (n == agreeing && entry.Confidence > entries[closest].Confidence) {
+			closest, agreeing = i, n
+		}
+	}
+	if closest < 0 {
+		return // Submissions without track checksums cannot narrow it down
+	}
+
+	for track, crc := range entries[closest].TrackCRCs {
+		if crc != r.TrackCRCs[track] {
+			r.DifferingTracks = append(r.DifferingTracks, track+1)
+			r.DifferingTrackSectors += offsets[track+1] - offsets[track]
+		}
+	}
+	r.Repairable = entries[closest].HasParity
+}
+
+// lookupCTDB fetches the disc's submissions from the database. A failed
+// lookup is recorded in the result.
+func (r *CDRipper) lookupCTDB(ctx context.Context, cdInfo *CDInfo) (*CTDBResult, []CTDBEntry) {
+	toc, err := CTDBTOC(cdInfo.Offsets)
+	if err != nil {
+		return &CTDBResult{Error: err.Error()}, nil
+	}
+	result := &CTDBResult{TOC: toc}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	entries, err := r.ctdb.Lookup(ctx, toc)
+	if err != nil && !errors.Is(err, ErrNotInCTDB) {
+		result.Error = err.Error()
+	}
+	return result, entries
+}
+
+// SetCTDBDatabase replaces the database rips are checked against
+func (r *CDRipper) SetCTDBDatabase(db CTDBDatabase) {
+	r.ctdb = db
+}
@@ -0,0 +1,69 @@
+package ripper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCTDBCompare(t *testing.T) {
+	offsets := []int{150, 1150, 3150, 3650} // Tracks of 1000, 2000 and 500 sectors
+	rip := []uint32{0x11, 0x22, 0x33}
+
+	tests := []struct {
+		name    string
+		crc     uint32
+		entries []CTDBEntry
+		want    CTDBResult
+		message string
+	}{
+		{
+			name:    "not in database",
+			message: "not in database",
+		},
+		{
+			name: "whole disc matches",
+			crc:  0xabcd,
+			entries: []CTDBEntry{
+				{CRC: 0xabcd, Confidence: 5, TrackCRCs: rip},
+				{CRC: 0xabcd, Confidence: 2},
+				{CRC: 0x1234, Confidence: 9, TrackCRCs: []uint32{0x11, 0x99, 0x33}},
+			},
+			want:    CTDBResult{Found: true, Entries: 3, Confidence: 7},
+			message: "accurately ripped (confidence 7)",
+		},
+		{
+			name: "closest submission",
+			crc:  0xabcd,
+			entries: []CTDBEntry{
+				{CRC: 0x1, Confidence: 50, TrackCRCs: []uint32{0x99, 0x98, 0x97}},
+				{CRC: 0x2, Confidence: 1, TrackCRCs: []uint32{0x11, 0x98, 0x97}},
+				{CRC: 0x3, Confidence: 3, TrackCRCs: []uint32{0x11, 0x98, 0x97}, HasParity: true},
+			},
+			want: CTDBResult{Found: true, Entries: 3, DifferingTracks: []int{2, 3},
+				DifferingTrackSectors: 2500, Repairable: true},
+			message: "differs from the closest submission in tracks 2, 3 totalling 2500 sectors",
+		},
+		{
+			name:    "submissions without track checksums",
+			crc:     0xabcd,
+			entries: []CTDBEntry{{CRC: 0x1, Confidence: 4}},
+			want:    CTDBResult{Found: true, Entries: 1},
+			message: "no match among 1 submissions",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &CTDBResult{CRC: tt.crc, TrackCRCs: rip}
+			result.compare(tt.entries, offsets)
+
+			tt.want.CRC, tt.want.TrackCRCs = tt.crc, rip
+			if !reflect.DeepEqual(*result, tt.want) {
+				t.Errorf("compare() = %+v, want %+v", *result, tt.want)
+			}
+			if got := result.String(); got != tt.message {
+				t.Errorf("String() = %q, want %q", got, tt.message)
+			}
+		})
+	}
+}
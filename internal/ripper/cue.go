@@ -0,0 +1,224 @@
+package ripper
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CueSheet describes the rip of a disc as a cue sheet over its track files,
+// keeping the gap layout. With the append policy a track's pregap is
+// indexed at the end of the file before it; with prepend it starts the
+// track's own file. A hidden track is indexed as track 1's pregap, and a
+// silent one that was not ripped is left to the player as PREGAP.
+// fileName names the file of a track relative to the cue sheet.
+func CueSheet(cdInfo *CDInfo, policy, fileType string, fileName func(track int) string) string {
+	var sb strings.Builder
+	writeCueHeader(&sb, cdInfo)
+
+	file := func(n int) {
+		fmt.Fprintf(&sb, "FILE %s %s\n", cueString(fileName(n)), fileType)
+	}
+	index := func(number, sectors int) {
+		fmt.Fprintf(&sb, "    INDEX %02d %s\n", number, cueTime(sectors))
+	}
+
+	for n := 1; n <= cdInfo.TrackCount; n++ {
+		pregap := cdInfo.Pregap(n)
+		switch {
+		case n == 1 && cdInfo.HiddenTrack:
+			file(0)
+			writeCueTrack(&sb, cdInfo, 1)
+			index(0, 0)
+			file(1)
+			index(1, 0)
+		case n == 1 && policy != "prepend":
+			file(1)
+			writeCueTrack(&sb, cdInfo, 1)
+			if pregap > 0 {
+				fmt.Fprintf(&sb, "    PREGAP %s\n", cueTime(pregap))
+			}
+			index(1, 0)
+		case policy == "prepend":
+			file(n)
+			writeCueTrack(&sb, cdInfo, n)
+			if pregap > 0 {
+				index(0, 0)
+			}
+			index(1, pregap)
+		case pregap > 0:
+			// The pregap ends the previous track's file
+			_, previous := cdInfo.trackSectors(n - 1)
+			writeCueTrack(&sb, cdInfo, n)
+			index(0, previous-pregap)
+			file(n)
+			index(1, 0)
+		default:
+			file(n)
+			writeCueTrack(&sb, cdInfo, n)
+			index(1, 0)
+		}
+	}
+	return sb.String()
+}
+
+// ImageCueSheet describes a disc image as a cue sheet. The image holds the
+// disc as it plays from the start of the program area, so every index sits
+// where the table of contents puts it: a track's pregap at INDEX 00 and its
+// start at INDEX 01. fileName is the image's name relative to the cue sheet.
+func ImageCueSheet(cdInfo *CDInfo, fileType, fileName string) string {
+	var sb strings.Builder
+	writeCueHeader(&sb, cdInfo)
+	fmt.Fprintf(&sb, "FILE %s %s\n", cueString(fileName), fileType)
+	for n := 1; n <= cdInfo.TrackCount; n++ {
+		start, _ := cdInfo.trackSectors(n)
+		writeCueTrack(&sb, cdInfo, n)
+		if pregap := cdInfo.Pregap(n); pregap > 0 {
+			fmt.Fprintf(&sb, "    INDEX 00 %s\n", cueTime(start-pregap))
+		}
+		fmt.Fprintf(&sb, "    INDEX 01 %s\n", cueTime(start))
+	}
+	return sb.String()
+}
+
+// writeCueHeader writes the disc's lines of a cue sheet
+func writeCueHeader(sb *strings.Builder, cdInfo *CDInfo) {
+	if cdInfo.Genre != "" {
+		fmt.Fprintf(sb, "REM GENRE %s\n", cueString(cdInfo.Genre))
+	}
+	if cdInfo.Year != "" {
+		fmt.Fprintf(sb, "REM DATE %s\n", cdInfo.Year)
+	}
+	if cdInfo.CDDBDiscID != "" {
+		fmt.Fprintf(sb, "REM DISCID %s\n", strings.ToUpper(cdInfo.CDDBDiscID))
+	}
+	if catalog := cueCatalog(cdInfo.Barcode); catalog != "" {
+		fmt.Fprintf(sb, "CATALOG %s\n", catalog)
+	}
+	fmt.Fprintf(sb, "PERFORMER %s\n", cueString(cdInfo.Artist))
+	fmt.Fprintf(sb, "TITLE %s\n", cueString(cdInfo.Album))
+}
+
+// writeCueTrack writes the TRACK line of a cue sheet and the track's
+// metadata
+func writeCueTrack(sb *strings.Builder, cdInfo *CDInfo, n int) {
+	track := cdInfo.Track(n)
+	fmt.Fprintf(sb, "  TRACK %02d AUDIO\n", n)
+	fmt.Fprintf(sb, "    TITLE %s\n", cueString(track.Title))
+	fmt.Fprintf(sb, "    PERFORMER %s\n", cueString(track.Artist))
+	if track.ISRC != "" {
+		fmt.Fprintf(sb, "    ISRC %s\n", track.ISRC)
+	}
+}
+
+// cueCatalog returns a barcode as a cue sheet CATALOG, the disc's 13 digit
+// media catalog number. A 12 digit UPC is an EAN with a leading zero; other
+// barcodes are left out.
+func cueCatalog(barcode string) string {
+	if strings.Trim(barcode, "0123456789") != "" {
+		return ""
+	}
+	switch len(barcode) {
+	case 12:
+		return "0" + barcode
+	case 13:
+		return barcode
+	default:
+		return ""
+	}
+}
+
+// cueTime formats sectors as a cue sheet time, mm:ss:ff
+func cueTime(sectors int) string {
+	return fmt.Sprintf("%02d:%02d:%02d", sectors/(75*60), sectors/75%60, sectors%75)
+}
+
+// cueString quotes a cue sheet value. Cue sheets have no escapes, so double
+// quotes become single ones.
+func cueString(value string) string {
+	return `"` + strings.ReplaceAll(value, `"`, "'") + `"`
+}
+
+// cueFileType is the FILE type cue sheets give a format's files
+func cueFileType(format string) string {
+	if format == "mp3" {
+		return "MP3"
+	}
+	return "WAVE"
+}
+
+// saveCueSheets writes a cue sheet for each target into every directory
+// the disc's tracks or image were filed in. Directories holding several
+// formats get one cue sheet per format.
+func (r *CDRipper) saveCueSheets(cdInfo *CDInfo, targets []OutputTarget) error {
+	dirs, err := albumDirs(cdInfo, targets)
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range dirs {
+		var filed []OutputTarget
+		for _, target := range targets {
+			path, err := target.trackPath(cdInfo, cdInfo.Track(1))
+			if err != nil {
+				return err
+			}
+			if filepath.Dir(path) == dir {
+				filed = append(filed, target)
+			}
+		}
+
+		for _, target := range filed {
+			var sheet string
+			if target.image {
+				path, err := target.imagePath(cdInfo)
+				if err != nil {
+					return err
+				}
+				sheet = ImageCueSheet(cdInfo, cueFileType(target.Format), filepath.Base(path))
+			} else {
+				var nameErr error
+				fileName := func(track int) string {
+					path, err := target.trackPath(cdInfo, cdInfo.Track(track))
+					if err != nil {
+						nameErr = err
+						return ""
+					}
+					if rel, err := filepath.Rel(dir, path); err == nil {
+						return filepath.ToSlash(rel)
+					}
+					return path
+				}
+				sheet = CueSheet(cdInfo, r.config.CDRipping.Gaps.Policy, cueFileType(target.Format), fileName)
+				if nameErr != nil {
+					return nameErr
+				}
+			}
+
+			name := filepath.Base(dir)
+			if len(filed) > 1 {
+				name += "." + target.Format
+			}
+			if err := os.WriteFile(filepath.Join(dir, name+".cue"), []byte(sheet), 0644); err != nil {
+				return fmt.Errorf("failed to save cue sheet: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// cueSheets lists the cue sheets in a directory
+func cueSheets(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var sheets []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.EqualFold(filepath.Ext(entry.Name()), ".cue") {
+			sheets = append(sheets, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return sheets
+}
@@ -0,0 +1,92 @@
+package ripper
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// cueTestDisc is a three track disc with a 32 second pregap before track 1
+// and pregaps of two and one seconds before tracks 2 and 3
+func cueTestDisc() *CDInfo {
+	return &CDInfo{
+		CDDBDiscID: "a70de90c",
+		TrackCount: 3,
+		Offsets:    []int{2550, 17550, 32550, 47550},
+		Pregaps:    []int{2400, 150, 75},
+		Artist:     "Band",
+		Album:      `The "Quoted" Album`,
+		Year:       "1999",
+		Genre:      "Rock",
+		Tracks: []TrackInfo{
+			{Number: 1, Title: "One", Artist: "Band", ISRC: "USABC9900001"},
+			{Number: 2, Title: "Two", Artist: "Guest"},
+			{Number: 3, Title: "Three", Artist: "Band"},
+		},
+	}
+}
+
+func TestCueSheet(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		hidden bool
+		golden string
+	}{
+		{"append", "append", false, "cue-append.cue"},
+		{"prepend", "prepend", false, "cue-prepend.cue"},
+		{"hidden track", "append", true, "cue-hidden.cue"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cdInfo := cueTestDisc()
+			cdInfo.HiddenTrack = tt.hidden
+			got := CueSheet(cdInfo, tt.policy, "WAVE", func(track int) string {
+				return fmt.Sprintf("%02d_%s.flac", track, cdInfo.Track(track).Title)
+			})
+
+			want, err := os.ReadFile(filepath.Join("testdata", tt.golden))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("CueSheet() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestImageCueSheet(t *testing.T) {
+	cdInfo := cueTestDisc()
+	cdInfo.Barcode = "724354526329"
+	got := ImageCueSheet(cdInfo, "WAVE", "Band - Album.flac")
+
+	want, err := os.ReadFile(filepath.Join("testdata", "cue-image.cue"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("ImageCueSheet() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCueCatalog(t *testing.T) {
+	tests := []struct {
+		barcode string
+		want    string
+	}{
+		{"724354526329", "0724354526329"},
+		{"5099902987620", "5099902987620"},
+		{"12345", ""},
+		{"B000002UAL", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.barcode, func(t *testing.T) {
+			if got := cueCatalog(tt.barcode); got != tt.want {
+				t.Errorf("cueCatalog(%q) = %q, want %q", tt.barcode, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package ripper
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// musicBrainzEncoding is the base64 variant used by MusicBrainz disc IDs.
+// It swaps '+', '/' and '=' for '.', '_' and '-' so IDs are URL safe.
+var musicBrainzEncoding = base64.NewEncoding(
+	"ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789._",
+).WithPadding('-')
+
+// MusicBrainzDiscID computes the MusicBrainz disc ID from a table of contents.
+// offsets holds the start frame of every track followed by the lead-out frame,
+// as stored in CDInfo.Offsets. The first track is assumed to be track 1.
+func MusicBrainzDiscID(offsets []int) (string, error) {
+	if len(offsets) < 2 {
+		return "", fmt.Errorf("need at least one track offset and the lead-out, got %d values", len(offsets))
+	}
+
+	trackCount := len(offsets) - 1
+	if trackCount > 99 {
+		return "", fmt.Errorf("too many tracks for a disc ID: %d", trackCount)
+	}
+
+	for i := 1; i < len(offsets); i++ {
+		if offsets[i] <= offsets[i-1] {
+			return "", fmt.Errorf("offsets must be strictly increasing (offset %d is %d, previous is %d)", i, offsets[i], offsets[i-1])
+		}
+	}
+
+	leadOut := offsets[trackCount]
+
+	// The hash input is the hex encoded first and last track numbers,
+	// the lead-out, then 99 track offsets padded with zeros
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%02X", 1))
+	sb.WriteString(fmt.Sprintf("%02X", trackCount))
+	sb.WriteString(fmt.Sprintf("%08X", leadOut))
+	for i := 0; i < 99; i++ {
+		offset := 0
+		if i < trackCount {
+			offset = offsets[i]
+		}
+		sb.WriteString(fmt.Sprintf("%08X", offset))
+	}
+
+	sum := sha1.Sum([]byte(sb.String()))
+	return musicBrainzEncoding.EncodeToString(sum[:]), nil
+}
+
+// CDDBDiscID computes the freedb/CDDB disc ID from a table of contents laid out
+// like CDInfo.Offsets: track start frames followed by the lead-out frame.
+func CDDBDiscID(offsets []int) (string, error) {
+	if len(offsets) < 2 {
+		return "", fmt.Errorf("need at least one track offset and the lead-out, got %d values", len(offsets))
+	}
+
+	trackCount := len(offsets) - 1
+	leadOut := offsets[trackCount]
+
+	// Sum the digits of each track's start time in seconds
+	checksum := 0
+	for _, offset := range offsets[:trackCount] {
+		for seconds := offset / 75; seconds > 0; seconds /= 10 {
+			checksum += seconds % 10
+		}
+	}
+
+	length := leadOut/75 - offsets[0]/75
+	if length < 0 {
+		return "", fmt.Errorf("lead-out %d is before the first track offset %d", leadOut, offsets[0])
+	}
+
+	id := uint32(checksum%0xff)<<24 | uint32(length)<<8 | uint32(trackCount)
+	return fmt.Sprintf("%08x", id), nil
+}
+
+// DiscIDMismatchError is returned when the disc ID reported by an external tool
+// does not match the one computed from the parsed table of contents
+type DiscIDMismatchError struct {
+	Reported string
+	Computed string
+}
+
+func (e *DiscIDMismatchError) Error() string {
+	return fmt.Sprintf("disc ID mismatch: cd-discid reported %s but the TOC gives %s", e.Reported, e.Computed)
+}
+
+// verifyCDDBDiscID checks a reported CDDB disc ID against the one computed from offsets
+func verifyCDDBDiscID(reported string, offsets []int) error {
+	computed, err := CDDBDiscID(offsets)
+	if err != nil {
+		return fmt.Errorf("failed to compute CDDB disc ID: %w", err)
+	}
+	if !strings.EqualFold(reported, computed) {
+		return &DiscIDMismatchError{Reported: reported, Computed: computed}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package ripper
+
+import (
+	"errors"
+	"testing"
+)
+
+// Tables of contents with their published disc IDs, laid out like
+// CDInfo.Offsets: track start frames followed by the lead-out frame
+var (
+	// libdiscid's test disc (test/test_put.c)
+	libdiscidTOC = []int{
+		150, 9700, 25887, 39297, 53795, 63735, 77517, 94877, 107270,
+		123552, 135522, 148422, 161197, 174790, 192022, 205545,
+		218010, 228700, 239590, 255470, 266932, 288750,
+		303602,
+	}
+	// The disc the MusicBrainz web service documentation looks up
+	musicBrainzDocsTOC = []int{
+		150, 22767, 41887, 58317, 72102, 91375, 104652, 115380, 132165, 143932, 159870, 174597,
+		267257,
+	}
+)
+
+func TestMusicBrainzDiscID(t *testing.T) {
+	tests := []struct {
+		name    string
+		offsets []int
+		want    string
+	}{
+		{"libdiscid test disc", libdiscidTOC, "xUp1F2NkfP8s8jaeFn_Av3jNEI4-"},
+		{"web service docs disc", musicBrainzDocsTOC, "I5l9cCSFccLKFEKS.7wqSZAorPU-"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MusicBrainzDiscID(tt.offsets)
+			if err != nil {
+				t.Fatalf("MusicBrainzDiscID() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("MusicBrainzDiscID() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMusicBrainzDiscIDInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		offsets []int
+	}{
+		{"no lead-out", []int{150}},
+		{"lead-out before track", []int{150, 100}},
+		{"tracks out of order", []int{150, 20000, 10000, 30000}},
+		{"too many tracks", make([]int, 101)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if id, err := MusicBrainzDiscID(tt.offsets); err == nil {
+				t.Errorf("MusicBrainzDiscID() = %s, want an error", id)
+			}
+		})
+	}
+}
+
+func TestCDDBDiscID(t *testing.T) {
+	tests := []struct {
+		name    string
+		offsets []int
+		want    string
+	}{
+		{"libdiscid test disc", libdiscidTOC, "370fce16"},
+		{"web service docs disc", musicBrainzDocsTOC, "a70de90c"},
+		// One track starting at 0:02 and three minutes long
+		{"single track", []int{150, 150 + 180*75}, "0200b401"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CDDBDiscID(tt.offsets)
+			if err != nil {
+				t.Fatalf("CDDBDiscID() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("CDDBDiscID() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyCDDBDiscID(t *testing.T) {
+	// cd-discid prints IDs in lower case, but any case matches
+	for _, reported := range []string{"370fce16", "370FCE16"} {
+		if err := verifyCDDBDiscID(reported, libdiscidTOC); err != nil {
+			t.Errorf("verifyCDDBDiscID(%s) error = %v", reported, err)
+		}
+	}
+
+	err := verifyCDDBDiscID("370fce17", libdiscidTOC)
+	var mismatch *DiscIDMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("verifyCDDBDiscID() error = %v, want a DiscIDMismatchError", err)
+	}
+	if mismatch.Reported != "370fce17" || mismatch.Computed != "370fce16" {
+		t.Errorf("mismatch = %+v, want reported 370fce17 and computed 370fce16", mismatch)
+	}
+
+	if err := verifyCDDBDiscID("370fce16", []int{150}); err == nil || errors.As(err, &mismatch) {
+		t.Errorf("verifyCDDBDiscID() with no lead-out error = %v, want a TOC error", err)
+	}
+}
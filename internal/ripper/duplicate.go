@@ -0,0 +1,270 @@
+package ripper
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/Bparsons0904/ripper/internal/config"
+	"github.com/Bparsons0904/ripper/internal/tagging"
+)
+
+// ErrAlreadyRipped is returned when a disc is skipped because the library
+// already has a rip of it
+var ErrAlreadyRipped = errors.New("disc already in the library")
+
+// maxLibraryDepth bounds how deep the library is searched for rips; album
+// directories are rarely more than a few levels below a root
+const maxLibraryDepth = 6
+
+// maxAlbumCopies bounds the numbered folders tried for a new copy of a rip
+const maxAlbumCopies = 99
+
+// ExistingRip is a rip of the disc found in the library
+type ExistingRip struct {
+	Dir    string // Album directory
+	Reason string // How the rip was recognised
+
+	// manifestMatches is set when the directory's manifest names the disc,
+	// read before the new rip replaces it
+	manifestMatches bool
+}
+
+// discIDs identify a disc. The MusicBrainz ID is the reliable one; CDDB IDs
+// are only compared when a side has no MusicBrainz ID, as they collide.
+type discIDs struct {
+	cddb        string
+	musicBrainz string
+}
+
+// sameDisc reports whether two sets of IDs name the same disc
+func (d discIDs) sameDisc(other discIDs) bool {
+	if d.musicBrainz != "" && other.musicBrainz != "" {
+		return d.musicBrainz == other.musicBrainz
+	}
+	return d.cddb != "" && d.cddb == other.cddb
+}
+
+// FindExistingRips looks for rips of the disc in the libraries it would be
+// filed in: album directories whose manifest or tags carry the disc's ID, and
+// the directories the disc's tracks would be filed in
+func (r *CDRipper) FindExistingRips(cdInfo *CDInfo) ([]ExistingRip, error) {
+	targets, err := r.OutputTargets()
+	if err != nil {
+		return nil, err
+	}
+
+	var rips []ExistingRip
+	found := func(dir string) bool {
+		return slices.ContainsFunc(rips, func(rip ExistingRip) bool { return rip.Dir == dir })
+	}
+
+	ids := discIDs{cddb: cdInfo.CDDBDiscID, musicBrainz: cdInfo.MusicBrainzDiscID}
+	if ids != (discIDs{}) {
+		var roots []string
+		for _, target := range targets {
+			if !slices.Contains(roots, target.Root) {
+				roots = append(roots, target.Root)
+			}
+		}
+		for _, root := range roots {
+			err := scanLibrary(root, 0, func(dir string, other discIDs, source string) {
+				if ids.sameDisc(other) && !found(dir) {
+					rips = append(rips, ExistingRip{Dir: dir, Reason: "disc ID in " + source, manifestMatches: source == "manifest"})
+				}
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to search %s for the disc: %w", root, err)
+			}
+		}
+	}
+
+	dirs, err := albumDirs(cdInfo, targets)
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range dirs {
+		if files, _ := audioFiles(dir); len(files) > 0 && !found(dir) {
+			m, err := ReadManifest(dir)
+			matches := err == nil && ids.sameDisc(discIDs{cddb: m.Disc.CDDBDiscID, musicBrainz: m.Disc.MusicBrainzDiscID})
+			rips = append(rips, ExistingRip{Dir: dir, Reason: "album folder already exists", manifestMatches: matches})
+		}
+	}
+	return rips, nil
+}
+
+// scanLibrary walks dir and calls match with the disc IDs of each album
+// directory, read from its manifest or else from the tags of its first
+// taggable track. Hidden directories are skipped.
+func scanLibrary(dir string, depth int, match func(dir string, ids discIDs, source string)) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if depth == 0 && errors.Is(err, os.ErrNotExist) {
+			return nil // Nothing has been ripped yet
+		}
+		return err
+	}
+
+	manifest, tagged := false, ""
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case entry.IsDir():
+			if depth < maxLibraryDepth && !strings.HasPrefix(name, ".") {
+				if err := scanLibrary(filepath.Join(dir, name), depth+1, match); err != nil {
+					return err
+				}
+			}
+		case name == ManifestFileName:
+			manifest = true
+		case tagged == "" && tagging.Supported(name):
+			tagged = filepath.Join(dir, name)
+		}
+	}
+
+	// The manifest is the better record; tags cover rips made without one
+	switch {
+	case manifest:
+		if m, err := ReadManifest(dir); err == nil {
+			match(dir, discIDs{cddb: m.Disc.CDDBDiscID, musicBrainz: m.Disc.MusicBrainzDiscID}, "manifest")
+		}
+	case tagged != "":
+		if tags, err := tagging.ReadFile(tagged); err == nil {
+			match(dir, discIDs{cddb: tags.CDDBDiscID, musicBrainz: tags.MusicBrainzDiscID}, "tags")
+		}
+	}
+	return nil
+}
+
+// audioFiles returns the files in dir with the extension of an output format
+func audioFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var extensions []string
+	for _, format := range config.OutputFormats {
+		extensions = append(extensions, "."+fileExtension(format))
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && slices.Contains(extensions, strings.ToLower(filepath.Ext(entry.Name()))) {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return files, nil
+}
+
+// duplicateAction returns what to do with existing rips of the disc
+func (r *CDRipper) duplicateAction(cdInfo *CDInfo) string {
+	if cdInfo.OnDuplicate != "" {
+		return cdInfo.OnDuplicate
+	}
+	return r.config.CDRipping.OnDuplicate
+}
+
+// checkExistingRips applies the duplicate action before a rip. Existing rips
+// are skipped, given a numbered folder to rip beside them, or returned to be
+// replaced once the new rip is done. Without a choice from the user, "ask"
+// skips.
+func (r *CDRipper) checkExistingRips(cdInfo *CDInfo, targets []OutputTarget) ([]ExistingRip, error) {
+	cdInfo.albumCopy = 0
+	existing, err := r.FindExistingRips(cdInfo)
+	if err != nil || len(existing) == 0 {
+		return nil, err
+	}
+
+	switch r.duplicateAction(cdInfo) {
+	case "new_folder":
+		return nil, newAlbumCopy(cdInfo, targets)
+	case "overwrite":
+		return existing, nil
+	default:
+		return nil, fmt.Errorf("%s (%s): %w", existing[0].Dir, existing[0].Reason, ErrAlreadyRipped)
+	}
+}
+
+// newAlbumCopy numbers the disc's album folders, e.g. "Album (2)", when
+// needed to file the rip beside existing ones
+func newAlbumCopy(cdInfo *CDInfo, targets []OutputTarget) error {
+	for n := 1; n <= maxAlbumCopies; n++ {
+		cdInfo.albumCopy = n
+		dirs, err := albumDirs(cdInfo, targets)
+		if err != nil {
+			return err
+		}
+		if !slices.ContainsFunc(dirs, func(dir string) bool {
+			_, err := os.Stat(dir)
+			return err == nil
+		}) {
+			return nil
+		}
+	}
+	cdInfo.albumCopy = 0
+	return fmt.Errorf("no free folder for a new copy of the album; the naming templates must use .Album")
+}
+
+// replaceExistingRips removes what is left of replaced rips once the new rip
+// is filed: tracks of the disc the new rip did not overwrite, and the
+// manifest, cover and cue sheets of folders it did not use. Only files
+// shown to be from the disc are removed, so an album folder shared with
+// another release keeps its tracks. Emptied folders are removed.
+func replaceExistingRips(existing []ExistingRip, cdInfo *CDInfo, targets []OutputTarget) error {
+	keep := map[string]bool{}
+	for _, target := range targets {
+		paths, err := target.files(cdInfo)
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			keep[path] = true
+		}
+	}
+	dirs, err := albumDirs(cdInfo, targets)
+	if err != nil {
+		return err
+	}
+
+	ids := discIDs{cddb: cdInfo.CDDBDiscID, musicBrainz: cdInfo.MusicBrainzDiscID}
+	for _, rip := range existing {
+		files, err := audioFiles(rip.Dir)
+		if err != nil {
+			continue // Already gone
+		}
+		var remove []string
+		for _, file := range files {
+			if !keep[file] && fromDisc(file, ids, rip.manifestMatches) {
+				remove = append(remove, file)
+			}
+		}
+		if rip.manifestMatches && !slices.Contains(dirs, rip.Dir) {
+			remove = append(remove, filepath.Join(rip.Dir, ManifestFileName), filepath.Join(rip.Dir, coverFileName))
+			remove = append(remove, cueSheets(rip.Dir)...)
+		}
+		for _, file := range remove {
+			if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to remove replaced track: %w", err)
+			}
+		}
+		os.Remove(rip.Dir) // Only succeeds once empty
+	}
+	return nil
+}
+
+// fromDisc reports whether a track of a replaced rip is from the disc, by
+// the IDs in its tags or, for formats without tags, its folder's manifest
+func fromDisc(file string, ids discIDs, manifestMatches bool) bool {
+	if !tagging.Supported(file) {
+		return manifestMatches
+	}
+	tags, err := tagging.ReadFile(file)
+	if err != nil {
+		return false
+	}
+	return ids.sameDisc(discIDs{cddb: tags.CDDBDiscID, musicBrainz: tags.MusicBrainzDiscID})
+}
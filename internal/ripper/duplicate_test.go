@@ -0,0 +1,232 @@
+package ripper
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/Bparsons0904/ripper/internal/config"
+	"github.com/Bparsons0904/ripper/internal/tagging"
+)
+
+// writeTrack writes a stand-in FLAC file tagged with a disc's IDs, or an
+// untagged WAV file
+func writeTrack(t *testing.T, path string, ids discIDs) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Ext(path) != ".flac" {
+		if err := os.WriteFile(path, []byte("RIFF"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	// fLaC and an empty STREAMINFO block marked last
+	data := append([]byte("fLaC\x80\x00\x00\x22"), make([]byte, 34)...)
+	if err := os.WriteFile(path, append(data, "AUDIO"...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := tagging.WriteFile(path, tagging.Tags{CDDBDiscID: ids.cddb, MusicBrainzDiscID: ids.musicBrainz}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeManifest writes a manifest naming a disc into an album directory
+func writeManifest(t *testing.T, dir string, ids discIDs) {
+	t.Helper()
+	data, err := json.Marshal(Manifest{Version: manifestVersion, Disc: ManifestDisc{CDDBDiscID: ids.cddb, MusicBrainzDiscID: ids.musicBrainz}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestFileName), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// listFiles returns the files under dir relative to it
+func listFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// newDuplicateTestRipper returns a ripper filing FLAC and WAV files in a
+// temporary library, and a disc to rip with it
+func newDuplicateTestRipper(t *testing.T) (*CDRipper, *CDInfo) {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.Paths.Music = t.TempDir()
+	cfg.CDRipping.OutputFormat = []string{"flac", "wav"}
+	cdInfo := &CDInfo{
+		CDDBDiscID:        testDisc.cddb,
+		MusicBrainzDiscID: testDisc.musicBrainz,
+		TrackCount:        1,
+		Artist:            "Band",
+		Album:             "Album",
+		Tracks:            []TrackInfo{{Number: 1, Title: "One", Artist: "Band"}},
+	}
+	return NewCDRipper(cfg), cdInfo
+}
+
+// testDisc is the disc ripped in the tests, and otherRelease another
+// pressing with a colliding CDDB ID
+var (
+	testDisc     = discIDs{cddb: "a70de90c", musicBrainz: "I5l9cCSFccLKFEKS.7wqSZAorPU-"}
+	otherRelease = discIDs{cddb: "a70de90c", musicBrainz: "other-release"}
+)
+
+func TestSameDisc(t *testing.T) {
+	tests := []struct {
+		name  string
+		other discIDs
+		want  bool
+	}{
+		{"same musicbrainz", discIDs{musicBrainz: testDisc.musicBrainz}, true},
+		{"musicbrainz overrides cddb", otherRelease, false},
+		{"cddb without musicbrainz", discIDs{cddb: testDisc.cddb}, true},
+		{"different cddb", discIDs{cddb: "370fce16"}, false},
+		{"no IDs", discIDs{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testDisc.sameDisc(tt.other); got != tt.want {
+				t.Errorf("sameDisc(%+v) = %v, want %v", tt.other, got, tt.want)
+			}
+		})
+	}
+	if (discIDs{}).sameDisc(discIDs{}) {
+		t.Error("discs without IDs are the same disc")
+	}
+}
+
+func TestFindExistingRips(t *testing.T) {
+	r, cdInfo := newDuplicateTestRipper(t)
+	music := r.config.Paths.Music
+
+	if rips, err := r.FindExistingRips(cdInfo); err != nil || len(rips) != 0 {
+		t.Fatalf("FindExistingRips() on an empty library = %+v, %v", rips, err)
+	}
+
+	// The manifest wins over tags; hidden folders are not searched
+	writeTrack(t, filepath.Join(music, "Band/Album (1999)/01_One.flac"), otherRelease)
+	writeManifest(t, filepath.Join(music, "Band/Album (1999)"), testDisc)
+	writeTrack(t, filepath.Join(music, "Band/Live/01_One.flac"), testDisc)
+	writeTrack(t, filepath.Join(music, "Band/Other/01_One.flac"), testDisc)
+	writeManifest(t, filepath.Join(music, "Band/Other"), otherRelease)
+	writeTrack(t, filepath.Join(music, "Band/.trash/01_One.flac"), testDisc)
+	writeTrack(t, filepath.Join(music, "Band/Album/01_One.wav"), discIDs{})
+
+	rips, err := r.FindExistingRips(cdInfo)
+	if err != nil {
+		t.Fatalf("FindExistingRips() error = %v", err)
+	}
+	want := []ExistingRip{
+		{Dir: filepath.Join(music, "Band/Album (1999)"), Reason: "disc ID in manifest", manifestMatches: true},
+		{Dir: filepath.Join(music, "Band/Live"), Reason: "disc ID in tags"},
+		{Dir: filepath.Join(music, "Band/Album"), Reason: "album folder already exists"},
+	}
+	if !slices.Equal(rips, want) {
+		t.Errorf("FindExistingRips() = %+v, want %+v", rips, want)
+	}
+}
+
+func TestNewAlbumCopy(t *testing.T) {
+	r, cdInfo := newDuplicateTestRipper(t)
+	music := r.config.Paths.Music
+	targets, err := r.OutputTargets()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTrack(t, filepath.Join(music, "Band/Album/01_One.flac"), testDisc)
+	writeTrack(t, filepath.Join(music, "Band/Album_(2)/01_One.wav"), testDisc)
+
+	if err := newAlbumCopy(cdInfo, targets); err != nil {
+		t.Fatalf("newAlbumCopy() error = %v", err)
+	}
+	path, err := targets[0].trackPath(cdInfo, cdInfo.Track(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(music, "Band/Album_(3)/01_One.flac"); path != want {
+		t.Errorf("track path of the new copy = %q, want %q", path, want)
+	}
+}
+
+func TestReplaceExistingRips(t *testing.T) {
+	r, cdInfo := newDuplicateTestRipper(t)
+	music := r.config.Paths.Music
+
+	// An earlier rip of the disc, and the folder the new rip uses, last
+	// written by another release of the album
+	for _, file := range []struct {
+		path string
+		ids  discIDs
+	}{
+		{"Band/Album (1999)/01_One.flac", testDisc},
+		{"Band/Album (1999)/01_One.wav", testDisc},
+		{"Band/Album/01_One.flac", testDisc},
+		{"Band/Album/02_B-Side.flac", otherRelease},
+		{"Band/Album/02_B-Side.wav", otherRelease},
+		{"Band/Album/03_Old.flac", testDisc},
+	} {
+		writeTrack(t, filepath.Join(music, file.path), file.ids)
+	}
+	writeManifest(t, filepath.Join(music, "Band/Album (1999)"), testDisc)
+	writeManifest(t, filepath.Join(music, "Band/Album"), otherRelease)
+	for _, name := range []string{coverFileName, "Album (1999).cue"} {
+		if err := os.WriteFile(filepath.Join(music, "Band/Album (1999)", name), []byte("stale"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	existing, err := r.FindExistingRips(cdInfo)
+	if err != nil {
+		t.Fatalf("FindExistingRips() error = %v", err)
+	}
+
+	// The new rip overwrites its tracks and the folder's manifest
+	targets, err := r.OutputTargets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTrack(t, filepath.Join(music, "Band/Album/01_One.flac"), testDisc)
+	writeTrack(t, filepath.Join(music, "Band/Album/01_One.wav"), testDisc)
+	writeManifest(t, filepath.Join(music, "Band/Album"), testDisc)
+
+	if err := replaceExistingRips(existing, cdInfo, targets); err != nil {
+		t.Fatalf("replaceExistingRips() error = %v", err)
+	}
+
+	// Tracks of the other release stay, tagged or not; the untagged one is
+	// kept as the folder's old manifest did not name the disc
+	got := listFiles(t, music)
+	want := []string{
+		"Band/Album/01_One.flac",
+		"Band/Album/01_One.wav",
+		"Band/Album/02_B-Side.flac",
+		"Band/Album/02_B-Side.wav",
+		"Band/Album/" + ManifestFileName,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("library after replacing = %q, want %q", got, want)
+	}
+}
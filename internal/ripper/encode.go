@@ -0,0 +1,243 @@
+package ripper
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Bparsons0904/ripper/internal/tagging"
+)
+
+// encoderTools names the tool that encodes each format; WAV is copied
+var encoderTools = map[string]string{
+	"flac":    "flac",
+	"mp3":     "lame",
+	"ogg":     "oggenc",
+	"opus":    "opusenc",
+	"m4a":     "ffmpeg",
+	"alac":    "ffmpeg",
+	"wavpack": "wavpack",
+}
+
+// encodeTrack encodes a WAV file into the target's format at outPath and tags
+// the result with the track's metadata. Formats the tagging package handles
+// are tagged by it; the rest through the encoder's own options.
+func encodeTrack(ctx context.Context, run toolRunner, target OutputTarget, wavPath, outPath string, cdInfo *CDInfo, track TrackInfo) error {
+	if err := encodeWAV(ctx, run, target, wavPath, outPath, cdInfo, track); err != nil {
+		return err
+	}
+	if tagging.Supported(outPath) {
+		return tagging.WriteFile(outPath, trackTags(cdInfo, track))
+	}
+	return nil
+}
+
+// encodeImage encodes a disc image into the target's format at outPath and
+// tags it with the album's metadata. A cue sheet, if given, is stored in
+// the tags too.
+func encodeImage(ctx context.Context, run toolRunner, target OutputTarget, wavPath, outPath string, cdInfo *CDInfo, cueSheet string) error {
+	if err := encodeWAV(ctx, run, target, wavPath, outPath, cdInfo, imageTrack(cdInfo)); err != nil {
+		return err
+	}
+	if tagging.Supported(outPath) {
+		return tagging.WriteFile(outPath, imageTags(cdInfo, cueSheet))
+	}
+	return nil
+}
+
+// encodeWAV encodes a WAV file into the target's format at outPath. Only
+// formats the tagging package cannot handle are tagged, through the
+// encoder's own options.
+func encodeWAV(ctx context.Context, run toolRunner, target OutputTarget, wavPath, outPath string, cdInfo *CDInfo, track TrackInfo) error {
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	encoder := encoderTools[target.Format]
+	var args []string
+
+	switch target.Format {
+	case "wav":
+		return copyFile(wavPath, outPath)
+	case "flac":
+		args = []string{"--silent", "--force", "-o", outPath}
+		args = append(args, target.encoderArgs...)
+		args = append(args, wavPath)
+	case "ogg":
+		args = []string{"--quiet", "-o", outPath}
+		args = append(args, target.encoderArgs...)
+		args = append(args, wavPath)
+	case "opus":
+		args = append([]string{"--quiet"}, target.encoderArgs...)
+		args = append(args, wavPath, outPath)
+	case "m4a", "alac":
+		// ffmpeg writes the iTunes-style atoms for the MP4 metadata keys
+		codec := "aac"
+		if target.Format == "alac" {
+			codec = "alac"
+		}
+		args = []string{"-nostdin", "-hide_banner", "-loglevel", "error", "-y", "-i", wavPath, "-c:a", codec}
+		args = append(args, target.encoderArgs...)
+		for _, tag := range mp4Metadata(cdInfo, track) {
+			args = append(args, "-metadata", tag)
+		}
+		args = append(args, outPath)
+	case "wavpack":
+		args = append([]string{"-q", "-y"}, target.encoderArgs...)
+		for _, tag := range apeTags(cdInfo, track) {
+			args = append(args, "-w", tag)
+		}
+		args = append(args, wavPath, "-o", outPath)
+	case "mp3":
+		args = append([]string{"--quiet"}, target.encoderArgs...)
+		args = append(args, wavPath, outPath)
+	default:
+		return fmt.Errorf("unsupported output format %q", target.Format)
+	}
+
+	cmd, err := run.command(ctx, encoder, args...)
+	if err != nil {
+		return err
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if message := strings.TrimSpace(string(output)); message != "" {
+			return fmt.Errorf("%s failed: %w: %s", encoder, err, message)
+		}
+		return fmt.Errorf("%s failed: %w", encoder, err)
+	}
+	return nil
+}
+
+// trackTags returns the tags written to a track's files
+func trackTags(cdInfo *CDInfo, track TrackInfo) tagging.Tags {
+	return tagging.Tags{
+		Title:              track.Title,
+		Artist:             track.Artist,
+		Album:              cdInfo.Album,
+		AlbumArtist:        cdInfo.Artist,
+		TrackNumber:        track.Number,
+		TrackTotal:         cdInfo.TrackCount,
+		DiscNumber:         cdInfo.DiscNumber,
+		Year:               cdInfo.Year,
+		Genre:              cdInfo.Genre,
+		Compilation:        cdInfo.Compilation,
+		ISRC:               track.ISRC,
+		MusicBrainzAlbumID: cdInfo.MusicBrainzReleaseID,
+		MusicBrainzTrackID: track.MusicBrainzRecordingID,
+		MusicBrainzDiscID:  cdInfo.MusicBrainzDiscID,
+		CDDBDiscID:         cdInfo.CDDBDiscID,
+		Picture:            cdInfo.embeddedCover,
+	}
+}
+
+// imageTrack is the track a disc image is tagged as: the whole album
+func imageTrack(cdInfo *CDInfo) TrackInfo {
+	return TrackInfo{Title: cdInfo.Album, Artist: cdInfo.Artist}
+}
+
+// imageTags returns the tags written to a disc image's files
+func imageTags(cdInfo *CDInfo, cueSheet string) tagging.Tags {
+	tags := trackTags(cdInfo, imageTrack(cdInfo))
+	tags.CueSheet = cueSheet
+	return tags
+}
+
+// mp4Metadata returns ffmpeg's MP4 metadata keys for a track as key=value
+// pairs
+func mp4Metadata(cdInfo *CDInfo, track TrackInfo) []string {
+	discNumber, compilation := discTags(cdInfo)
+
+	return tagPairs([]struct{ name, value string }{
+		{"title", track.Title},
+		{"artist", track.Artist},
+		{"album", cdInfo.Album},
+		{"album_artist", cdInfo.Artist},
+		{"track", fmt.Sprintf("%d/%d", track.Number, cdInfo.TrackCount)},
+		{"disc", discNumber},
+		{"date", cdInfo.Year},
+		{"genre", cdInfo.Genre},
+		{"compilation", compilation},
+	})
+}
+
+// apeTags returns the APEv2 items WavPack writes for a track as Key=value
+// pairs
+func apeTags(cdInfo *CDInfo, track TrackInfo) []string {
+	discNumber, compilation := discTags(cdInfo)
+
+	return tagPairs([]struct{ name, value string }{
+		{"Title", track.Title},
+		{"Artist", track.Artist},
+		{"Album", cdInfo.Album},
+		{"Album Artist", cdInfo.Artist},
+		{"Track", fmt.Sprintf("%d/%d", track.Number, cdInfo.TrackCount)},
+		{"Disc", discNumber},
+		{"Year", cdInfo.Year},
+		{"Genre", cdInfo.Genre},
+		{"Compilation", compilation},
+		{"ISRC", track.ISRC},
+		{"MUSICBRAINZ_ALBUMID", cdInfo.MusicBrainzReleaseID},
+		{"MUSICBRAINZ_TRACKID", track.MusicBrainzRecordingID},
+		{"MUSICBRAINZ_DISCID", cdInfo.MusicBrainzDiscID},
+		{"CDDB", cdInfo.CDDBDiscID},
+	})
+}
+
+// discTags returns the disc number and compilation flag as tag values,
+// empty when unset
+func discTags(cdInfo *CDInfo) (discNumber, compilation string) {
+	if cdInfo.DiscNumber > 0 {
+		discNumber = fmt.Sprintf("%d", cdInfo.DiscNumber)
+	}
+	if cdInfo.Compilation {
+		compilation = "1"
+	}
+	return discNumber, compilation
+}
+
+// tagPairs joins tags into name=value pairs, leaving out empty values
+func tagPairs(tags []struct{ name, value string }) []string {
+	var result []string
+	for _, tag := range tags {
+		if tag.value != "" {
+			result = append(result, tag.name+"="+tag.value)
+		}
+	}
+	return result
+}
+
+// fileExtension returns the file extension of a format
+func fileExtension(format string) string {
+	switch format {
+	case "m4a", "alac":
+		return "m4a"
+	case "wavpack":
+		return "wv"
+	default:
+		return format
+	}
+}
+
+// copyFile copies src to dst, replacing dst if it exists
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,25 @@
+package ripper
+
+import (
+	"context"
+	"fmt"
+)
+
+// Extractor is an extraction backend that reads the tracks of a disc once and
+// files them in every output target, reporting progress through the ripper
+type Extractor interface {
+	Rip(ctx context.Context, cdInfo *CDInfo, targets []OutputTarget) error
+}
+
+// newExtractor returns the extraction backend selected in the configuration,
+// running its tools through run
+func (r *CDRipper) newExtractor(run toolRunner) (Extractor, error) {
+	switch r.config.CDRipping.ExtractionBackend {
+	case "", "abcde":
+		return &abcdeExtractor{r: r, run: run}, nil
+	case "cdparanoia":
+		return &paranoiaExtractor{r: r, run: run}, nil
+	default:
+		return nil, fmt.Errorf("unknown extraction backend %q", r.config.CDRipping.ExtractionBackend)
+	}
+}
@@ -0,0 +1,324 @@
+package ripper
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Pregap returns the sectors before a track's start index, 0 when none are
+// known
+func (c *CDInfo) Pregap(track int) int {
+	if track < 1 || track > len(c.Pregaps) {
+		return 0
+	}
+	return c.Pregaps[track-1]
+}
+
+// FirstTrack returns the number of the first track ripped: 0 once a rip
+// found a hidden track, else 1
+func (c *CDInfo) FirstTrack() int {
+	if c.HiddenTrack {
+		return 0
+	}
+	return 1
+}
+
+// trackSectors returns where a track starts, counted from the start of the
+// program area as cdparanoia does, and how many sectors it has. Track 0 is
+// the audio in track 1's pregap.
+func (c *CDInfo) trackSectors(track int) (start, length int) {
+	if track == 0 {
+		return 0, c.Pregap(1)
+	}
+	return c.Offsets[track-1] - 150, c.Offsets[track] - c.Offsets[track-1]
+}
+
+// tocPregaps returns the pregaps a table of contents shows. Only track 1's
+// is there: the sectors before it beyond the two second lead-in.
+func tocPregaps(offsets []int, trackCount int) []int {
+	pregaps := make([]int, trackCount)
+	if trackCount > 0 && len(offsets) > 0 {
+		pregaps[0] = max(offsets[0]-150, 0)
+	}
+	return pregaps
+}
+
+// scanGaps asks cdrdao for the pregap of every track. cdrdao finds them in
+// the Q subchannel around each track start, so it reads the disc for a
+// while. Track 1's pregap is kept from the table of contents.
+func (e *paranoiaExtractor) scanGaps(ctx context.Context, cdInfo *CDInfo, workDir string) ([]int, error) {
+	tocPath := filepath.Join(workDir, "gaps.toc")
+	// cdrdao refuses to overwrite a TOC file
+	os.Remove(tocPath)
+	defer os.Remove(tocPath)
+
+	cmd, err := e.run.command(ctx, "cdrdao", "read-toc",
+		"--device", e.r.config.Drives.CDDrive,
+		"--datafile", "data.bin",
+		tocPath,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+		return nil, fmt.Errorf("cdrdao failed: %w: %s", err, lines[len(lines)-1])
+	}
+
+	data, err := os.ReadFile(tocPath)
+	if err != nil {
+		return nil, fmt.Errorf("cdrdao wrote no TOC: %w", err)
+	}
+	pregaps, err := parseCDRDAOTOC(data, cdInfo.TrackCount)
+	if err != nil {
+		return nil, err
+	}
+	pregaps[0] = cdInfo.Pregap(1)
+
+	for n := 2; n <= cdInfo.TrackCount; n++ {
+		if _, length := cdInfo.trackSectors(n - 1); pregaps[n-1] >= length {
+			return nil, fmt.Errorf("pregap of track %d is longer than track %d", n, n-1)
+		}
+	}
+	return pregaps, nil
+}
+
+// parseCDRDAOTOC returns the pregap of each track from a TOC file written
+// by cdrdao read-toc. A track's START or PREGAP statement gives its pregap.
+func parseCDRDAOTOC(data []byte, trackCount int) ([]int, error) {
+	pregaps := make([]int, trackCount)
+	track := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "//") {
+			continue
+		}
+
+		switch fields[0] {
+		case "TRACK":
+			track++
+			if track > trackCount {
+				return nil, fmt.Errorf("cdrdao found more than %d tracks", trackCount)
+			}
+		case "START", "PREGAP":
+			if track == 0 || len(fields) < 2 {
+				return nil, fmt.Errorf("misplaced %s in cdrdao TOC", fields[0])
+			}
+			sectors, err := parseMSF(fields[1])
+			if err != nil {
+				return nil, err
+			}
+			pregaps[track-1] = sectors
+		}
+	}
+	if track != trackCount {
+		return nil, fmt.Errorf("cdrdao found %d tracks, the disc has %d", track, trackCount)
+	}
+	return pregaps, nil
+}
+
+// parseMSF parses a cdrdao time, mm:ss:ff with 75 sectors to the second,
+// into sectors
+func parseMSF(value string) (int, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid cdrdao time %q", value)
+	}
+	var msf [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid cdrdao time %q", value)
+		}
+		msf[i] = n
+	}
+	if msf[1] >= 60 || msf[2] >= 75 {
+		return 0, fmt.Errorf("invalid cdrdao time %q", value)
+	}
+	return (msf[0]*60+msf[1])*75 + msf[2], nil
+}
+
+// openWAV opens a WAV file positioned at its audio, returning the audio's
+// size and where it starts
+func openWAV(path string) (*os.File, int64, uint32, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	reader := &countingReader{r: bufio.NewReader(f)}
+	size, err := wavDataSize(reader)
+	if err != nil {
+		f.Close()
+		return nil, 0, 0, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	if _, err := f.Seek(reader.n, io.SeekStart); err != nil {
+		f.Close()
+		return nil, 0, 0, err
+	}
+	return f, reader.n, size, nil
+}
+
+// writeWAVHeader writes the header of a CD audio WAV file holding size
+// bytes of audio
+func writeWAVHeader(w io.Writer, size uint32) error {
+	header := make([]byte, 44)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], 36+size)
+	copy(header[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], 1) // PCM
+	binary.LittleEndian.PutUint16(header[22:], 2)
+	binary.LittleEndian.PutUint32(header[24:], 44100)
+	binary.LittleEndian.PutUint32(header[28:], 44100*4)
+	binary.LittleEndian.PutUint16(header[32:], 4)
+	binary.LittleEndian.PutUint16(header[34:], 16)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], size)
+	_, err := w.Write(header)
+	return err
+}
+
+// trimWAV cuts a WAV file's audio down to sectors
+func trimWAV(path string, sectors int) error {
+	f, start, size, err := openWAV(path)
+	if err != nil {
+		return err
+	}
+	f.Close()
+
+	keep := uint32(sectors * sectorBytes)
+	if size < keep {
+		return fmt.Errorf("%s is %d sectors short", filepath.Base(path), (keep-size+sectorBytes-1)/sectorBytes)
+	}
+	if size == keep {
+		return nil
+	}
+
+	f, err = os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := f.Truncate(start + int64(keep)); err != nil {
+		return fmt.Errorf("failed to trim %s: %w", filepath.Base(path), err)
+	}
+	// The RIFF size counts everything after its own field; the data size
+	// sits just before the audio
+	var field [4]byte
+	binary.LittleEndian.PutUint32(field[:], uint32(start)+keep-8)
+	if _, err := f.WriteAt(field[:], 4); err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint32(field[:], keep)
+	if _, err := f.WriteAt(field[:], start-4); err != nil {
+		return err
+	}
+	return nil
+}
+
+// isSilent reports whether a WAV file's audio is digital silence
+func isSilent(path string) (bool, error) {
+	f, _, size, err := openWAV(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 64*sectorBytes)
+	reader := io.LimitReader(f, int64(size))
+	for {
+		n, err := reader.Read(buf)
+		for _, b := range buf[:n] {
+			if b != 0 {
+				return false, nil
+			}
+		}
+		if err == io.EOF {
+			return true, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+		}
+	}
+}
+
+// regapTrack lays a track read as the disc plays out for the prepend gap
+// policy: the pregap in leadPath, if any, is put before it, and its last
+// trail sectors, the next track's pregap, are moved into trailPath
+func regapTrack(wavPath, leadPath string, trail int, trailPath string) error {
+	src, start, size, err := openWAV(wavPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	keep := int64(size) - int64(trail*sectorBytes)
+	if keep < 0 {
+		return fmt.Errorf("%s is shorter than the next track's pregap", filepath.Base(wavPath))
+	}
+
+	if trail > 0 {
+		if _, err := src.Seek(start+keep, io.SeekStart); err != nil {
+			return err
+		}
+		if err := writeWAV(trailPath, uint32(trail*sectorBytes), src); err != nil {
+			return err
+		}
+	}
+
+	if _, err := src.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+	audio := io.LimitReader(src, keep)
+	length := uint32(keep)
+	if leadPath != "" {
+		lead, _, leadSize, err := openWAV(leadPath)
+		if err != nil {
+			return err
+		}
+		defer lead.Close()
+		audio = io.MultiReader(io.LimitReader(lead, int64(leadSize)), audio)
+		length += leadSize
+	}
+
+	tmpPath := wavPath + ".regap"
+	if err := writeWAV(tmpPath, length, audio); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, wavPath)
+}
+
+// writeWAV writes size bytes of audio from r into a new WAV file
+func writeWAV(path string, size uint32, r io.Reader) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if err := writeWAVHeader(w, size); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := io.CopyN(w, r, int64(size)); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
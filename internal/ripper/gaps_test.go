@@ -0,0 +1,176 @@
+package ripper
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCDRDAOTOC(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "cdrdao-read-toc.toc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Track 3 has an INDEX 02 but no pregap
+	pregaps, err := parseCDRDAOTOC(data, 4)
+	if err != nil {
+		t.Fatalf("parseCDRDAOTOC() error = %v", err)
+	}
+	if want := []int{2400, 160, 0, 1}; !reflect.DeepEqual(pregaps, want) {
+		t.Errorf("parseCDRDAOTOC() = %v, want %v", pregaps, want)
+	}
+
+	tests := []struct {
+		name       string
+		data       string
+		trackCount int
+		wantErr    string
+	}{
+		{"more tracks than the disc", string(data), 3, "more than 3 tracks"},
+		{"fewer tracks than the disc", string(data), 5, "found 4 tracks, the disc has 5"},
+		{"start before a track", "CD_DA\nSTART 00:02:00\nTRACK AUDIO\n", 1, "misplaced START"},
+		{"bad time", "TRACK AUDIO\nSTART 00:60:00\n", 1, `invalid cdrdao time "00:60:00"`},
+		{"short time", "TRACK AUDIO\nPREGAP 02:00\n", 1, `invalid cdrdao time "02:00"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseCDRDAOTOC([]byte(tt.data), tt.trackCount)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseCDRDAOTOC() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// wavAudio reads the audio of a WAV file, checking its header's sizes
+func wavAudio(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if riff := binary.LittleEndian.Uint32(data[4:]); int(riff) != len(data)-8 {
+		t.Errorf("%s RIFF size = %d, want %d", filepath.Base(path), riff, len(data)-8)
+	}
+
+	f, start, size, err := openWAV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if int(start)+int(size) != len(data) {
+		t.Errorf("%s data size = %d, want %d", filepath.Base(path), size, len(data)-int(start))
+	}
+	audio, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return audio
+}
+
+func TestTrimWAV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "track00.wav")
+	writeTestWAV(t, path, sectorAudio("ABCDEF"))
+
+	if err := trimWAV(path, 4); err != nil {
+		t.Fatalf("trimWAV() error = %v", err)
+	}
+	if got := wavAudio(t, path); !bytes.Equal(got, sectorAudio("ABCD")) {
+		t.Errorf("trimmed audio is %d bytes, want sectors ABCD", len(got))
+	}
+
+	// Trimming to the length it has already is a no-op
+	if err := trimWAV(path, 4); err != nil {
+		t.Fatalf("trimWAV() to the same length error = %v", err)
+	}
+	if got := wavAudio(t, path); !bytes.Equal(got, sectorAudio("ABCD")) {
+		t.Errorf("audio changed trimming to its own length")
+	}
+
+	if err := trimWAV(path, 6); err == nil || !strings.Contains(err.Error(), "2 sectors short") {
+		t.Errorf("trimWAV() past the end error = %v, want 2 sectors short", err)
+	}
+}
+
+func TestRegapTrack(t *testing.T) {
+	tests := []struct {
+		name      string
+		lead      string // Pregap put before the track, empty for none
+		trail     int
+		want      string
+		wantTrail string // Empty when no trail file is written
+		wantErr   string
+	}{
+		{name: "no gaps", want: "ABCDEF"},
+		{name: "next pregap moved out", trail: 2, want: "ABCD", wantTrail: "EF"},
+		{name: "own pregap put first", lead: "XY", want: "XYABCDEF"},
+		{name: "both", lead: "XY", trail: 1, want: "XYABCDE", wantTrail: "F"},
+		{name: "whole track", trail: 6, want: "", wantTrail: "ABCDEF"},
+		{name: "pregap longer than the track", trail: 7, wantErr: "shorter than the next track's pregap"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			wavPath := filepath.Join(dir, "track02.wav")
+			writeTestWAV(t, wavPath, sectorAudio("ABCDEF"))
+			leadPath := ""
+			if tt.lead != "" {
+				leadPath = filepath.Join(dir, "pregap02.wav")
+				writeTestWAV(t, leadPath, sectorAudio(tt.lead))
+			}
+			trailPath := filepath.Join(dir, "pregap03.wav")
+
+			err := regapTrack(wavPath, leadPath, tt.trail, trailPath)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("regapTrack() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				if got := wavAudio(t, wavPath); !bytes.Equal(got, sectorAudio("ABCDEF")) {
+					t.Error("failed regap changed the track")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("regapTrack() error = %v", err)
+			}
+
+			if got := wavAudio(t, wavPath); !bytes.Equal(got, sectorAudio(tt.want)) {
+				t.Errorf("track audio is %d bytes, want sectors %q", len(got), tt.want)
+			}
+			if tt.wantTrail == "" {
+				if _, err := os.Stat(trailPath); !os.IsNotExist(err) {
+					t.Errorf("trail file written without a trail")
+				}
+			} else if got := wavAudio(t, trailPath); !bytes.Equal(got, sectorAudio(tt.wantTrail)) {
+				t.Errorf("trail audio is %d bytes, want sectors %q", len(got), tt.wantTrail)
+			}
+			if _, err := os.Stat(wavPath + ".regap"); !os.IsNotExist(err) {
+				t.Error("temporary file left behind")
+			}
+		})
+	}
+}
+
+func TestIsSilent(t *testing.T) {
+	dir := t.TempDir()
+	silent := filepath.Join(dir, "silent.wav")
+	writeTestWAV(t, silent, sectorAudio("\x00\x00\x00"))
+	loud := filepath.Join(dir, "loud.wav")
+	writeTestWAV(t, loud, append(sectorAudio("\x00\x00"), 0, 0, 1, 0))
+
+	for path, want := range map[string]bool{silent: true, loud: false} {
+		got, err := isSilent(path)
+		if err != nil {
+			t.Fatalf("isSilent(%s) error = %v", filepath.Base(path), err)
+		}
+		if got != want {
+			t.Errorf("isSilent(%s) = %v, want %v", filepath.Base(path), got, want)
+		}
+	}
+}
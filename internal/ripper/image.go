@@ -0,0 +1,74 @@
+package ripper
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// encodeImages joins the tracks read into one image of the disc and encodes
+// it into each target, embedding the cue sheet where configured. parts are
+// the tracks' WAV files in disc order, from the start of the program area.
+// The images are encoded in the work directory and filed once all are done.
+func (e *paranoiaExtractor) encodeImages(ctx context.Context, cdInfo *CDInfo, targets []OutputTarget, workDir string, parts []string, progress *ripProgress) error {
+	wavPath := filepath.Join(workDir, "image.wav")
+	if err := joinWAVs(wavPath, parts); err != nil {
+		return fmt.Errorf("failed to join the tracks into an image: %w", err)
+	}
+	for _, part := range parts {
+		os.Remove(part)
+	}
+
+	staged := make([]string, len(targets))
+	outPaths := make([]string, len(targets))
+	for i, target := range targets {
+		outPath, err := target.imagePath(cdInfo)
+		if err != nil {
+			return err
+		}
+		outPaths[i] = outPath
+		cueSheet := ""
+		if e.r.config.CDRipping.Image.EmbedCue {
+			cueSheet = ImageCueSheet(cdInfo, cueFileType(target.Format), filepath.Base(outPath))
+		}
+		staged[i] = filepath.Join(workDir, target.Format, "image."+fileExtension(target.Format))
+		if err := encodeImage(ctx, e.run, target, wavPath, staged[i], cdInfo, cueSheet); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to encode the image to %s: %w", target.Format, err)
+		}
+		progress.targetEncoded(i == len(targets)-1)
+	}
+
+	for i, target := range targets {
+		if err := moveFile(staged[i], outPaths[i]); err != nil {
+			return fmt.Errorf("failed to file the %s image: %w", target.Format, err)
+		}
+	}
+	return nil
+}
+
+// joinWAVs writes the audio of WAV files one after another into a new WAV
+// file
+func joinWAVs(path string, parts []string) error {
+	var readers []io.Reader
+	size := uint32(0)
+	for _, part := range parts {
+		f, _, partSize, err := openWAV(part)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		readers = append(readers, io.LimitReader(f, int64(partSize)))
+		size += partSize
+	}
+
+	if err := writeWAV(path, size, io.MultiReader(readers...)); err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,545 @@
+package ripper
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Bparsons0904/ripper/internal/tagging"
+)
+
+// ManifestFileName is the manifest each album directory gets after a rip
+const ManifestFileName = "rip-manifest.json"
+
+// manifestVersion is bumped when the manifest's layout changes incompatibly
+const manifestVersion = 1
+
+// Manifest records how an album directory's tracks were ripped, so the disc
+// can be recognised and the tracks checked or re-tagged later
+type Manifest struct {
+	Version    int                `json:"version"`
+	Ripper     string             `json:"ripper"`
+	StartedAt  time.Time          `json:"started_at"`
+	FinishedAt time.Time          `json:"finished_at"`
+	Disc       ManifestDisc       `json:"disc"`
+	Metadata   ManifestMetadata   `json:"metadata"`
+	Extraction ManifestExtraction `json:"extraction"`
+	Tools      map[string]string  `json:"tools,omitempty"` // Version of each tool run, by name
+	Outputs    []ManifestOutput   `json:"outputs"`
+	Tracks     []ManifestTrack    `json:"tracks"`
+	// Image lists the disc image's files when the disc was ripped as one;
+	// its tracks then have no files of their own
+	Image []ManifestFile `json:"image,omitempty"`
+
+	// AccurateRip is set when the rip was verified
+	AccurateRip *ManifestAccurateRip `json:"accuraterip,omitempty"`
+	// CTDB is set when the rip was checked against the CUETools Database
+	CTDB *ManifestCTDB `json:"ctdb,omitempty"`
+}
+
+// ManifestDisc identifies the disc that was ripped
+type ManifestDisc struct {
+	CDDBDiscID        string `json:"cddb_disc_id,omitempty"`
+	MusicBrainzDiscID string `json:"musicbrainz_disc_id,omitempty"`
+	TrackCount        int    `json:"track_count"`
+	Offsets           []int  `json:"offsets"` // Track start frames followed by the lead-out frame
+	DiscNumber        int    `json:"disc_number,omitempty"`
+	// Pregaps are the sectors before each track's start index, set when any
+	// track has one; HiddenTrack marks audio before track 1 ripped as track 0
+	Pregaps     []int `json:"pregaps,omitempty"`
+	HiddenTrack bool  `json:"hidden_track,omitempty"`
+}
+
+// ManifestMetadata is the album metadata the tracks were tagged with
+type ManifestMetadata struct {
+	Source               string `json:"source"` // musicbrainz, cddb or none
+	Edited               bool   `json:"edited,omitempty"`
+	MusicBrainzReleaseID string `json:"musicbrainz_release_id,omitempty"`
+	Barcode              string `json:"barcode,omitempty"`
+	Artist               string `json:"artist"`
+	Album                string `json:"album"`
+	Year                 string `json:"year,omitempty"`
+	Genre                string `json:"genre,omitempty"`
+	Comment              string `json:"comment,omitempty"`
+	Compilation          bool   `json:"compilation,omitempty"`
+}
+
+// ManifestExtraction describes how the disc was read
+type ManifestExtraction struct {
+	Backend    string `json:"backend"`   // abcde or cdparanoia
+	Execution  string `json:"execution"` // native or container
+	Drive      string `json:"drive"`
+	DriveModel string `json:"drive_model,omitempty"`
+
+	// ReadOffset is the offset applied to the read in samples, set when
+	// cdparanoia read the disc
+	ReadOffset *ManifestReadOffset `json:"read_offset,omitempty"`
+	// Secure is set when every track was read twice and compared
+	Secure bool `json:"secure,omitempty"`
+	// GapPolicy is how pregaps were filed, append or prepend, set when
+	// cdparanoia read the disc
+	GapPolicy string `json:"gap_policy,omitempty"`
+	// RipMode is how the disc was filed, tracks or image, set when
+	// cdparanoia read the disc
+	RipMode string `json:"rip_mode,omitempty"`
+}
+
+// ManifestReadOffset is the drive read offset a rip was corrected by
+type ManifestReadOffset struct {
+	Samples int    `json:"samples"`
+	Source  string `json:"source"` // configured, known drive or unknown drive
+}
+
+// ManifestAccurateRip is the disc's AccurateRip lookup
+type ManifestAccurateRip struct {
+	ID    string `json:"id"`
+	Found bool   `json:"found"`
+	Error string `json:"error,omitempty"`
+}
+
+// ManifestCTDB is the disc's CUETools Database check
+type ManifestCTDB struct {
+	TOC                   string `json:"toc"`
+	CRC                   string `json:"crc32"`
+	Found                 bool   `json:"found"`
+	Error                 string `json:"error,omitempty"`
+	Confidence            int    `json:"confidence,omitempty"`
+	DifferingTracks       []int  `json:"differing_tracks,omitempty"`
+	DifferingTrackSectors int    `json:"differing_track_sectors,omitempty"`
+	Repairable            bool   `json:"repairable,omitempty"`
+}
+
+// ManifestOutput is a format in the directory and how it was encoded
+type ManifestOutput struct {
+	Format   string   `json:"format"`
+	Encoder  string   `json:"encoder,omitempty"`
+	Settings []string `json:"settings,omitempty"`
+}
+
+// ManifestTrack is a track and the files it was filed as
+type ManifestTrack struct {
+	Number                 int            `json:"number"`
+	Title                  string         `json:"title"`
+	Artist                 string         `json:"artist"`
+	ISRC                   string         `json:"isrc,omitempty"`
+	MusicBrainzRecordingID string         `json:"musicbrainz_recording_id,omitempty"`
+	Files                  []ManifestFile `json:"files"`
+
+	AccurateRip *ManifestTrackAccurateRip `json:"accuraterip,omitempty"`
+	CTDBCRC     string                    `json:"ctdb_crc32,omitempty"`
+	Read        *ManifestTrackRead        `json:"read,omitempty"`
+}
+
+// ManifestTrackRead is how a track read in secure mode
+type ManifestTrackRead struct {
+	Reads      int `json:"reads"`
+	Rereads    int `json:"rereads"`
+	Mismatched int `json:"mismatched_sectors"`
+	// Suspicious are the sectors no two reads agreed on, counted from the
+	// start of the track
+	Suspicious []ManifestSectorRange `json:"suspicious,omitempty"`
+}
+
+// ManifestSectorRange is a run of sectors, from Start up to but not
+// including End, with its position in the track as m:ss.ff
+type ManifestSectorRange struct {
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Time  string `json:"time"`
+}
+
+// ManifestTrackAccurateRip is a track's AccurateRip checksums and the
+// confidence of the version that matched, if one did
+type ManifestTrackAccurateRip struct {
+	CRCv1      string `json:"crc_v1"`
+	CRCv2      string `json:"crc_v2"`
+	Version    int    `json:"version,omitempty"`
+	Confidence int    `json:"confidence,omitempty"`
+}
+
+// ManifestFile is one of a track's files, named relative to the manifest
+type ManifestFile struct {
+	Format string `json:"format"`
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// ReadManifest reads the manifest of an album directory
+func ReadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest in %s: %w", dir, err)
+	}
+	if manifest.Version > manifestVersion {
+		return nil, fmt.Errorf("manifest in %s has unsupported version %d", dir, manifest.Version)
+	}
+	return &manifest, nil
+}
+
+// CDInfo rebuilds the disc's information from the manifest
+func (m *Manifest) CDInfo() *CDInfo {
+	cdInfo := &CDInfo{
+		Artist:               m.Metadata.Artist,
+		Album:                m.Metadata.Album,
+		Year:                 m.Metadata.Year,
+		Genre:                m.Metadata.Genre,
+		Comment:              m.Metadata.Comment,
+		DiscNumber:           m.Disc.DiscNumber,
+		TrackCount:           m.Disc.TrackCount,
+		DiscID:               m.Disc.CDDBDiscID,
+		CDDBDiscID:           m.Disc.CDDBDiscID,
+		Offsets:              m.Disc.Offsets,
+		MusicBrainzDiscID:    m.Disc.MusicBrainzDiscID,
+		Pregaps:              m.Disc.Pregaps,
+		HiddenTrack:          m.Disc.HiddenTrack,
+		MusicBrainzReleaseID: m.Metadata.MusicBrainzReleaseID,
+		Barcode:              m.Metadata.Barcode,
+		Compilation:          m.Metadata.Compilation,
+		MetadataSource:       m.Metadata.Source,
+		MetadataEdited:       m.Metadata.Edited,
+	}
+	for _, track := range m.Tracks {
+		if track.Number < 1 {
+			continue // The hidden track has no metadata of its own
+		}
+		cdInfo.Tracks = append(cdInfo.Tracks, TrackInfo{
+			Number:                 track.Number,
+			Title:                  track.Title,
+			Artist:                 track.Artist,
+			ISRC:                   track.ISRC,
+			MusicBrainzRecordingID: track.MusicBrainzRecordingID,
+		})
+	}
+	return cdInfo
+}
+
+// RetagAlbum rewrites the tags of an album directory's tracks or image from
+// its manifest, e.g. after the manifest's metadata was corrected. Embedded
+// covers are kept; formats the tagging package cannot write are skipped.
+func RetagAlbum(dir string) error {
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		return err
+	}
+
+	cdInfo := manifest.CDInfo()
+	for _, track := range manifest.Tracks {
+		for _, file := range track.Files {
+			path := filepath.Join(dir, file.Name)
+			if !tagging.Supported(path) {
+				continue
+			}
+			if err := tagging.WriteFile(path, trackTags(cdInfo, cdInfo.Track(track.Number))); err != nil {
+				return err
+			}
+		}
+	}
+	for _, file := range manifest.Image {
+		path := filepath.Join(dir, file.Name)
+		if !tagging.Supported(path) {
+			continue
+		}
+		// An embedded cue sheet is rebuilt from the corrected metadata
+		tags, err := tagging.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		cueSheet := ""
+		if tags.CueSheet != "" {
+			cueSheet = ImageCueSheet(cdInfo, cueFileType(file.Format), file.Name)
+		}
+		if err := tagging.WriteFile(path, imageTags(cdInfo, cueSheet)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newManifest describes a finished rip, without its files
+func (r *CDRipper) newManifest(ctx context.Context, cdInfo *CDInfo, targets []OutputTarget, startedAt time.Time) *Manifest {
+	source := cdInfo.MetadataSource
+	if source == "" {
+		source = "none"
+	}
+
+	manifest := &Manifest{
+		Version:   manifestVersion,
+		Ripper:    r.config.CDRipping.UserAgent,
+		StartedAt: startedAt.UTC(),
+		Disc: ManifestDisc{
+			CDDBDiscID:        cdInfo.CDDBDiscID,
+			MusicBrainzDiscID: cdInfo.MusicBrainzDiscID,
+			TrackCount:        cdInfo.TrackCount,
+			Offsets:           cdInfo.Offsets,
+			DiscNumber:        cdInfo.DiscNumber,
+		},
+		Metadata: ManifestMetadata{
+			Source:               source,
+			Edited:               cdInfo.MetadataEdited,
+			MusicBrainzReleaseID: cdInfo.MusicBrainzReleaseID,
+			Barcode:              cdInfo.Barcode,
+			Artist:               cdInfo.Artist,
+			Album:                cdInfo.Album,
+			Year:                 cdInfo.Year,
+			Genre:                cdInfo.Genre,
+			Comment:              cdInfo.Comment,
+			Compilation:          cdInfo.Compilation,
+		},
+		Extraction: ManifestExtraction{
+			Backend:   r.config.CDRipping.ExtractionBackend,
+			Execution: r.config.Execution.PreferredBackend,
+			Drive:     r.config.Drives.CDDrive,
+		},
+	}
+
+	offset := r.ReadOffset()
+	manifest.Extraction.DriveModel = offset.Drive
+	if r.config.CDRipping.ExtractionBackend == "cdparanoia" {
+		manifest.Extraction.ReadOffset = &ManifestReadOffset{Samples: offset.Samples, Source: offset.Source}
+		manifest.Extraction.Secure = r.config.CDRipping.Secure.Enabled
+		manifest.Extraction.GapPolicy = r.config.CDRipping.Gaps.Policy
+		manifest.Extraction.RipMode = "tracks"
+		if r.ImageRip() {
+			manifest.Extraction.RipMode = "image"
+		}
+	}
+
+	tools := []string{"cd-discid", r.config.CDRipping.ExtractionBackend}
+	for _, target := range targets {
+		encoder, settings := r.encoderOf(target)
+		manifest.Outputs = append(manifest.Outputs, ManifestOutput{Format: target.Format, Encoder: encoder, Settings: settings})
+		if encoder != "" {
+			tools = append(tools, encoder)
+		}
+	}
+	if run, ok := r.backend.(toolRunner); ok {
+		manifest.Tools = toolVersions(ctx, run, tools)
+	}
+
+	if ar := cdInfo.AccurateRip; ar != nil {
+		manifest.AccurateRip = &ManifestAccurateRip{ID: ar.ID.String(), Found: ar.Found, Error: ar.Error}
+	}
+	if ctdb := cdInfo.CTDB; ctdb != nil {
+		manifest.CTDB = &ManifestCTDB{
+			TOC:                   ctdb.TOC,
+			CRC:                   fmt.Sprintf("%08x", ctdb.CRC),
+			Found:                 ctdb.Found,
+			Error:                 ctdb.Error,
+			Confidence:            ctdb.Confidence,
+			DifferingTracks:       ctdb.DifferingTracks,
+			DifferingTrackSectors: ctdb.DifferingTrackSectors,
+			Repairable:            ctdb.Repairable,
+		}
+	}
+
+	if slices.ContainsFunc(cdInfo.Pregaps, func(pregap int) bool { return pregap > 0 }) {
+		manifest.Disc.Pregaps = cdInfo.Pregaps
+	}
+	manifest.Disc.HiddenTrack = cdInfo.HiddenTrack
+
+	for n := cdInfo.FirstTrack(); n <= cdInfo.TrackCount; n++ {
+		track := cdInfo.Track(n)
+		entry := ManifestTrack{
+			Number:                 track.Number,
+			Title:                  track.Title,
+			Artist:                 track.Artist,
+			ISRC:                   track.ISRC,
+			MusicBrainzRecordingID: track.MusicBrainzRecordingID,
+		}
+		if cdInfo.AccurateRip != nil {
+			for _, v := range cdInfo.AccurateRip.Tracks {
+				if v.Track == n {
+					entry.AccurateRip = &ManifestTrackAccurateRip{
+						CRCv1:      fmt.Sprintf("%08x", v.CRCv1),
+						CRCv2:      fmt.Sprintf("%08x", v.CRCv2),
+						Version:    v.Version,
+						Confidence: v.Confidence,
+					}
+				}
+			}
+		}
+		// The hidden track is not in the disc's checksums; its secure read,
+		// if any, is reported like any other track's
+		if cdInfo.CTDB != nil && n >= 1 && n <= len(cdInfo.CTDB.TrackCRCs) {
+			entry.CTDBCRC = fmt.Sprintf("%08x", cdInfo.CTDB.TrackCRCs[n-1])
+		}
+		for _, report := range cdInfo.SecureReads {
+			if report.Track != n {
+				continue
+			}
+			entry.Read = &ManifestTrackRead{Reads: report.Reads, Rereads: report.Rereads, Mismatched: report.Mismatched}
+			for _, s := range report.Suspicious {
+				entry.Read.Suspicious = append(entry.Read.Suspicious, ManifestSectorRange{Start: s.Start, End: s.End, Time: s.String()})
+			}
+		}
+		manifest.Tracks = append(manifest.Tracks, entry)
+	}
+	return manifest
+}
+
+// encoderOf returns the encoder that produced a target's files and the
+// quality settings it was given
+func (r *CDRipper) encoderOf(target OutputTarget) (string, []string) {
+	if r.config.CDRipping.ExtractionBackend == "cdparanoia" {
+		return encoderTools[target.Format], target.encoderArgs
+	}
+	encoder := encoderTools[target.Format]
+	if target.Format == "m4a" {
+		encoder = "fdkaac" // abcde's AAC encoder
+	}
+	return encoder, strings.Fields(r.abcdeEncoderOptions(target))
+}
+
+// saveManifests writes a manifest into every directory the disc's tracks or
+// image were filed in, listing the files in that directory with their
+// checksums
+func (r *CDRipper) saveManifests(manifest *Manifest, cdInfo *CDInfo, targets []OutputTarget) error {
+	dirs, err := albumDirs(cdInfo, targets)
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range dirs {
+		album := *manifest
+		album.Outputs = nil
+		album.Tracks = nil
+		album.Image = nil
+		for _, track := range manifest.Tracks {
+			track.Files = nil
+			album.Tracks = append(album.Tracks, track)
+		}
+
+		for _, target := range targets {
+			filed := false
+			if target.image {
+				path, err := target.imagePath(cdInfo)
+				if err != nil {
+					return err
+				}
+				if filepath.Dir(path) == dir {
+					file, err := describeFile(path)
+					if err != nil {
+						return err
+					}
+					file.Format = target.Format
+					album.Image = append(album.Image, file)
+					filed = true
+				}
+			} else {
+				for i, track := range album.Tracks {
+					path, err := target.trackPath(cdInfo, cdInfo.Track(track.Number))
+					if err != nil {
+						return err
+					}
+					if filepath.Dir(path) != dir {
+						continue
+					}
+					file, err := describeFile(path)
+					if err != nil {
+						return err
+					}
+					file.Format = target.Format
+					album.Tracks[i].Files = append(album.Tracks[i].Files, file)
+					filed = true
+				}
+			}
+			if filed {
+				for _, output := range manifest.Outputs {
+					if output.Format == target.Format {
+						album.Outputs = append(album.Outputs, output)
+					}
+				}
+			}
+		}
+
+		album.FinishedAt = time.Now().UTC()
+		data, err := json.MarshalIndent(album, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode manifest: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, ManifestFileName), append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to save manifest: %w", err)
+		}
+	}
+	return nil
+}
+
+// describeFile returns the name, size and SHA-256 checksum of a track's file
+func describeFile(path string) (ManifestFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ManifestFile{}, fmt.Errorf("failed to checksum %s: %w", path, err)
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return ManifestFile{}, fmt.Errorf("failed to checksum %s: %w", path, err)
+	}
+	return ManifestFile{
+		Name:   filepath.Base(path),
+		Size:   size,
+		SHA256: hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// versionArgs are the arguments that make each tool print its version
+var versionArgs = map[string][]string{
+	"abcde":      {"-v"},
+	"cd-discid":  {"--version"},
+	"cdparanoia": {"-V"},
+	"flac":       {"--version"},
+	"lame":       {"--version"},
+	"oggenc":     {"--version"},
+	"opusenc":    {"--version"},
+	"ffmpeg":     {"-version"},
+	"wavpack":    {"--version"},
+}
+
+// toolVersions asks each tool for its version, leaving out tools that fail
+// or say nothing
+func toolVersions(ctx context.Context, run toolRunner, tools []string) map[string]string {
+	versions := map[string]string{}
+	for _, tool := range tools {
+		args, ok := versionArgs[tool]
+		if !ok || versions[tool] != "" {
+			continue
+		}
+
+		toolCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		cmd, err := run.command(toolCtx, tool, args...)
+		if err != nil {
+			cancel()
+			continue
+		}
+		output, err := cmd.CombinedOutput()
+		cancel()
+		if err != nil {
+			continue
+		}
+
+		for _, line := range strings.Split(string(output), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				version, _, _ := strings.Cut(line, " Copyright")
+				versions[tool] = version
+				break
+			}
+		}
+	}
+	return versions
+}
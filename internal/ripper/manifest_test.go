@@ -0,0 +1,132 @@
+package ripper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestManifestRoundTrip(t *testing.T) {
+	r, cdInfo := newDuplicateTestRipper(t)
+	cdInfo.Year = "1999"
+	cdInfo.Offsets = []int{150, 15000, 30000}
+	cdInfo.TrackCount = 2
+	cdInfo.MetadataSource = "musicbrainz"
+	cdInfo.Tracks = []TrackInfo{
+		{Number: 1, Title: "One", Artist: "Band", ISRC: "USABC9900001"},
+		{Number: 2, Title: "Two", Artist: "Guest"},
+	}
+
+	targets, err := r.OutputTargets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files [][]ManifestFile
+	for n := 1; n <= cdInfo.TrackCount; n++ {
+		var trackFiles []ManifestFile
+		for _, target := range targets {
+			path, err := target.trackPath(cdInfo, cdInfo.Track(n))
+			if err != nil {
+				t.Fatal(err)
+			}
+			writeTrack(t, path, testDisc)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sum := sha256.Sum256(data)
+			trackFiles = append(trackFiles, ManifestFile{
+				Format: target.Format,
+				Name:   filepath.Base(path),
+				Size:   int64(len(data)),
+				SHA256: hex.EncodeToString(sum[:]),
+			})
+		}
+		files = append(files, trackFiles)
+	}
+
+	manifest := &Manifest{
+		Version:   manifestVersion,
+		Ripper:    "media-ripper/test",
+		StartedAt: time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC),
+		Disc: ManifestDisc{
+			CDDBDiscID:        cdInfo.CDDBDiscID,
+			MusicBrainzDiscID: cdInfo.MusicBrainzDiscID,
+			TrackCount:        2,
+			Offsets:           cdInfo.Offsets,
+		},
+		Metadata:   ManifestMetadata{Source: "musicbrainz", Artist: "Band", Album: "Album", Year: "1999"},
+		Extraction: ManifestExtraction{Backend: "cdparanoia", Execution: "native", Drive: "/dev/sr0"},
+		Tools:      map[string]string{"flac": "flac 1.4.3"},
+		Outputs:    []ManifestOutput{{Format: "flac", Encoder: "flac", Settings: []string{"-5"}}, {Format: "wav"}},
+		Tracks: []ManifestTrack{
+			{Number: 1, Title: "One", Artist: "Band", ISRC: "USABC9900001"},
+			{Number: 2, Title: "Two", Artist: "Guest"},
+		},
+	}
+	if err := r.saveManifests(manifest, cdInfo, targets); err != nil {
+		t.Fatalf("saveManifests() error = %v", err)
+	}
+
+	dir := filepath.Join(r.config.Paths.Music, "Band", "Album")
+	got, err := ReadManifest(dir)
+	if err != nil {
+		t.Fatalf("ReadManifest() error = %v", err)
+	}
+	if got.FinishedAt.IsZero() {
+		t.Error("FinishedAt not recorded")
+	}
+
+	want := *manifest
+	want.FinishedAt = got.FinishedAt
+	want.Tracks = []ManifestTrack{
+		{Number: 1, Title: "One", Artist: "Band", ISRC: "USABC9900001", Files: files[0]},
+		{Number: 2, Title: "Two", Artist: "Guest", Files: files[1]},
+	}
+	if !reflect.DeepEqual(got, &want) {
+		t.Errorf("ReadManifest() =\n%+v\nwant\n%+v", got, &want)
+	}
+
+	// The disc rebuilt from the manifest is the one that was ripped
+	rebuilt := got.CDInfo()
+	if rebuilt.Artist != "Band" || rebuilt.Album != "Album" || rebuilt.CDDBDiscID != cdInfo.CDDBDiscID ||
+		rebuilt.MusicBrainzDiscID != cdInfo.MusicBrainzDiscID || !reflect.DeepEqual(rebuilt.Offsets, cdInfo.Offsets) {
+		t.Errorf("CDInfo() = %+v", rebuilt)
+	}
+	if !reflect.DeepEqual(rebuilt.Tracks, cdInfo.Tracks) {
+		t.Errorf("CDInfo() tracks = %+v, want %+v", rebuilt.Tracks, cdInfo.Tracks)
+	}
+}
+
+func TestReadManifestErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"newer version", `{"version": 2}`, "unsupported version 2"},
+		{"not json", `version = 1`, "failed to parse manifest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, ManifestFileName), []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := ReadManifest(dir)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ReadManifest() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := ReadManifest(t.TempDir()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ReadManifest() of a folder without one: error = %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package ripper
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// VariousArtists is the album artist used for compilations
+const VariousArtists = "Various Artists"
+
+// titleCaseMinorWords stay lower case unless they start or end a title
+var titleCaseMinorWords = map[string]bool{
+	"a": true, "an": true, "and": true, "as": true, "at": true, "but": true,
+	"by": true, "for": true, "in": true, "nor": true, "of": true, "on": true,
+	"or": true, "the": true, "to": true, "with": true,
+}
+
+// SetAlbumArtist changes the album artist, carrying the change over to tracks
+// that were credited to the old album artist
+func (c *CDInfo) SetAlbumArtist(artist string) {
+	for i := range c.Tracks {
+		if c.Tracks[i].Artist == c.Artist {
+			c.Tracks[i].Artist = artist
+		}
+	}
+	c.Artist = artist
+}
+
+// TitleCaseAll title-cases the album, artists and every track title
+func (c *CDInfo) TitleCaseAll() {
+	c.Artist = titleCase(c.Artist)
+	c.Album = titleCase(c.Album)
+	for i := range c.Tracks {
+		c.Tracks[i].Title = titleCase(c.Tracks[i].Title)
+		c.Tracks[i].Artist = titleCase(c.Tracks[i].Artist)
+	}
+}
+
+// SwapArtistTitle swaps the artist and title of every track, fixing entries
+// submitted the wrong way round
+func (c *CDInfo) SwapArtistTitle() {
+	for i := range c.Tracks {
+		c.Tracks[i].Artist, c.Tracks[i].Title = c.Tracks[i].Title, c.Tracks[i].Artist
+	}
+}
+
+// SetCompilation marks the disc as a various artists compilation, or clears
+// the flag. Track artists are kept so each file is still tagged correctly.
+func (c *CDInfo) SetCompilation(compilation bool) {
+	if compilation == c.Compilation {
+		return
+	}
+	c.Compilation = compilation
+
+	if compilation {
+		// Keep the old album artist on tracks that had no artist of their own
+		for i := range c.Tracks {
+			if c.Tracks[i].Artist == "" {
+				c.Tracks[i].Artist = c.Artist
+			}
+		}
+		c.Artist = VariousArtists
+	}
+}
+
+// Track returns the metadata for track number n with gaps filled in: a
+// numbered title when the disc has none and the album artist as track artist.
+// Track 0 is the hidden track before track 1.
+func (c *CDInfo) Track(n int) TrackInfo {
+	var track TrackInfo
+	if n >= 1 && n <= len(c.Tracks) {
+		track = c.Tracks[n-1]
+	}
+	track.Number = n
+	if track.Title == "" && n == 0 {
+		track.Title = "Hidden Track"
+	} else if track.Title == "" {
+		track.Title = fmt.Sprintf("Track %02d", n)
+	}
+	if track.Artist == "" {
+		track.Artist = c.Artist
+	}
+	return track
+}
+
+// titleCase upper-cases the first letter of each word. Letters after the first
+// are left alone so names like "AC/DC" or "McCartney" survive.
+func titleCase(s string) string {
+	words := strings.Fields(s)
+	for i, word := range words {
+		lower := strings.ToLower(word)
+		if i > 0 && i < len(words)-1 && titleCaseMinorWords[lower] {
+			words[i] = lower
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(word)
+		if r == utf8.RuneError {
+			continue
+		}
+		words[i] = string(unicode.ToUpper(r)) + word[size:]
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,153 @@
+package ripper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTitleCase(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"hello world", "Hello World"},
+		{"the end of the road", "The End of the Road"},
+		{"songs to learn and sing", "Songs to Learn and Sing"},
+		{"what are you waiting for", "What Are You Waiting For"},
+		{"back in black by AC/DC", "Back in Black by AC/DC"},
+		{"paul mcCartney", "Paul McCartney"},
+		{"THE WALL", "THE WALL"},
+		{"  extra   spaces ", "Extra Spaces"},
+		{"élan vital", "Élan Vital"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := titleCase(tt.in); got != tt.want {
+				t.Errorf("titleCase(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetAlbumArtist(t *testing.T) {
+	cdInfo := &CDInfo{
+		Artist: "Band",
+		Tracks: []TrackInfo{
+			{Number: 1, Artist: "Band"},
+			{Number: 2, Artist: "Guest"},
+			{Number: 3, Artist: ""},
+		},
+	}
+
+	cdInfo.SetAlbumArtist("The Band")
+
+	if cdInfo.Artist != "The Band" {
+		t.Errorf("Artist = %q", cdInfo.Artist)
+	}
+	want := []string{"The Band", "Guest", ""}
+	for i, artist := range want {
+		if cdInfo.Tracks[i].Artist != artist {
+			t.Errorf("track %d artist = %q, want %q", i+1, cdInfo.Tracks[i].Artist, artist)
+		}
+	}
+}
+
+func TestTitleCaseAll(t *testing.T) {
+	cdInfo := &CDInfo{
+		Artist: "the band",
+		Album:  "songs of the sea",
+		Tracks: []TrackInfo{
+			{Number: 1, Title: "a day in the life", Artist: "the band"},
+			{Number: 2, Title: "out of time", Artist: "guest star"},
+		},
+	}
+
+	cdInfo.TitleCaseAll()
+
+	want := &CDInfo{
+		Artist: "The Band",
+		Album:  "Songs of the Sea",
+		Tracks: []TrackInfo{
+			{Number: 1, Title: "A Day in the Life", Artist: "The Band"},
+			{Number: 2, Title: "Out of Time", Artist: "Guest Star"},
+		},
+	}
+	if !reflect.DeepEqual(cdInfo, want) {
+		t.Errorf("TitleCaseAll() = %+v, want %+v", cdInfo, want)
+	}
+}
+
+func TestSwapArtistTitle(t *testing.T) {
+	cdInfo := &CDInfo{
+		Tracks: []TrackInfo{
+			{Number: 1, Title: "Band", Artist: "First Song"},
+			{Number: 2, Title: "Guest", Artist: ""},
+		},
+	}
+
+	cdInfo.SwapArtistTitle()
+
+	want := []TrackInfo{
+		{Number: 1, Title: "First Song", Artist: "Band"},
+		{Number: 2, Title: "", Artist: "Guest"},
+	}
+	if !reflect.DeepEqual(cdInfo.Tracks, want) {
+		t.Errorf("tracks = %+v, want %+v", cdInfo.Tracks, want)
+	}
+
+	cdInfo.SwapArtistTitle()
+	if cdInfo.Tracks[0].Title != "Band" || cdInfo.Tracks[0].Artist != "First Song" {
+		t.Errorf("swapping twice = %+v, want the original", cdInfo.Tracks[0])
+	}
+}
+
+func TestSetCompilation(t *testing.T) {
+	tests := []struct {
+		name        string
+		cdInfo      CDInfo
+		compilation bool
+		wantArtist  string
+		wantTracks  []string
+	}{
+		{
+			name:        "mark compilation",
+			cdInfo:      CDInfo{Artist: "Band", Tracks: []TrackInfo{{Artist: "Band"}, {Artist: ""}, {Artist: "Guest"}}},
+			compilation: true,
+			wantArtist:  VariousArtists,
+			wantTracks:  []string{"Band", "Band", "Guest"},
+		},
+		{
+			name:        "already compilation",
+			cdInfo:      CDInfo{Artist: "Hits", Compilation: true, Tracks: []TrackInfo{{Artist: ""}}},
+			compilation: true,
+			wantArtist:  "Hits",
+			wantTracks:  []string{""},
+		},
+		{
+			name:        "clear compilation",
+			cdInfo:      CDInfo{Artist: VariousArtists, Compilation: true, Tracks: []TrackInfo{{Artist: "One"}, {Artist: "Two"}}},
+			compilation: false,
+			wantArtist:  VariousArtists,
+			wantTracks:  []string{"One", "Two"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cdInfo := tt.cdInfo
+			cdInfo.SetCompilation(tt.compilation)
+
+			if cdInfo.Compilation != tt.compilation {
+				t.Errorf("Compilation = %v, want %v", cdInfo.Compilation, tt.compilation)
+			}
+			if cdInfo.Artist != tt.wantArtist {
+				t.Errorf("Artist = %q, want %q", cdInfo.Artist, tt.wantArtist)
+			}
+			for i, artist := range tt.wantTracks {
+				if cdInfo.Tracks[i].Artist != artist {
+					t.Errorf("track %d artist = %q, want %q", i+1, cdInfo.Tracks[i].Artist, artist)
+				}
+			}
+		})
+	}
+}
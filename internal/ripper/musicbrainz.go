@@ -0,0 +1,219 @@
+package ripper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrDiscNotFound is returned when a metadata service has no entry for a disc
+var ErrDiscNotFound = errors.New("disc not found")
+
+// musicBrainzRateLimit is the minimum delay between requests allowed by the
+// MusicBrainz web service for anonymous clients
+const musicBrainzRateLimit = time.Second
+
+// MusicBrainzClient queries the MusicBrainz web service (version 2)
+type MusicBrainzClient struct {
+	baseURL     string
+	userAgent   string
+	httpClient  *http.Client
+	minInterval time.Duration
+
+	mu          sync.Mutex
+	lastRequest time.Time
+}
+
+// NewMusicBrainzClient creates a client for the given server, e.g. https://musicbrainz.org
+func NewMusicBrainzClient(baseURL, userAgent string) *MusicBrainzClient {
+	return &MusicBrainzClient{
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		userAgent:   userAgent,
+		httpClient:  &http.Client{Timeout: 30 * time.Second},
+		minInterval: musicBrainzRateLimit,
+	}
+}
+
+// MusicBrainzRelease is the subset of a MusicBrainz release used by the ripper
+type MusicBrainzRelease struct {
+	ID           string              `json:"id"`
+	Title        string              `json:"title"`
+	Date         string              `json:"date"`
+	Country      string              `json:"country"`
+	Barcode      string              `json:"barcode"`
+	ArtistCredit []musicBrainzCredit `json:"artist-credit"`
+	Media        []musicBrainzMedium `json:"media"`
+}
+
+type musicBrainzCredit struct {
+	Name       string `json:"name"`
+	JoinPhrase string `json:"joinphrase"`
+}
+
+type musicBrainzMedium struct {
+	Position   int    `json:"position"`
+	Format     string `json:"format"`
+	TrackCount int    `json:"track-count"`
+	Discs      []struct {
+		ID string `json:"id"`
+	} `json:"discs"`
+	Tracks []musicBrainzTrack `json:"tracks"`
+}
+
+type musicBrainzTrack struct {
+	Position     int                 `json:"position"`
+	Title        string              `json:"title"`
+	Length       int                 `json:"length"` // milliseconds
+	ArtistCredit []musicBrainzCredit `json:"artist-credit"`
+	Recording    struct {
+		ID    string   `json:"id"`
+		ISRCs []string `json:"isrcs"`
+	} `json:"recording"`
+}
+
+// LookupDiscID returns the releases that contain the given MusicBrainz disc ID
+func (c *MusicBrainzClient) LookupDiscID(ctx context.Context, discID string) ([]MusicBrainzRelease, error) {
+	if discID == "" {
+		return nil, fmt.Errorf("no MusicBrainz disc ID available")
+	}
+
+	endpoint := fmt.Sprintf("%s/ws/2/discid/%s?inc=artists+recordings+isrcs&fmt=json", c.baseURL, url.PathEscape(discID))
+
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MusicBrainz request: %w", err)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("MusicBrainz request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("MusicBrainz disc %s: %w", discID, ErrDiscNotFound)
+	case http.StatusServiceUnavailable:
+		return nil, fmt.Errorf("MusicBrainz rate limit exceeded (status %d)", resp.StatusCode)
+	default:
+		return nil, fmt.Errorf("MusicBrainz returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Releases []MusicBrainzRelease `json:"releases"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode MusicBrainz response: %w", err)
+	}
+
+	if len(result.Releases) == 0 {
+		return nil, fmt.Errorf("MusicBrainz disc %s: %w", discID, ErrDiscNotFound)
+	}
+	return result.Releases, nil
+}
+
+// wait blocks until the rate limit allows another request
+func (c *MusicBrainzClient) wait(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if delay := c.minInterval - time.Since(c.lastRequest); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	c.lastRequest = time.Now()
+	return nil
+}
+
+// ApplyTo copies the release metadata for the medium matching cdInfo onto it
+func (rel *MusicBrainzRelease) ApplyTo(cdInfo *CDInfo) error {
+	medium := rel.findMedium(cdInfo)
+	if medium == nil {
+		return fmt.Errorf("release %s has no medium with %d tracks", rel.ID, cdInfo.TrackCount)
+	}
+
+	cdInfo.Artist = joinCredits(rel.ArtistCredit)
+	cdInfo.Album = rel.Title
+	if len(rel.Date) >= 4 {
+		cdInfo.Year = rel.Date[:4]
+	}
+	cdInfo.MusicBrainzReleaseID = rel.ID
+	cdInfo.Barcode = rel.Barcode
+	cdInfo.Compilation = isVariousArtists(cdInfo.Artist)
+	// Only discs of a set get a number, so single discs keep the plain layout
+	cdInfo.DiscNumber = 0
+	if len(rel.Media) > 1 {
+		cdInfo.DiscNumber = medium.Position
+	}
+
+	if len(cdInfo.Tracks) < len(medium.Tracks) {
+		cdInfo.Tracks = append(cdInfo.Tracks, make([]TrackInfo, len(medium.Tracks)-len(cdInfo.Tracks))...)
+	}
+
+	for i, track := range medium.Tracks {
+		info := &cdInfo.Tracks[i]
+		info.Number = i + 1
+		info.Title = track.Title
+		info.Artist = joinCredits(track.ArtistCredit)
+		if info.Artist == "" {
+			info.Artist = cdInfo.Artist
+		}
+		if track.Length > 0 {
+			seconds := track.Length / 1000
+			info.Duration = fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+		}
+		info.MusicBrainzRecordingID = track.Recording.ID
+		if len(track.Recording.ISRCs) > 0 {
+			// A recording can have several; any of them identifies it
+			info.ISRC = track.Recording.ISRCs[0]
+		}
+	}
+
+	return nil
+}
+
+// findMedium picks the medium holding this disc, preferring a disc ID match
+func (rel *MusicBrainzRelease) findMedium(cdInfo *CDInfo) *musicBrainzMedium {
+	for i := range rel.Media {
+		for _, disc := range rel.Media[i].Discs {
+			if disc.ID == cdInfo.MusicBrainzDiscID {
+				return &rel.Media[i]
+			}
+		}
+	}
+	for i := range rel.Media {
+		if len(rel.Media[i].Tracks) == cdInfo.TrackCount {
+			return &rel.Media[i]
+		}
+	}
+	return nil
+}
+
+// joinCredits renders an artist credit list the way MusicBrainz displays it
+func joinCredits(credits []musicBrainzCredit) string {
+	var sb strings.Builder
+	for _, credit := range credits {
+		sb.WriteString(credit.Name)
+		sb.WriteString(credit.JoinPhrase)
+	}
+	return strings.TrimSpace(sb.String())
+}
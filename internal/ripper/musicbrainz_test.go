@@ -0,0 +1,171 @@
+package ripper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// musicBrainzTestDiscID is the disc the stand-in server knows
+const musicBrainzTestDiscID = "I5l9cCSFccLKFEKS.7wqSZAorPU-"
+
+// newMusicBrainzTestClient returns a client for a stand-in server answering
+// disc ID lookups with handler, without the rate limit
+func newMusicBrainzTestClient(t *testing.T, handler http.HandlerFunc) *MusicBrainzClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	client := NewMusicBrainzClient(server.URL+"/", "media-ripper-test/1.0")
+	client.minInterval = 0
+	return client
+}
+
+// musicBrainzReply returns a handler that checks the lookup request and
+// replies with status and body
+func musicBrainzReply(t *testing.T, status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ws/2/discid/"+musicBrainzTestDiscID {
+			t.Errorf("request path = %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("inc"); got != "artists recordings isrcs" {
+			t.Errorf("inc = %q", got)
+		}
+		if got := r.Header.Get("User-Agent"); got != "media-ripper-test/1.0" {
+			t.Errorf("User-Agent = %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}
+}
+
+func TestMusicBrainzLookupHit(t *testing.T) {
+	client := newMusicBrainzTestClient(t, musicBrainzReply(t, http.StatusOK, `{
+		"releases": [{
+			"id": "release-1",
+			"title": "The Album",
+			"date": "1999-05-01",
+			"barcode": "012345678905",
+			"artist-credit": [{"name": "Band", "joinphrase": " feat. "}, {"name": "Guest"}],
+			"media": [{
+				"position": 1,
+				"discs": [{"id": "I5l9cCSFccLKFEKS.7wqSZAorPU-"}],
+				"tracks": [
+					{"position": 1, "title": "First", "length": 185000,
+						"recording": {"id": "rec-1", "isrcs": ["USABC9900001"]}},
+					{"position": 2, "title": "Second", "length": 61000,
+						"artist-credit": [{"name": "Guest"}], "recording": {"id": "rec-2"}}
+				]
+			}]
+		}]
+	}`))
+
+	releases, err := client.LookupDiscID(context.Background(), musicBrainzTestDiscID)
+	if err != nil {
+		t.Fatalf("LookupDiscID() error = %v", err)
+	}
+	if len(releases) != 1 {
+		t.Fatalf("got %d releases, want 1", len(releases))
+	}
+
+	cdInfo := &CDInfo{TrackCount: 2, MusicBrainzDiscID: musicBrainzTestDiscID}
+	if err := releases[0].ApplyTo(cdInfo); err != nil {
+		t.Fatalf("ApplyTo() error = %v", err)
+	}
+	if cdInfo.Artist != "Band feat. Guest" || cdInfo.Album != "The Album" || cdInfo.Year != "1999" {
+		t.Errorf("album = %q / %q (%s)", cdInfo.Artist, cdInfo.Album, cdInfo.Year)
+	}
+	if cdInfo.MusicBrainzReleaseID != "release-1" || cdInfo.DiscNumber != 0 {
+		t.Errorf("release = %s, disc %d", cdInfo.MusicBrainzReleaseID, cdInfo.DiscNumber)
+	}
+	if cdInfo.Barcode != "012345678905" {
+		t.Errorf("Barcode = %q", cdInfo.Barcode)
+	}
+	want := []TrackInfo{
+		{Number: 1, Title: "First", Artist: "Band feat. Guest", Duration: "3:05", ISRC: "USABC9900001", MusicBrainzRecordingID: "rec-1"},
+		{Number: 2, Title: "Second", Artist: "Guest", Duration: "1:01", MusicBrainzRecordingID: "rec-2"},
+	}
+	if len(cdInfo.Tracks) != len(want) {
+		t.Fatalf("got %d tracks, want %d", len(cdInfo.Tracks), len(want))
+	}
+	for i := range want {
+		if cdInfo.Tracks[i] != want[i] {
+			t.Errorf("track %d = %+v, want %+v", i+1, cdInfo.Tracks[i], want[i])
+		}
+	}
+}
+
+func TestMusicBrainzLookupMiss(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"not found", http.StatusNotFound, `{"error": "Not Found"}`},
+		{"no releases", http.StatusOK, `{"releases": []}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newMusicBrainzTestClient(t, musicBrainzReply(t, tt.status, tt.body))
+			_, err := client.LookupDiscID(context.Background(), musicBrainzTestDiscID)
+			if !errors.Is(err, ErrDiscNotFound) {
+				t.Errorf("LookupDiscID() error = %v, want ErrDiscNotFound", err)
+			}
+		})
+	}
+}
+
+func TestMusicBrainzLookupMultipleReleases(t *testing.T) {
+	// The disc is the second medium of a two disc set in one release, and
+	// the only medium of another
+	client := newMusicBrainzTestClient(t, musicBrainzReply(t, http.StatusOK, `{
+		"releases": [
+			{"id": "set", "title": "The Set", "artist-credit": [{"name": "Band"}], "media": [
+				{"position": 1, "discs": [{"id": "other"}], "tracks": [{"title": "Wrong"}]},
+				{"position": 2, "discs": [{"id": "I5l9cCSFccLKFEKS.7wqSZAorPU-"}], "tracks": [{"title": "Right"}]}
+			]},
+			{"id": "single", "title": "The Single", "artist-credit": [{"name": "Band"}], "media": [
+				{"position": 1, "discs": [{"id": "I5l9cCSFccLKFEKS.7wqSZAorPU-"}], "tracks": [{"title": "Only"}]}
+			]}
+		]
+	}`))
+
+	releases, err := client.LookupDiscID(context.Background(), musicBrainzTestDiscID)
+	if err != nil {
+		t.Fatalf("LookupDiscID() error = %v", err)
+	}
+	if len(releases) != 2 {
+		t.Fatalf("got %d releases, want 2", len(releases))
+	}
+
+	tests := []struct {
+		release MusicBrainzRelease
+		album   string
+		title   string
+		disc    int
+	}{
+		{releases[0], "The Set", "Right", 2},
+		{releases[1], "The Single", "Only", 0},
+	}
+	for _, tt := range tests {
+		cdInfo := &CDInfo{TrackCount: 1, MusicBrainzDiscID: musicBrainzTestDiscID}
+		if err := tt.release.ApplyTo(cdInfo); err != nil {
+			t.Fatalf("ApplyTo(%s) error = %v", tt.release.ID, err)
+		}
+		if cdInfo.Album != tt.album || cdInfo.Tracks[0].Title != tt.title || cdInfo.DiscNumber != tt.disc {
+			t.Errorf("ApplyTo(%s) = %q, track %q, disc %d; want %q, %q, %d",
+				tt.release.ID, cdInfo.Album, cdInfo.Tracks[0].Title, cdInfo.DiscNumber, tt.album, tt.title, tt.disc)
+		}
+	}
+}
+
+func TestMusicBrainzLookupMalformed(t *testing.T) {
+	client := newMusicBrainzTestClient(t, musicBrainzReply(t, http.StatusOK, `{"releases": [{"id": `))
+
+	_, err := client.LookupDiscID(context.Background(), musicBrainzTestDiscID)
+	if err == nil || errors.Is(err, ErrDiscNotFound) {
+		t.Errorf("LookupDiscID() error = %v, want a decoding error", err)
+	}
+}
@@ -0,0 +1,182 @@
+package ripper
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/Bparsons0904/ripper/internal/naming"
+)
+
+// OutputTarget is one format produced from a rip and the library it is
+// filed in
+type OutputTarget struct {
+	Format string
+	Root   string
+
+	encoderArgs []string       // Quality options from the format's encoder profile
+	paths       *naming.Scheme // Names each track's file under Root
+	image       bool           // The disc is filed as one image, not a file per track
+}
+
+// OutputTargets returns the configured output formats. Formats without
+// their own root are filed in the music directory.
+func (r *CDRipper) OutputTargets() ([]OutputTarget, error) {
+	cdCfg := r.config.CDRipping
+
+	paths, err := naming.New(r.config.Paths.NamingTemplates(), r.config.Paths.SanitizePolicy())
+	if err != nil {
+		return nil, fmt.Errorf("invalid naming template: %w", err)
+	}
+
+	targets := make([]OutputTarget, 0, len(cdCfg.OutputFormat))
+	for _, format := range cdCfg.OutputFormat {
+		root := cdCfg.Outputs[format].Root
+		if root == "" {
+			root = r.config.Paths.Music
+		}
+		targets = append(targets, OutputTarget{
+			Format:      format,
+			Root:        root,
+			encoderArgs: r.encoderArgs(format),
+			paths:       paths,
+			image:       r.ImageRip(),
+		})
+	}
+	return targets, nil
+}
+
+// trackPath returns where a track is filed under the target's root
+func (t OutputTarget) trackPath(cdInfo *CDInfo, track TrackInfo) (string, error) {
+	return t.paths.FilePath(t.Root, pathFields(cdInfo, track), fileExtension(t.Format))
+}
+
+// imagePath returns where the disc's image is filed under the target's
+// root: in the directory track 1 would be filed in, named after it
+func (t OutputTarget) imagePath(cdInfo *CDInfo) (string, error) {
+	path, err := t.trackPath(cdInfo, cdInfo.Track(1))
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Dir(path)
+	return filepath.Join(dir, filepath.Base(dir)+"."+fileExtension(t.Format)), nil
+}
+
+// files returns the paths of the files the target makes of the disc: its
+// image, or a file per track
+func (t OutputTarget) files(cdInfo *CDInfo) ([]string, error) {
+	if t.image {
+		path, err := t.imagePath(cdInfo)
+		if err != nil {
+			return nil, err
+		}
+		return []string{path}, nil
+	}
+
+	var paths []string
+	for n := cdInfo.FirstTrack(); n <= cdInfo.TrackCount; n++ {
+		path, err := t.trackPath(cdInfo, cdInfo.Track(n))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// ImageRip reports whether discs are ripped as images. Only the cdparanoia
+// backend rips them.
+func (r *CDRipper) ImageRip() bool {
+	return r.config.CDRipping.RipMode == "image" && r.config.CDRipping.ExtractionBackend == "cdparanoia"
+}
+
+// albumDirs returns the directories the disc's tracks are filed in, usually
+// one album directory per target
+func albumDirs(cdInfo *CDInfo, targets []OutputTarget) ([]string, error) {
+	var dirs []string
+	for _, target := range targets {
+		last := cdInfo.TrackCount
+		if target.image {
+			last = 1 // The image is filed where track 1 would be
+		}
+		for n := 1; n <= last; n++ {
+			path, err := target.trackPath(cdInfo, cdInfo.Track(n))
+			if err != nil {
+				return nil, err
+			}
+			if dir := filepath.Dir(path); !slices.Contains(dirs, dir) {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	return dirs, nil
+}
+
+// pathFields returns the values the naming templates see for a track. A
+// copy ripped beside an existing rip has its number added to the album.
+func pathFields(cdInfo *CDInfo, track TrackInfo) naming.Fields {
+	album := cdInfo.Album
+	if cdInfo.albumCopy > 1 {
+		album = fmt.Sprintf("%s (%d)", album, cdInfo.albumCopy)
+	}
+	return naming.Fields{
+		AlbumArtist: cdInfo.Artist,
+		Album:       album,
+		Artist:      track.Artist,
+		Title:       track.Title,
+		Year:        cdInfo.Year,
+		Genre:       cdInfo.Genre,
+		Track:       track.Number,
+		TrackTotal:  cdInfo.TrackCount,
+		Disc:        cdInfo.DiscNumber,
+		Compilation: cdInfo.Compilation,
+	}
+}
+
+// encoderArgs returns the quality options for a format's encoder from its
+// encoder profile
+func (r *CDRipper) encoderArgs(format string) []string {
+	encoders := r.config.CDRipping.Encoders
+	switch format {
+	case "flac":
+		return []string{fmt.Sprintf("-%d", encoders.FLAC.CompressionLevel)}
+	case "mp3":
+		if encoders.MP3.Mode == "cbr" {
+			return []string{"-b", strconv.Itoa(encoders.MP3.Bitrate), "--cbr"}
+		}
+		return []string{"-V", strconv.Itoa(encoders.MP3.VBRQuality)}
+	case "ogg":
+		return []string{"-q", strconv.FormatFloat(encoders.Ogg.Quality, 'f', -1, 64)}
+	case "opus":
+		return []string{"--bitrate", strconv.Itoa(encoders.Opus.Bitrate)}
+	case "m4a":
+		return []string{"-b:a", fmt.Sprintf("%dk", encoders.M4A.Bitrate)}
+	case "wavpack":
+		switch encoders.WavPack.Mode {
+		case "fast":
+			return []string{"-f"}
+		case "high":
+			return []string{"-h"}
+		case "very_high":
+			return []string{"-hh"}
+		}
+	}
+	return nil
+}
+
+// moveFile moves src to dst, creating dst's directory. The file is copied
+// when it cannot be renamed, e.g. across filesystems.
+func moveFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	if err := copyFile(src, dst); err != nil {
+		return fmt.Errorf("failed to move %s: %w", src, err)
+	}
+	return os.Remove(src)
+}
@@ -0,0 +1,389 @@
+package ripper
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Bparsons0904/ripper/internal/config"
+)
+
+// cdparanoia reports read positions in 16-bit samples, 1176 to a sector
+const paranoiaWordsPerSector = 1176
+
+// paranoiaProgressPattern matches the progress lines cdparanoia prints on
+// stderr with -e, e.g. "##: -2 [wrote] @ 1234464"
+var paranoiaProgressPattern = regexp.MustCompile(`^##: (-?\d+) \[([^\]]*)\] @ (\d+)`)
+
+// ParanoiaStatus is the state of a track being read by cdparanoia
+type ParanoiaStatus struct {
+	SectorsRead  int
+	TotalSectors int
+	Corrections  int // Jitter, drift and dropped/duplicated bytes that were repaired
+	Skips        int // Ranges cdparanoia gave up on
+	ReadErrors   int // Scratches and transport errors reported by the drive
+}
+
+// Percent returns how much of the track has been read
+func (s ParanoiaStatus) Percent() int {
+	if s.TotalSectors <= 0 {
+		return 0
+	}
+	return s.SectorsRead * 100 / s.TotalSectors
+}
+
+// paranoiaExtractor reads each track with cdparanoia into a WAV file in the
+// work directory, then encodes the WAV files into the library
+type paranoiaExtractor struct {
+	r   *CDRipper
+	run toolRunner
+}
+
+// Rip extracts every track of the disc once and encodes the results into
+// each target. Tracks are handed to the encoder pool as soon as they are
+// read, and filed in the library once every track is encoded. Audio in
+// track 1's pregap is ripped as track 00; a silent pregap is filed like the
+// others, by the gap policy. In image mode the tracks are instead joined
+// into one image of the disc, pregaps where they play, once all are read.
+func (e *paranoiaExtractor) Rip(ctx context.Context, cdInfo *CDInfo, targets []OutputTarget) error {
+	r := e.r
+	if len(cdInfo.Offsets) != cdInfo.TrackCount+1 {
+		return fmt.Errorf("cdparanoia needs %d track offsets, got %d", cdInfo.TrackCount+1, len(cdInfo.Offsets))
+	}
+	image := r.ImageRip()
+	if image {
+		for _, target := range targets {
+			if !slices.Contains(config.ImageFormats, target.Format) {
+				return fmt.Errorf("image mode writes only %s, not %s", strings.Join(config.ImageFormats, ", "), target.Format)
+			}
+		}
+	}
+
+	workDir, err := r.workDir(cdInfo)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	// An encoding failure stops the drive too
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if r.config.CDRipping.Gaps.Detect {
+		r.sendProgress(ProgressInfo{TotalTracks: cdInfo.TrackCount, Status: "Scanning for track gaps with cdrdao..."})
+		pregaps, err := e.scanGaps(ctx, cdInfo, workDir)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			r.sendProgress(ProgressInfo{
+				TotalTracks: cdInfo.TrackCount,
+				Status:      fmt.Sprintf("Gap scan failed, only the gap before track 1 is known: %v", err),
+			})
+		} else {
+			cdInfo.Pregaps = pregaps
+		}
+	}
+
+	verifier := r.newRipVerifier(ctx, cdInfo)
+	secure := r.config.CDRipping.Secure.Enabled
+	// An image keeps the gaps as the disc plays them
+	prepend := r.config.CDRipping.Gaps.Policy == "prepend" && !image
+	cdInfo.SecureReads = nil
+	cdInfo.HiddenTrack = false
+
+	progress := newRipProgress(r, cdInfo, len(targets))
+	if secure {
+		progress.passes = 2
+	}
+	progress.image = image
+	pool := r.startEncoders(ctx, cancel, e.run, cdInfo, targets, workDir, progress)
+
+	var parts []string // The tracks read for the image, in disc order
+	queue := func(track TrackInfo, wavPath string) {
+		if image {
+			parts = append(parts, wavPath)
+		} else {
+			pool.add(track, wavPath)
+		}
+	}
+
+	var readErr error
+	lead := "" // The pregap of the next track, with the prepend policy
+	for n := 0; n <= cdInfo.TrackCount; n++ {
+		if n == 0 && cdInfo.Pregap(1) == 0 {
+			continue
+		}
+		track := cdInfo.Track(n)
+		wavPath := filepath.Join(workDir, fmt.Sprintf("track%02d.wav", track.Number))
+
+		var err error
+		var report TrackReadReport
+		if secure {
+			report, err = e.readSecurely(ctx, cdInfo, track, wavPath, progress)
+		} else {
+			err = e.ExtractTrack(ctx, cdInfo, track.Number, wavPath, func(status ParanoiaStatus) {
+				progress.trackReading(track, status)
+			})
+		}
+		if err != nil {
+			readErr = fmt.Errorf("failed to read track %d: %w", track.Number, err)
+			cancel()
+			break
+		}
+
+		_, sectors := cdInfo.trackSectors(n)
+		progress.trackRead(sectors)
+		if secure {
+			cdInfo.SecureReads = append(cdInfo.SecureReads, report)
+			progress.trackSecured(report)
+		}
+
+		if n == 0 {
+			silent, err := isSilent(wavPath)
+			if err != nil {
+				readErr = fmt.Errorf("failed to read the pregap of track 1: %w", err)
+				cancel()
+				break
+			}
+			if !silent {
+				cdInfo.HiddenTrack = true
+				queue(track, wavPath)
+			} else if image {
+				parts = append(parts, wavPath)
+			} else if prepend {
+				lead = wavPath
+			} else {
+				os.Remove(wavPath)
+			}
+			continue
+		}
+
+		if verifier != nil {
+			verification, err := verifier.addTrack(track.Number, wavPath)
+			if err != nil {
+				readErr = fmt.Errorf("failed to checksum track %d: %w", track.Number, err)
+				cancel()
+				break
+			}
+			if verification != nil {
+				progress.trackVerified(*verification)
+			}
+		}
+
+		// Tracks are verified as the disc plays, then moved into place
+		if prepend {
+			trail := cdInfo.Pregap(n + 1)
+			next := ""
+			if trail > 0 {
+				next = filepath.Join(workDir, fmt.Sprintf("pregap%02d.wav", n+1))
+			}
+			if lead != "" || trail > 0 {
+				if err := regapTrack(wavPath, lead, trail, next); err != nil {
+					readErr = fmt.Errorf("failed to move the pregaps of track %d: %w", track.Number, err)
+					cancel()
+					break
+				}
+			}
+			if lead != "" {
+				os.Remove(lead)
+			}
+			lead = next
+		}
+
+		queue(track, wavPath)
+	}
+
+	// A failed encoder cancels the read, so its error is the root cause
+	if err := pool.wait(); err != nil {
+		return err
+	}
+	if readErr != nil {
+		return readErr
+	}
+	if image {
+		if err := e.encodeImages(ctx, cdInfo, targets, workDir, parts, progress); err != nil {
+			return err
+		}
+	} else if err := pool.file(cdInfo); err != nil {
+		return err
+	}
+
+	if verifier != nil {
+		verifier.finish()
+		if cdInfo.CTDB != nil {
+			r.sendProgress(ProgressInfo{
+				CurrentTrack: cdInfo.TrackCount,
+				TotalTracks:  cdInfo.TrackCount,
+				Status:       fmt.Sprintf("CTDB: %s", cdInfo.CTDB),
+				Progress:     99,
+			})
+		}
+	}
+
+	if r.config.CDRipping.AutoEject {
+		// The files are safe at this point, a stuck tray is not worth failing for
+		_ = r.ejectDisc(ctx, e.run)
+	}
+
+	return nil
+}
+
+// ExtractTrack reads one track into a WAV file with cdparanoia, calling
+// onProgress whenever the read position or error counts change. Track 0 is
+// the audio in track 1's pregap.
+func (e *paranoiaExtractor) ExtractTrack(ctx context.Context, cdInfo *CDInfo, track int, wavPath string, onProgress func(ParanoiaStatus)) error {
+	if track < 0 || track > cdInfo.TrackCount || len(cdInfo.Offsets) <= track {
+		return fmt.Errorf("track %d is not on the disc", track)
+	}
+
+	// cdparanoia counts sectors from the start of the program area, without
+	// the two second lead-in included in the offsets
+	firstSector, sectors := cdInfo.trackSectors(track)
+	if track > 0 {
+		return e.read(ctx, strconv.Itoa(track), firstSector, sectors, wavPath, onProgress)
+	}
+
+	// cdparanoia calls the pregap track 0 but has no end for it, so the
+	// span runs up to track 1 and the file is trimmed to the pregap
+	if sectors == 0 {
+		return fmt.Errorf("the disc has no audio before track 1")
+	}
+	span := fmt.Sprintf("0[%s]-0[%s]", paranoiaTime(0), paranoiaTime(sectors))
+	if err := e.read(ctx, span, firstSector, sectors, wavPath, onProgress); err != nil {
+		return err
+	}
+	return trimWAV(wavPath, sectors)
+}
+
+// ExtractRange reads sectors start up to end of a track into a WAV file.
+// The file may run on past end, by a sector at most.
+func (e *paranoiaExtractor) ExtractRange(ctx context.Context, cdInfo *CDInfo, track, start, end int, wavPath string) error {
+	if track < 0 || track > cdInfo.TrackCount || len(cdInfo.Offsets) <= track {
+		return fmt.Errorf("track %d is not on the disc", track)
+	}
+	trackStart, length := cdInfo.trackSectors(track)
+	if start < 0 || end > length || start >= end {
+		return fmt.Errorf("sectors %d-%d are not in track %d", start, end, track)
+	}
+
+	// Whether cdparanoia includes the sector a span ends at is not worth
+	// relying on, so the span ends one sector late unless that is past the
+	// track, where an open span reads to its end. Track 1 always follows
+	// the pregap.
+	span := fmt.Sprintf("%d[%s]-%d", track, paranoiaTime(start), track)
+	if end < length || track == 0 {
+		span += fmt.Sprintf("[%s]", paranoiaTime(end))
+	}
+	return e.read(ctx, span, trackStart+start, end-start, wavPath, func(ParanoiaStatus) {})
+}
+
+// paranoiaTime formats a sector count as a cdparanoia span time, m:ss.ff
+// with 75 sectors to the second
+func paranoiaTime(sectors int) string {
+	return fmt.Sprintf("%d:%02d.%02d", sectors/(75*60), sectors/75%60, sectors%75)
+}
+
+// read runs cdparanoia for a span of the disc that starts at firstSector
+// and is sectors long
+func (e *paranoiaExtractor) read(ctx context.Context, span string, firstSector, sectors int, wavPath string, onProgress func(ParanoiaStatus)) error {
+	status := ParanoiaStatus{TotalSectors: sectors}
+
+	args := []string{
+		"-d", e.r.config.Drives.CDDrive,
+		"-e", // Machine readable progress on stderr
+	}
+	// Shift the read so the samples line up with what was mastered
+	if offset := e.r.ReadOffset(); offset.Samples != 0 {
+		args = append(args, "-O", strconv.Itoa(offset.Samples))
+	}
+	args = append(args, "-w", span, wavPath)
+	cmd, err := e.run.command(ctx, "cdparanoia", args...)
+	if err != nil {
+		return err
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stderr pipe: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start cdparanoia: %w", err)
+	}
+
+	lastError := ""
+	lastReported := ParanoiaStatus{SectorsRead: -1}
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		matches := paranoiaProgressPattern.FindStringSubmatch(line)
+		if matches == nil {
+			lower := strings.ToLower(line)
+			if strings.Contains(lower, "error") || strings.Contains(lower, "unable") {
+				lastError = strings.TrimSpace(line)
+			}
+			continue
+		}
+
+		switch matches[2] {
+		case "jitter", "correction", "scratch repair", "drift", "overlap", "dropped", "duped":
+			status.Corrections++
+		case "skip":
+			status.Skips++
+		case "scratch", "transport error", "cache error":
+			status.ReadErrors++
+		}
+
+		if position, err := strconv.ParseInt(matches[3], 10, 64); err == nil {
+			read := int(position/paranoiaWordsPerSector) - firstSector
+			read = max(0, min(read, status.TotalSectors))
+			// Positions jump back while paranoia re-reads; report the furthest point
+			status.SectorsRead = max(status.SectorsRead, read)
+		}
+
+		// cdparanoia prints many lines per sector; only report visible changes
+		if status.Percent() != lastReported.Percent() || status.Skips != lastReported.Skips ||
+			status.ReadErrors != lastReported.ReadErrors || lastReported.SectorsRead < 0 {
+			onProgress(status)
+			lastReported = status
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if lastError != "" {
+			return fmt.Errorf("cdparanoia failed: %w: %s", err, lastError)
+		}
+		return fmt.Errorf("cdparanoia failed: %w", err)
+	}
+
+	return nil
+}
+
+// workDir creates an empty directory for the disc's intermediate files
+func (r *CDRipper) workDir(cdInfo *CDInfo) (string, error) {
+	name := cdInfo.CDDBDiscID
+	if name == "" {
+		name = "unknown"
+	}
+
+	dir := filepath.Join(r.config.Paths.Work, name)
+	// A previous rip of the disc may have been interrupted
+	if err := os.RemoveAll(dir); err != nil {
+		return "", fmt.Errorf("failed to clear work directory: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create work directory: %w", err)
+	}
+	return dir, nil
+}
@@ -0,0 +1,93 @@
+package ripper
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Bparsons0904/ripper/internal/config"
+)
+
+// newParanoiaTestExtractor returns an extractor that runs the cdparanoia
+// stub in testdata, which replays recorded progress output
+func newParanoiaTestExtractor(t *testing.T) *paranoiaExtractor {
+	t.Helper()
+	stub, err := filepath.Abs(filepath.Join("testdata", "cdparanoia"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.Drives.CDDrive = "/dev/null"
+	cfg.Tools.CDParanoiaPath = stub
+	r := NewCDRipper(cfg)
+	return &paranoiaExtractor{r: r, run: &nativeBackend{r: r}}
+}
+
+func TestParanoiaExtractTrackProgress(t *testing.T) {
+	e := newParanoiaTestExtractor(t)
+	// Track 2 covers sectors 50-149 once the lead-in is taken off
+	cdInfo := &CDInfo{TrackCount: 2, Offsets: []int{150, 200, 300}}
+	wavPath := filepath.Join(t.TempDir(), "track02.wav")
+
+	var reports []ParanoiaStatus
+	err := e.ExtractTrack(context.Background(), cdInfo, 2, wavPath, func(status ParanoiaStatus) {
+		reports = append(reports, status)
+	})
+	if err != nil {
+		t.Fatalf("ExtractTrack() error = %v", err)
+	}
+	if _, err := os.Stat(wavPath); err != nil {
+		t.Errorf("WAV file not written: %v", err)
+	}
+
+	// Re-reads behind the furthest position and reads past the end of the
+	// track do not move progress; corrections alone are not reported
+	want := []ParanoiaStatus{
+		{SectorsRead: 0, TotalSectors: 100},
+		{SectorsRead: 25, TotalSectors: 100},
+		{SectorsRead: 50, TotalSectors: 100, Corrections: 1},
+		{SectorsRead: 50, TotalSectors: 100, Corrections: 1, Skips: 1},
+		{SectorsRead: 50, TotalSectors: 100, Corrections: 1, Skips: 1, ReadErrors: 1},
+		{SectorsRead: 100, TotalSectors: 100, Corrections: 1, Skips: 1, ReadErrors: 1},
+	}
+	if !reflect.DeepEqual(reports, want) {
+		t.Errorf("progress reports:\n got %+v\nwant %+v", reports, want)
+	}
+}
+
+func TestParanoiaExtractTrackFailure(t *testing.T) {
+	e := newParanoiaTestExtractor(t)
+	// Nothing was recorded for track 1, so the stub fails like an empty drive
+	cdInfo := &CDInfo{TrackCount: 2, Offsets: []int{150, 200, 300}}
+
+	err := e.ExtractTrack(context.Background(), cdInfo, 1, filepath.Join(t.TempDir(), "track01.wav"), func(ParanoiaStatus) {})
+	if err == nil || !strings.Contains(err.Error(), "Unable to open disc") {
+		t.Errorf("ExtractTrack() error = %v, want the cdparanoia message", err)
+	}
+
+	err = e.ExtractTrack(context.Background(), cdInfo, 3, filepath.Join(t.TempDir(), "track03.wav"), func(ParanoiaStatus) {})
+	if err == nil || !strings.Contains(err.Error(), "not on the disc") {
+		t.Errorf("ExtractTrack(3) error = %v, want a missing track error", err)
+	}
+}
+
+func TestParanoiaStatusPercent(t *testing.T) {
+	tests := []struct {
+		status ParanoiaStatus
+		want   int
+	}{
+		{ParanoiaStatus{SectorsRead: 0, TotalSectors: 0}, 0},
+		{ParanoiaStatus{SectorsRead: 0, TotalSectors: 300}, 0},
+		{ParanoiaStatus{SectorsRead: 100, TotalSectors: 300}, 33},
+		{ParanoiaStatus{SectorsRead: 299, TotalSectors: 300}, 99},
+		{ParanoiaStatus{SectorsRead: 300, TotalSectors: 300}, 100},
+	}
+	for _, tt := range tests {
+		if got := tt.status.Percent(); got != tt.want {
+			t.Errorf("%+v.Percent() = %d, want %d", tt.status, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package ripper
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// encoderWorkers returns how many tracks are encoded at once
+func (r *CDRipper) encoderWorkers() int {
+	if workers := r.config.CDRipping.EncoderWorkers; workers > 0 {
+		return workers
+	}
+	return runtime.NumCPU()
+}
+
+// combinedProgress turns the read and encode stages into overall progress.
+// 100 is reserved for completion.
+func combinedProgress(readProgress, encodeProgress int) int {
+	return min((readProgress+encodeProgress)/2, 99)
+}
+
+// encodeJob is an extracted track waiting to be encoded into one target.
+// The targets of a track share its WAV, which is removed by the last of them.
+type encodeJob struct {
+	track     TrackInfo
+	wavPath   string
+	target    OutputTarget
+	remaining *atomic.Int32
+}
+
+// encoderPool encodes extracted tracks on a fixed number of workers so the
+// drive keeps reading while earlier tracks are encoded. Tracks are encoded
+// into the stage directory and only filed once the whole rip succeeds.
+type encoderPool struct {
+	jobs     chan encodeJob
+	wg       sync.WaitGroup
+	cancel   context.CancelFunc
+	targets  []OutputTarget
+	stageDir string
+
+	mu  sync.Mutex
+	err error
+}
+
+// startEncoders starts the encoder workers. cancel is called when a track
+// fails to encode so extraction stops as well.
+func (r *CDRipper) startEncoders(ctx context.Context, cancel context.CancelFunc, run toolRunner, cdInfo *CDInfo, targets []OutputTarget, stageDir string, progress *ripProgress) *encoderPool {
+	pool := &encoderPool{
+		// Room for every track and a hidden one, so queueing never holds up
+		// the drive
+		jobs:     make(chan encodeJob, (cdInfo.TrackCount+1)*len(targets)),
+		cancel:   cancel,
+		targets:  targets,
+		stageDir: stageDir,
+	}
+
+	for i := 0; i < r.encoderWorkers(); i++ {
+		pool.wg.Add(1)
+		go func() {
+			defer pool.wg.Done()
+			for job := range pool.jobs {
+				if ctx.Err() != nil {
+					continue // Drain the queue after a failure
+				}
+
+				staged := stagedTrackPath(stageDir, job.target, job.track.Number)
+				if err := encodeTrack(ctx, run, job.target, job.wavPath, staged, cdInfo, job.track); err != nil {
+					// Encoders killed by a cancellation are not failures of their own
+					if ctx.Err() == nil {
+						pool.fail(fmt.Errorf("failed to encode track %d to %s: %w", job.track.Number, job.target.Format, err))
+					}
+					continue
+				}
+
+				trackDone := job.remaining.Add(-1) == 0
+				if trackDone {
+					// Free work space as soon as every target of a track is done
+					os.Remove(job.wavPath)
+				}
+				progress.targetEncoded(trackDone)
+			}
+		}()
+	}
+
+	return pool
+}
+
+// stagedTrackPath returns where a track is encoded for a target before it
+// is filed, one directory per format
+func stagedTrackPath(stageDir string, target OutputTarget, track int) string {
+	return filepath.Join(stageDir, target.Format, fmt.Sprintf("%02d.%s", track, fileExtension(target.Format)))
+}
+
+// add queues an extracted track for every target
+func (p *encoderPool) add(track TrackInfo, wavPath string) {
+	remaining := &atomic.Int32{}
+	remaining.Store(int32(len(p.targets)))
+	for _, target := range p.targets {
+		p.jobs <- encodeJob{track: track, wavPath: wavPath, target: target, remaining: remaining}
+	}
+}
+
+// Err returns the first encoding failure so far
+func (p *encoderPool) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// fail records the first failure and stops the rip
+func (p *encoderPool) fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err == nil {
+		p.err = err
+		p.cancel()
+	}
+}
+
+// wait closes the queue, waits for the queued tracks and returns the first failure
+func (p *encoderPool) wait() error {
+	close(p.jobs)
+	p.wg.Wait()
+	return p.Err()
+}
+
+// file moves the staged tracks into the library under each target's
+// naming templates
+func (p *encoderPool) file(cdInfo *CDInfo) error {
+	for _, target := range p.targets {
+		for n := cdInfo.FirstTrack(); n <= cdInfo.TrackCount; n++ {
+			track := cdInfo.Track(n)
+			path, err := target.trackPath(cdInfo, track)
+			if err != nil {
+				return err
+			}
+			if err := moveFile(stagedTrackPath(p.stageDir, target, n), path); err != nil {
+				return fmt.Errorf("failed to file track %d: %w", n, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ripProgress combines the read and encode stages of a rip into progress
+// updates. It is shared by the extraction loop and the encoder workers.
+type ripProgress struct {
+	r      *CDRipper
+	cdInfo *CDInfo
+
+	mu            sync.Mutex
+	totalSectors  int
+	sectorsDone   int // Sectors of tracks read completely
+	reading       TrackInfo
+	status        ParanoiaStatus
+	doneReading   bool
+	targets       int // Formats produced per track
+	encodes       int // Track and target pairs encoded
+	encoded       int // Tracks encoded into every target
+	verified      *TrackVerification
+	passes        int // Full reads of each track
+	pass          int // Full reads of the current track done
+	rereadSectors int // Sectors of the current track being read again
+	secured       *TrackReadReport
+	image         bool // The disc is encoded as one image once read
+}
+
+// newRipProgress tracks a rip of cdInfo into targets formats
+func newRipProgress(r *CDRipper, cdInfo *CDInfo, targets int) *ripProgress {
+	return &ripProgress{
+		r:            r,
+		cdInfo:       cdInfo,
+		totalSectors: cdInfo.Offsets[cdInfo.TrackCount] - cdInfo.Offsets[0] + cdInfo.Pregap(1),
+		targets:      max(targets, 1),
+		passes:       1,
+	}
+}
+
+// trackReading reports the read position within a track
+func (p *ripProgress) trackReading(track TrackInfo, status ParanoiaStatus) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.reading = track
+	p.status = status
+	p.send()
+}
+
+// passRead marks one full read of the current track, sectors long, as done
+// when the track is read more than once
+func (p *ripProgress) passRead(sectors int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sectorsDone += sectors
+	p.pass++
+	p.status = ParanoiaStatus{}
+	p.send()
+}
+
+// rereading reports sectors of a track being read again
+func (p *ripProgress) rereading(track TrackInfo, sectors int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.reading = track
+	p.rereadSectors = sectors
+	p.send()
+}
+
+// trackRead marks the current track, sectors long, as read completely
+func (p *ripProgress) trackRead(sectors int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sectorsDone += sectors
+	p.pass = 0
+	p.rereadSectors = 0
+	p.status = ParanoiaStatus{}
+	if p.reading.Number == p.cdInfo.TrackCount {
+		p.doneReading = true
+	}
+	p.send()
+}
+
+// trackVerified reports the AccurateRip verification of the track just read
+func (p *ripProgress) trackVerified(verification TrackVerification) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.verified = &verification
+	p.send()
+	p.verified = nil
+}
+
+// trackSecured reports the secure mode read of the track just read
+func (p *ripProgress) trackSecured(report TrackReadReport) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.secured = &report
+	p.send()
+	p.secured = nil
+}
+
+// targetEncoded counts a finished encode; trackDone marks the last target
+// of its track
+func (p *ripProgress) targetEncoded(trackDone bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.encodes++
+	if trackDone {
+		p.encoded++
+	}
+	p.send()
+}
+
+// send reports the current state. The caller holds p.mu.
+func (p *ripProgress) send() {
+	total := p.cdInfo.TrackCount
+	readProgress := 0
+	if p.totalSectors > 0 {
+		readProgress = (p.sectorsDone + p.status.SectorsRead) * 100 / (p.totalSectors * p.passes)
+	}
+	// A hidden track is encoded as well, once the rip has found it
+	tracks := total + 1 - p.cdInfo.FirstTrack()
+	if p.image {
+		tracks = 1
+	}
+	encodeProgress := p.encodes * 100 / (tracks * p.targets)
+
+	status := fmt.Sprintf("Reading track %d of %d, %d encoded...", p.reading.Number, total, p.encoded)
+	if problems := p.status.Skips + p.status.ReadErrors; problems > 0 {
+		status = fmt.Sprintf("Reading track %d of %d (read problems: %d), %d encoded...", p.reading.Number, total, problems, p.encoded)
+	}
+	if p.pass > 0 {
+		status = fmt.Sprintf("Reading track %d of %d again to compare, %d encoded...", p.reading.Number, total, p.encoded)
+	}
+	if p.rereadSectors > 0 {
+		status = fmt.Sprintf("Re-reading %d sectors of track %d the reads disagree on...", p.rereadSectors, p.reading.Number)
+	}
+	if p.doneReading {
+		status = fmt.Sprintf("Encoding, %d of %d tracks done...", p.encoded, tracks)
+		if p.image {
+			status = fmt.Sprintf("Encoding the disc image, %d of %d formats done...", p.encodes, p.targets)
+		}
+	}
+	if p.secured != nil && !p.secured.Secure() {
+		status = p.secured.Warning()
+	}
+	if p.verified != nil {
+		status = fmt.Sprintf("Track %d %s", p.verified.Track, p.verified)
+	}
+
+	p.r.sendProgress(ProgressInfo{
+		CurrentTrack:   p.reading.Number,
+		TotalTracks:    total,
+		TrackName:      p.reading.Title,
+		TrackProgress:  p.status.Percent(),
+		ReadProgress:   readProgress,
+		EncodeProgress: encodeProgress,
+		TracksEncoded:  p.encoded,
+		Status:         status,
+		Progress:       combinedProgress(readProgress, encodeProgress),
+		AccurateRip:    p.verified,
+		SecureRead:     p.secured,
+	})
+}
@@ -0,0 +1,257 @@
+package ripper
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sectorBytes is the audio in one CD sector: 588 16-bit stereo samples
+const sectorBytes = 2352
+
+// SectorRange is a run of sectors within a track, from Start up to but not
+// including End
+type SectorRange struct {
+	Start int
+	End   int
+}
+
+// String gives the range as track times, e.g. "1:02.15-1:02.20"
+func (s SectorRange) String() string {
+	return fmt.Sprintf("%s-%s", paranoiaTime(s.Start), paranoiaTime(s.End))
+}
+
+// TrackReadReport is how a track read in secure mode
+type TrackReadReport struct {
+	Track      int
+	Reads      int           // Full reads of the track
+	Rereads    int           // Reads of ranges the full reads disagreed on
+	Mismatched int           // Sectors the full reads disagreed on
+	Suspicious []SectorRange // Sectors no two reads agreed on
+}
+
+// Secure reports whether every sector was read the same way twice
+func (r TrackReadReport) Secure() bool {
+	return len(r.Suspicious) == 0
+}
+
+// Warning describes the suspicious sectors, empty for a secure read
+func (r TrackReadReport) Warning() string {
+	if r.Secure() {
+		return ""
+	}
+	sectors := 0
+	ranges := make([]string, len(r.Suspicious))
+	for i, s := range r.Suspicious {
+		sectors += s.End - s.Start
+		ranges[i] = s.String()
+	}
+	return fmt.Sprintf("Track %d: %d suspicious sectors at %s", r.Track, sectors, strings.Join(ranges, ", "))
+}
+
+// sectorVote is a value read for a sector and the reads that agree on it
+type sectorVote struct {
+	sum   uint32
+	votes int
+	path  string // A WAV file holding the value
+	at    int64  // Where the sector's audio starts in it
+}
+
+// readSecurely reads a track into wavPath twice and compares the reads
+// sector by sector. Ranges they disagree on are re-read until two reads
+// agree on every sector or the re-read limit is reached; the agreed values
+// are written into wavPath. Sectors still in doubt are the most common
+// value read and are reported as suspicious.
+func (e *paranoiaExtractor) readSecurely(ctx context.Context, cdInfo *CDInfo, track TrackInfo, wavPath string, progress *ripProgress) (TrackReadReport, error) {
+	report := TrackReadReport{Track: track.Number, Reads: 2}
+	_, sectors := cdInfo.trackSectors(track.Number)
+	onProgress := func(status ParanoiaStatus) {
+		progress.trackReading(track, status)
+	}
+
+	if err := e.ExtractTrack(ctx, cdInfo, track.Number, wavPath, onProgress); err != nil {
+		return report, err
+	}
+	progress.passRead(sectors)
+
+	checkPath := strings.TrimSuffix(wavPath, ".wav") + ".check.wav"
+	defer os.Remove(checkPath)
+	if err := e.ExtractTrack(ctx, cdInfo, track.Number, checkPath, onProgress); err != nil {
+		return report, err
+	}
+
+	first, firstAt, err := wavSectors(wavPath)
+	if err != nil {
+		return report, err
+	}
+	second, secondAt, err := wavSectors(checkPath)
+	if err != nil {
+		return report, err
+	}
+	if len(first) != len(second) {
+		return report, fmt.Errorf("reads of track %d differ in length: %d and %d sectors", track.Number, len(first), len(second))
+	}
+
+	var mismatched []SectorRange
+	for i := range first {
+		if first[i] == second[i] {
+			continue
+		}
+		report.Mismatched++
+		if n := len(mismatched); n > 0 && mismatched[n-1].End == i {
+			mismatched[n-1].End++
+		} else {
+			mismatched = append(mismatched, SectorRange{i, i + 1})
+		}
+	}
+
+	for _, bad := range mismatched {
+		votes := make([][]sectorVote, bad.End-bad.Start)
+		for i := range votes {
+			sector := bad.Start + i
+			votes[i] = []sectorVote{
+				{sum: first[sector], votes: 1, path: wavPath, at: firstAt + int64(sector)*sectorBytes},
+				{sum: second[sector], votes: 1, path: checkPath, at: secondAt + int64(sector)*sectorBytes},
+			}
+		}
+
+		for attempt := 1; attempt <= e.r.config.CDRipping.Secure.MaxRereads && !agreed(votes); attempt++ {
+			progress.rereading(track, bad.End-bad.Start)
+			rereadPath := fmt.Sprintf("%s.reread%d-%d.wav", strings.TrimSuffix(wavPath, ".wav"), bad.Start, attempt)
+			defer os.Remove(rereadPath)
+			if err := e.ExtractRange(ctx, cdInfo, track.Number, bad.Start, bad.End, rereadPath); err != nil {
+				return report, err
+			}
+			report.Rereads++
+
+			sums, at, err := wavSectors(rereadPath)
+			if err != nil {
+				return report, err
+			}
+			if len(sums) < len(votes) {
+				return report, fmt.Errorf("re-read of track %d sectors %s is short", track.Number, bad)
+			}
+			for i := range votes {
+				votes[i] = vote(votes[i], sectorVote{sum: sums[i], votes: 1, path: rereadPath, at: at + int64(i)*sectorBytes})
+			}
+		}
+
+		for i, candidates := range votes {
+			best := candidates[0]
+			for _, candidate := range candidates[1:] {
+				if candidate.votes > best.votes {
+					best = candidate
+				}
+			}
+			sector := bad.Start + i
+			if best.votes < 2 {
+				if n := len(report.Suspicious); n > 0 && report.Suspicious[n-1].End == sector {
+					report.Suspicious[n-1].End++
+				} else {
+					report.Suspicious = append(report.Suspicious, SectorRange{sector, sector + 1})
+				}
+			}
+			if best.path != wavPath {
+				if err := copySector(best.path, best.at, wavPath, firstAt+int64(sector)*sectorBytes); err != nil {
+					return report, err
+				}
+			}
+		}
+	}
+	return report, nil
+}
+
+// agreed reports whether two reads agree on every sector
+func agreed(votes [][]sectorVote) bool {
+	for _, candidates := range votes {
+		settled := false
+		for _, candidate := range candidates {
+			settled = settled || candidate.votes >= 2
+		}
+		if !settled {
+			return false
+		}
+	}
+	return true
+}
+
+// vote counts a read of a sector towards the value it agrees with
+func vote(candidates []sectorVote, read sectorVote) []sectorVote {
+	for i := range candidates {
+		if candidates[i].sum == read.sum {
+			candidates[i].votes++
+			return candidates
+		}
+	}
+	return append(candidates, read)
+}
+
+// wavSectors returns the CRC32 of each sector of a WAV file's audio, and
+// where in the file the audio starts
+func wavSectors(path string) ([]uint32, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	reader := &countingReader{r: bufio.NewReader(f)}
+	size, err := wavDataSize(reader)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	start := reader.n
+
+	sums := make([]uint32, 0, (size+sectorBytes-1)/sectorBytes)
+	buf := make([]byte, sectorBytes)
+	for remaining := int(size); remaining > 0; remaining -= sectorBytes {
+		n := min(remaining, sectorBytes)
+		if _, err := io.ReadFull(reader, buf[:n]); err != nil {
+			return nil, 0, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+		}
+		sums = append(sums, crc32.ChecksumIEEE(buf[:n]))
+	}
+	return sums, start, nil
+}
+
+// copySector copies a sector of audio between WAV files
+func copySector(from string, fromAt int64, to string, toAt int64) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	buf := make([]byte, sectorBytes)
+	n, err := src.ReadAt(buf, fromAt)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed to read re-read sector: %w", err)
+	}
+
+	dst, err := os.OpenFile(to, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := dst.WriteAt(buf[:n], toAt); err != nil {
+		dst.Close()
+		return fmt.Errorf("failed to write re-read sector: %w", err)
+	}
+	return dst.Close()
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
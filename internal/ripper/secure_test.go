@@ -0,0 +1,163 @@
+package ripper
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Bparsons0904/ripper/internal/config"
+)
+
+// sectorAudio returns CD audio with a sector per letter, each sector filled
+// with its letter, so reads are written and compared as strings
+func sectorAudio(sectors string) []byte {
+	var audio []byte
+	for _, c := range []byte(sectors) {
+		audio = append(audio, bytes.Repeat([]byte{c}, sectorBytes)...)
+	}
+	return audio
+}
+
+// scriptedReads stands in for cdparanoia. Each run writes the next read as
+// a WAV file to the output path and records the span it was asked for.
+type scriptedReads struct {
+	t     *testing.T
+	reads []string
+	spans []string
+}
+
+func (s *scriptedReads) command(ctx context.Context, tool string, args ...string) (*exec.Cmd, error) {
+	if len(s.reads) == 0 {
+		return nil, fmt.Errorf("read of %s not scripted", args[len(args)-2])
+	}
+	s.spans = append(s.spans, args[len(args)-2])
+	writeTestWAV(s.t, args[len(args)-1], sectorAudio(s.reads[0]))
+	s.reads = s.reads[1:]
+	return exec.CommandContext(ctx, "true"), nil
+}
+
+func TestReadSecurely(t *testing.T) {
+	tests := []struct {
+		name       string
+		reads      []string // Full reads of the six sector track, then re-reads
+		maxRereads int
+		want       string // The track written
+		wantReport TrackReadReport
+		wantSpans  []string // Ranges re-read
+		wantErr    string
+	}{
+		{
+			name:       "reads agree",
+			reads:      []string{"ABCDEF", "ABCDEF"},
+			maxRereads: 3,
+			want:       "ABCDEF",
+			wantReport: TrackReadReport{Track: 1, Reads: 2},
+		},
+		{
+			name:       "mismatches merged into ranges",
+			reads:      []string{"ABCDEF", "AXYDZF", "BC", "Z"},
+			maxRereads: 3,
+			want:       "ABCDZF",
+			wantReport: TrackReadReport{Track: 1, Reads: 2, Rereads: 2, Mismatched: 3},
+			wantSpans:  []string{"1[0:00.01]-1[0:00.03]", "1[0:00.04]-1[0:00.05]"},
+		},
+		{
+			name:       "re-read until every sector agrees",
+			reads:      []string{"ABCDEF", "AXYDEF", "BZ", "QY"},
+			maxRereads: 3,
+			want:       "ABYDEF",
+			wantReport: TrackReadReport{Track: 1, Reads: 2, Rereads: 2, Mismatched: 2},
+			wantSpans:  []string{"1[0:00.01]-1[0:00.03]", "1[0:00.01]-1[0:00.03]"},
+		},
+		{
+			name:       "end of track",
+			reads:      []string{"ABCDEF", "ABCDEX", "X"},
+			maxRereads: 3,
+			want:       "ABCDEX",
+			wantReport: TrackReadReport{Track: 1, Reads: 2, Rereads: 1, Mismatched: 1},
+			wantSpans:  []string{"1[0:00.05]-1"},
+		},
+		{
+			name:       "re-read limit",
+			reads:      []string{"ABCDEF", "ABXDEF", "Y", "Z"},
+			maxRereads: 2,
+			want:       "ABCDEF",
+			wantReport: TrackReadReport{Track: 1, Reads: 2, Rereads: 2, Mismatched: 1, Suspicious: []SectorRange{{2, 3}}},
+			wantSpans:  []string{"1[0:00.02]-1[0:00.03]", "1[0:00.02]-1[0:00.03]"},
+		},
+		{
+			name:       "short re-read",
+			reads:      []string{"ABCDEF", "AXYDEF", "B"},
+			maxRereads: 3,
+			wantErr:    "re-read of track 1 sectors 0:00.01-0:00.03 is short",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.Drives.CDDrive = "/dev/null"
+			cfg.CDRipping.Secure.MaxRereads = tt.maxRereads
+			r := NewCDRipper(cfg)
+			run := &scriptedReads{t: t, reads: tt.reads}
+			e := &paranoiaExtractor{r: r, run: run}
+
+			cdInfo := &CDInfo{TrackCount: 1, Offsets: []int{150, 156}}
+			wavPath := filepath.Join(t.TempDir(), "track01.wav")
+			report, err := e.readSecurely(context.Background(), cdInfo, cdInfo.Track(1), wavPath, newRipProgress(r, cdInfo, 1))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("readSecurely() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readSecurely() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(report, tt.wantReport) {
+				t.Errorf("report = %+v, want %+v", report, tt.wantReport)
+			}
+			// The two full reads come first
+			if spans := run.spans[2:]; !slices.Equal(spans, tt.wantSpans) {
+				t.Errorf("re-read spans = %q, want %q", spans, tt.wantSpans)
+			}
+			if len(run.reads) > 0 {
+				t.Errorf("%d scripted reads left over", len(run.reads))
+			}
+
+			data, err := os.ReadFile(wavPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := data[44:]; !bytes.Equal(got, sectorAudio(tt.want)) {
+				t.Errorf("track written differs from %q", tt.want)
+			}
+
+			// Only the track is left in the work directory
+			if files, _ := os.ReadDir(filepath.Dir(wavPath)); len(files) != 1 {
+				t.Errorf("work directory holds %d files, want the track alone", len(files))
+			}
+		})
+	}
+}
+
+func TestTrackReadReportWarning(t *testing.T) {
+	report := TrackReadReport{Track: 4, Suspicious: []SectorRange{{2, 3}, {4575, 4580}}}
+	if report.Secure() {
+		t.Error("Secure() = true with suspicious sectors")
+	}
+	want := "Track 4: 6 suspicious sectors at 0:00.02-0:00.03, 1:01.00-1:01.05"
+	if got := report.Warning(); got != want {
+		t.Errorf("Warning() = %q, want %q", got, want)
+	}
+	if got := (TrackReadReport{Track: 1}).Warning(); got != "" {
+		t.Errorf("Warning() of a secure read = %q", got)
+	}
+}
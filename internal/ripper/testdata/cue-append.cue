@@ -0,0 +1,24 @@
+REM GENRE "Rock"
+REM DATE 1999
+REM DISCID A70DE90C
+PERFORMER "Band"
+TITLE "The 'Quoted' Album"
+FILE "01_One.flac" WAVE
+  TRACK 01 AUDIO
+    TITLE "One"
+    PERFORMER "Band"
+    ISRC USABC9900001
+    PREGAP 00:32:00
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Two"
+    PERFORMER "Guest"
+    INDEX 00 03:18:00
+FILE "02_Two.flac" WAVE
+    INDEX 01 00:00:00
+  TRACK 03 AUDIO
+    TITLE "Three"
+    PERFORMER "Band"
+    INDEX 00 03:19:00
+FILE "03_Three.flac" WAVE
+    INDEX 01 00:00:00
@@ -0,0 +1,23 @@
+REM GENRE "Rock"
+REM DATE 1999
+REM DISCID A70DE90C
+CATALOG 0724354526329
+PERFORMER "Band"
+TITLE "The 'Quoted' Album"
+FILE "Band - Album.flac" WAVE
+  TRACK 01 AUDIO
+    TITLE "One"
+    PERFORMER "Band"
+    ISRC USABC9900001
+    INDEX 00 00:00:00
+    INDEX 01 00:32:00
+  TRACK 02 AUDIO
+    TITLE "Two"
+    PERFORMER "Guest"
+    INDEX 00 03:50:00
+    INDEX 01 03:52:00
+  TRACK 03 AUDIO
+    TITLE "Three"
+    PERFORMER "Band"
+    INDEX 00 07:11:00
+    INDEX 01 07:12:00
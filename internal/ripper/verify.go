@@ -0,0 +1,134 @@
+package ripper
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// ripVerifier checksums each track as cdparanoia reads it and compares the
+// checksums with AccurateRip and the CUETools Database. Results are kept in
+// the disc's CDInfo.
+type ripVerifier struct {
+	cdInfo      *CDInfo
+	accurateRip []AccurateRipEntry
+	ctdb        []CTDBEntry
+	ctdbDisc    hash.Hash32 // CRC32 of the disc so far
+}
+
+// newRipVerifier looks the disc up in the enabled databases before it is
+// read, so each track can be verified as soon as it is. It returns nil when
+// verification is off.
+func (r *CDRipper) newRipVerifier(ctx context.Context, cdInfo *CDInfo) *ripVerifier {
+	cfg := r.config.CDRipping
+	if !cfg.AccurateRip.Enabled && !cfg.CTDB.Enabled {
+		return nil
+	}
+
+	v := &ripVerifier{cdInfo: cdInfo}
+	if cfg.AccurateRip.Enabled {
+		cdInfo.AccurateRip, v.accurateRip = r.lookupAccurateRip(ctx, cdInfo)
+		if cdInfo.AccurateRip.Error != "" {
+			r.sendProgress(ProgressInfo{
+				TotalTracks: cdInfo.TrackCount,
+				Status:      fmt.Sprintf("AccurateRip lookup failed, checksums are kept unverified: %s", cdInfo.AccurateRip.Error),
+			})
+		}
+	}
+	if cfg.CTDB.Enabled {
+		cdInfo.CTDB, v.ctdb = r.lookupCTDB(ctx, cdInfo)
+		v.ctdbDisc = crc32.NewIEEE()
+		if cdInfo.CTDB.Error != "" {
+			r.sendProgress(ProgressInfo{
+				TotalTracks: cdInfo.TrackCount,
+				Status:      fmt.Sprintf("CTDB lookup failed, checksums are kept unverified: %s", cdInfo.CTDB.Error),
+			})
+		}
+	}
+	return v
+}
+
+// addTrack checksums a track's WAV file, reading it once for every
+// database. Tracks must be added in disc order. It returns the track's
+// AccurateRip verification, nil when AccurateRip is off.
+func (v *ripVerifier) addTrack(track int, wavPath string) (*TrackVerification, error) {
+	f, err := os.Open(wavPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	size, err := wavDataSize(reader)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", wavPath, err)
+	}
+	first, last := track == 1, track == v.cdInfo.TrackCount
+
+	var writers []io.Writer
+	var arSum *accurateRipSum
+	if v.cdInfo.AccurateRip != nil {
+		arSum = newAccurateRipSum(int(size/4), first, last)
+		writers = append(writers, arSum)
+	}
+	var trackCRC hash.Hash32
+	if v.cdInfo.CTDB != nil {
+		trackCRC = crc32.NewIEEE()
+		audio := &rangeWriter{w: io.MultiWriter(v.ctdbDisc, trackCRC), end: int64(size)}
+		if first {
+			audio.start = ctdbSkipBytes
+		}
+		if last {
+			audio.end -= ctdbSkipBytes
+		}
+		writers = append(writers, audio)
+	}
+
+	if _, err := io.CopyN(io.MultiWriter(writers...), reader, int64(size)); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", wavPath, err)
+	}
+
+	if trackCRC != nil {
+		v.cdInfo.CTDB.TrackCRCs = append(v.cdInfo.CTDB.TrackCRCs, trackCRC.Sum32())
+	}
+	if arSum == nil {
+		return nil, nil
+	}
+	verification := verifyTrack(track, arSum.v1, arSum.v2, v.accurateRip)
+	v.cdInfo.AccurateRip.Tracks = append(v.cdInfo.AccurateRip.Tracks, verification)
+	return &verification, nil
+}
+
+// finish compares the whole disc with the CUETools Database once every
+// track has been added
+func (v *ripVerifier) finish() {
+	if v.cdInfo.CTDB == nil {
+		return
+	}
+	v.cdInfo.CTDB.CRC = v.ctdbDisc.Sum32()
+	v.cdInfo.CTDB.compare(v.ctdb, v.cdInfo.Offsets)
+}
+
+// rangeWriter passes on only the bytes between start and end of what is
+// written to it
+type rangeWriter struct {
+	w          io.Writer
+	start, end int64
+	written    int64
+}
+
+func (rw *rangeWriter) Write(p []byte) (int, error) {
+	lo := max(rw.start-rw.written, 0)
+	hi := min(rw.end-rw.written, int64(len(p)))
+	rw.written += int64(len(p))
+	if lo < hi {
+		if _, err := rw.w.Write(p[lo:hi]); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,174 @@
+// Package sanitize cleans names for use as file and directory names. A
+// policy picks the rules of the filesystem the library lives on, so music
+// and movie outputs are named the same way.
+package sanitize
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Profiles a policy can use
+const (
+	// POSIX only removes what a POSIX filesystem cannot store: slashes and
+	// control characters
+	POSIX = "posix"
+	// Windows also avoids the characters and names Windows and SMB shares
+	// reject, and the trailing dots and spaces they strip
+	Windows = "windows"
+	// ASCII applies the Windows rules and transliterates everything else to
+	// plain ASCII, writing characters it cannot transliterate as U+XXXX
+	ASCII = "ascii"
+)
+
+// Profiles are the available profiles, least restrictive first
+var Profiles = []string{POSIX, Windows, ASCII}
+
+// MaxNameLength is the most bytes a single file or directory name can have
+// on common filesystems
+const MaxNameLength = 255
+
+// Policy is a set of sanitisation rules
+type Policy struct {
+	Profile       string
+	ReplaceSpaces bool // Turn spaces into underscores
+	MaxPathLength int  // Longest full path in bytes, 0 for no limit
+}
+
+// windowsReplacements maps the characters Windows rejects to stand-ins,
+// empty for characters that are dropped
+var windowsReplacements = map[rune]string{
+	'/':  "_",
+	'\\': "_",
+	':':  "-",
+	'|':  "-",
+	'"':  "'",
+	'<':  "",
+	'>':  "",
+	'?':  "",
+	'*':  "",
+}
+
+// windowsReserved are device names Windows will not use as a file name,
+// with or without an extension
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// Name cleans a value for use as one file or directory name. It never
+// contains a slash, is never "." or ".." and is never empty.
+func (p Policy) Name(value string) string {
+	return p.finish(p.Clean(value))
+}
+
+// Clean replaces or drops the characters of value the profile does not
+// allow, leaving the ends alone. It suits parts of a name, such as the
+// fields of a naming template.
+func (p Policy) Clean(value string) string {
+	var sb strings.Builder
+	for _, r := range value {
+		replacement, rejected := windowsReplacements[r]
+		switch {
+		case unicode.IsControl(r):
+		case r == '/':
+			sb.WriteRune('_')
+		case r == ' ' && p.ReplaceSpaces:
+			sb.WriteRune('_')
+		case rejected && p.Profile != POSIX:
+			sb.WriteString(replacement)
+		case r >= utf8.RuneSelf && p.Profile == ASCII:
+			sb.WriteString(transliterate(r))
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// finish applies the rules for the ends of a name: Windows' trailing dots
+// and spaces are dropped and reserved device names are prefixed with an
+// underscore. Leading dots are kept, as in ".hack", but "." and ".." name
+// directories that already exist and are replaced.
+func (p Policy) finish(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "." || name == ".." {
+		name = ""
+	}
+	if p.Profile != POSIX {
+		name = strings.TrimRight(name, ". ")
+		base, _, _ := strings.Cut(name, ".")
+		if windowsReserved[strings.ToUpper(base)] {
+			name = "_" + name
+		}
+	}
+	if name == "" {
+		return "_"
+	}
+	return name
+}
+
+// Fit joins dir, the names and ext into a path that respects the length
+// limits. Names longer than MaxNameLength are shortened, then the longest
+// names are cut down evenly until the path is short enough. It fails if even
+// one-character names do not fit.
+func (p Policy) Fit(dir string, names []string, ext string) (string, error) {
+	names = append([]string(nil), names...)
+	if ext != "" {
+		ext = "." + ext
+	}
+
+	// The extension belongs to the last name
+	limit := func(i int) int {
+		if i == len(names)-1 {
+			return MaxNameLength - len(ext)
+		}
+		return MaxNameLength
+	}
+	for i := range names {
+		names[i] = p.truncate(names[i], limit(i))
+	}
+
+	path := filepath.Join(dir, filepath.Join(names...)) + ext
+	for p.MaxPathLength > 0 && len(path) > p.MaxPathLength {
+		longest, runnerUp := 0, 0
+		for i := range names {
+			if len(names[i]) > len(names[longest]) {
+				longest = i
+			}
+		}
+		for i := range names {
+			if i != longest {
+				runnerUp = max(runnerUp, len(names[i]))
+			}
+		}
+		if len(names[longest]) <= 1 {
+			return "", fmt.Errorf("%s does not fit in %d bytes", path, p.MaxPathLength)
+		}
+		// Cut no further than just below the next longest name, so long
+		// names are shortened evenly
+		cut := min(len(path)-p.MaxPathLength, len(names[longest])-runnerUp+1)
+		names[longest] = p.truncate(names[longest], max(1, len(names[longest])-cut))
+		path = filepath.Join(dir, filepath.Join(names...)) + ext
+	}
+	return path, nil
+}
+
+// truncate shortens a name to at most n bytes without splitting a
+// character, reapplying the rules for the end of a name
+func (p Policy) truncate(name string, n int) string {
+	for len(name) > n {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+		if len(name) <= n {
+			name = p.finish(name)
+		}
+	}
+	return name
+}
@@ -0,0 +1,165 @@
+package sanitize
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestName(t *testing.T) {
+	tests := []struct {
+		profile string
+		value   string
+		want    string
+	}{
+		{POSIX, ".hack//SIGN", ".hack__SIGN"},
+		{Windows, ".hack//SIGN", ".hack__SIGN"},
+		{ASCII, ".hack", ".hack"},
+		{POSIX, "...", "..."},
+		{POSIX, ".", "_"},
+		{POSIX, "..", "_"},
+		{Windows, "..", "_"},
+		{Windows, "...", "_"},
+		{POSIX, "  ", "_"},
+		{POSIX, "Album. ", "Album."},
+		{Windows, "Album. ", "Album"},
+		{Windows, "What?: Part 1", "What- Part 1"},
+		{POSIX, "What?: Part 1", "What?: Part 1"},
+		{Windows, "con", "_con"},
+		{Windows, "Aux.flac", "_Aux.flac"},
+		{POSIX, "CON", "CON"},
+		{POSIX, "tab\there", "tabhere"},
+		{POSIX, "Björk – Jóga", "Björk – Jóga"},
+		{ASCII, "Björk – Jóga", "Bjork - Joga"},
+		{ASCII, "Ærøskøbing… Live", "AEroskobing... Live"},
+		{ASCII, "Cafe\u0301", "Cafe"},
+		{ASCII, "日本", "U+65E5U+672C"},
+		{ASCII, "中国", "U+4E2DU+56FD"},
+		{ASCII, "Sigur Rós – ( )", "Sigur Ros - ( )"},
+		{ASCII, "🎵", "U+1F3B5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.profile+" "+tt.value, func(t *testing.T) {
+			if got := (Policy{Profile: tt.profile}).Name(tt.value); got != tt.want {
+				t.Errorf("Name(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNameReplaceSpaces(t *testing.T) {
+	policy := Policy{Profile: Windows, ReplaceSpaces: true}
+	if got := policy.Name("The Band"); got != "The_Band" {
+		t.Errorf("Name() = %q, want The_Band", got)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		profile string
+		name    string
+		n       int
+		want    string
+	}{
+		{POSIX, "Album", 10, "Album"},
+		{POSIX, "Album Title", 5, "Album"},
+		{POSIX, "Björk", 3, "Bj"},
+		{POSIX, "Album... Part", 8, "Album..."},
+		{Windows, "Album... Part", 8, "Album"},
+		// Cutting can leave a reserved name, which is prefixed and cut again
+		{Windows, "Aux.long name", 5, "_Aux"},
+		{Windows, "Console", 3, "_Co"},
+		{POSIX, "Console", 3, "Con"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.profile+" "+tt.name, func(t *testing.T) {
+			got := (Policy{Profile: tt.profile}).truncate(tt.name, tt.n)
+			if got != tt.want {
+				t.Errorf("truncate(%q, %d) = %q, want %q", tt.name, tt.n, got, tt.want)
+			}
+			if len(got) > tt.n {
+				t.Errorf("truncate(%q, %d) is %d bytes", tt.name, tt.n, len(got))
+			}
+		})
+	}
+}
+
+func TestFit(t *testing.T) {
+	long := strings.Repeat("a", 300)
+	tests := []struct {
+		name    string
+		policy  Policy
+		dir     string
+		names   []string
+		ext     string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "no limit",
+			policy: Policy{Profile: POSIX},
+			dir:    "/music",
+			names:  []string{"Band", "Album", "01 Song"},
+			ext:    "flac",
+			want:   "/music/Band/Album/01 Song.flac",
+		},
+		{
+			name:   "long name keeps its extension",
+			policy: Policy{Profile: POSIX},
+			dir:    "/music",
+			names:  []string{"Band", long},
+			ext:    "flac",
+			want:   "/music/Band/" + long[:MaxNameLength-len(".flac")] + ".flac",
+		},
+		{
+			name:   "longest name cut",
+			policy: Policy{Profile: POSIX, MaxPathLength: 20},
+			dir:    "/m",
+			names:  []string{"aaaaaaaaaa", "bbbb", "cc"},
+			ext:    "x",
+			want:   "/m/aaaaaaa/bbbb/cc.x",
+		},
+		{
+			name:   "long names cut evenly",
+			policy: Policy{Profile: POSIX, MaxPathLength: 14},
+			dir:    "/m",
+			names:  []string{"aaaaaaaa", "bbbbbbbb"},
+			want:   "/m/aaaaa/bbbbb",
+		},
+		{
+			name:   "reserved name after cutting",
+			policy: Policy{Profile: Windows, MaxPathLength: 8},
+			dir:    "/m",
+			names:  []string{"Aux.long name"},
+			want:   "/m/_Aux",
+		},
+		{
+			name:    "does not fit",
+			policy:  Policy{Profile: POSIX, MaxPathLength: 10},
+			dir:     "/a/long/directory",
+			names:   []string{"Band", "Album"},
+			ext:     "flac",
+			wantErr: "does not fit in 10 bytes",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.policy.Fit(tt.dir, tt.names, tt.ext)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Fit() = %q, %v; want an error containing %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fit() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Fit() = %q, want %q", got, tt.want)
+			}
+			if tt.policy.MaxPathLength > 0 && len(got) > tt.policy.MaxPathLength {
+				t.Errorf("Fit() = %d bytes, over the %d byte limit", len(got), tt.policy.MaxPathLength)
+			}
+		})
+	}
+}
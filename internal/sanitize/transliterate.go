@@ -0,0 +1,69 @@
+package sanitize
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// asciiLetters groups accented letters by the plain letter they become
+var asciiLetters = map[string]string{
+	"A": "ÀÁÂÃÄÅĀĂĄ", "a": "àáâãäåāăą",
+	"C": "ÇĆĈĊČ", "c": "çćĉċč",
+	"D": "ĎĐÐ", "d": "ďđð",
+	"E": "ÈÉÊËĒĔĖĘĚ", "e": "èéêëēĕėęě",
+	"G": "ĜĞĠĢ", "g": "ĝğġģ",
+	"H": "ĤĦ", "h": "ĥħ",
+	"I": "ÌÍÎÏĨĪĬĮİ", "i": "ìíîïĩīĭįı",
+	"J": "Ĵ", "j": "ĵ",
+	"K": "Ķ", "k": "ķ",
+	"L": "ĹĻĽĿŁ", "l": "ĺļľŀł",
+	"N": "ÑŃŅŇ", "n": "ñńņň",
+	"O": "ÒÓÔÕÖØŌŎŐ", "o": "òóôõöøōŏő",
+	"R": "ŔŖŘ", "r": "ŕŗř",
+	"S": "ŚŜŞŠ", "s": "śŝşš",
+	"T": "ŢŤŦ", "t": "ţťŧ",
+	"U": "ÙÚÛÜŨŪŬŮŰŲ", "u": "ùúûüũūŭůűų",
+	"W": "Ŵ", "w": "ŵ",
+	"Y": "ÝŶŸ", "y": "ýÿŷ",
+	"Z": "ŹŻŽ", "z": "źżž",
+}
+
+// asciiSpecial are characters that become more than one letter or are
+// punctuation with a plain equivalent
+var asciiSpecial = map[rune]string{
+	'Æ': "AE", 'æ': "ae", 'Œ': "OE", 'œ': "oe", 'ß': "ss",
+	'Þ': "Th", 'þ': "th", 'Ĳ': "IJ", 'ĳ': "ij",
+	'‘': "'", '’': "'", '‚': "'", '′': "'",
+	'“': "'", '”': "'", '„': "'", '″': "'", // Double quotes are not allowed on Windows
+	'–': "-", '—': "-", '‐': "-", '‒': "-",
+	'…': "...", '×': "x", '·': "-", '•': "-",
+	' ': " ", // No-break space
+}
+
+// asciiTable maps every known character to its transliteration
+var asciiTable = func() map[rune]string {
+	table := map[rune]string{}
+	for plain, accented := range asciiLetters {
+		for _, r := range accented {
+			table[r] = plain
+		}
+	}
+	for r, plain := range asciiSpecial {
+		table[r] = plain
+	}
+	return table
+}()
+
+// transliterate returns the ASCII stand-in for a character. Accents left as
+// combining marks are dropped; other characters without a stand-in are
+// written as their code point, e.g. U+65E5, so names in other scripts do not
+// all collapse to the same name.
+func transliterate(r rune) string {
+	if plain, ok := asciiTable[r]; ok {
+		return plain
+	}
+	if unicode.Is(unicode.Mn, r) {
+		return ""
+	}
+	return fmt.Sprintf("U+%04X", r)
+}
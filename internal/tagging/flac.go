@@ -0,0 +1,195 @@
+package tagging
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// FLAC metadata block types
+const (
+	flacStreamInfo    = 0
+	flacPadding       = 1
+	flacVorbisComment = 4
+	flacPicture       = 6
+)
+
+// flacPaddingSize is the padding left after the metadata when a file has to
+// be rewritten, the same amount the flac encoder leaves
+const flacPaddingSize = 8192
+
+// flacMaxBlockSize is the largest metadata block FLAC can describe
+const flacMaxBlockSize = 1<<24 - 1
+
+var errNotFLAC = errors.New("not a FLAC file")
+
+// flacBlock is a metadata block without its header
+type flacBlock struct {
+	blockType byte
+	data      []byte
+}
+
+// readFLACMetadata reads the metadata blocks of a FLAC stream and returns
+// them with the offset the audio frames start at
+func readFLACMetadata(r io.Reader) ([]flacBlock, int64, error) {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != "fLaC" {
+		return nil, 0, errNotFLAC
+	}
+
+	var blocks []flacBlock
+	offset := int64(4)
+	for {
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(br, header); err != nil {
+			return nil, 0, fmt.Errorf("truncated metadata: %w", err)
+		}
+		last := header[0]&0x80 != 0
+		length := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+
+		data := make([]byte, length)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return nil, 0, fmt.Errorf("truncated metadata: %w", err)
+		}
+		blocks = append(blocks, flacBlock{blockType: header[0] & 0x7f, data: data})
+		offset += 4 + int64(length)
+
+		if last {
+			return blocks, offset, nil
+		}
+	}
+}
+
+// marshalFLACMetadata encodes blocks with their headers, marking the last
+func marshalFLACMetadata(blocks []flacBlock) []byte {
+	var buf bytes.Buffer
+	for i, block := range blocks {
+		header := block.blockType
+		if i == len(blocks)-1 {
+			header |= 0x80
+		}
+		length := len(block.data)
+		buf.Write([]byte{header, byte(length >> 16), byte(length >> 8), byte(length)})
+		buf.Write(block.data)
+	}
+	return buf.Bytes()
+}
+
+// writeFLAC replaces the Vorbis comments of a FLAC file. The metadata is
+// rewritten in place when it fits in the space of the old metadata and its
+// padding; otherwise the whole file is rewritten.
+func writeFLAC(path string, tags Tags) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	blocks, audioOffset, err := readFLACMetadata(f)
+	if err != nil {
+		return err
+	}
+	if blocks[0].blockType != flacStreamInfo {
+		return errNotFLAC
+	}
+
+	var updated []flacBlock
+	found := false
+	for _, block := range blocks {
+		switch block.blockType {
+		case flacPadding:
+			continue // Recomputed below
+		case flacPicture:
+			if tags.Picture != nil && isFrontCover(block.data) {
+				continue // Replaced below
+			}
+		case flacVorbisComment:
+			comment, _, err := parseVorbisComment(block.data)
+			if err != nil {
+				return err
+			}
+			comment.setTags(tags)
+			block.data = comment.marshal()
+			found = true
+		}
+		updated = append(updated, block)
+	}
+	if !found {
+		comment := &vorbisComment{vendor: "media-ripper"}
+		comment.setTags(tags)
+		// The comment goes right after STREAMINFO, which must come first
+		updated = append(updated[:1], append([]flacBlock{{flacVorbisComment, comment.marshal()}}, updated[1:]...)...)
+	}
+	if tags.Picture != nil {
+		updated = append(updated, flacBlock{flacPicture, marshalPicture(tags.Picture)})
+	}
+
+	for _, block := range updated {
+		if len(block.data) > flacMaxBlockSize {
+			return fmt.Errorf("metadata block of %d bytes is too large", len(block.data))
+		}
+	}
+
+	size := int64(0)
+	for _, block := range updated {
+		size += 4 + int64(len(block.data))
+	}
+	available := audioOffset - 4
+
+	// Rewrite in place if the old space is filled exactly or leaves room
+	// for a padding block
+	if size == available || size+4 <= available {
+		if size < available {
+			updated = append(updated, flacBlock{flacPadding, make([]byte, available-size-4)})
+		}
+		_, err := f.WriteAt(marshalFLACMetadata(updated), 4)
+		if err != nil {
+			return err
+		}
+		return f.Close()
+	}
+
+	updated = append(updated, flacBlock{flacPadding, make([]byte, flacPaddingSize)})
+	return replaceFile(path, func(out *os.File) error {
+		if _, err := out.Write([]byte("fLaC")); err != nil {
+			return err
+		}
+		if _, err := out.Write(marshalFLACMetadata(updated)); err != nil {
+			return err
+		}
+		if _, err := f.Seek(audioOffset, io.SeekStart); err != nil {
+			return err
+		}
+		_, err := io.Copy(out, f)
+		return err
+	})
+}
+
+// readFLAC reads the Vorbis comments of a FLAC file
+func readFLAC(path string) (Tags, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Tags{}, err
+	}
+	defer f.Close()
+
+	blocks, _, err := readFLACMetadata(f)
+	if err != nil {
+		return Tags{}, err
+	}
+	for _, block := range blocks {
+		if block.blockType == flacVorbisComment {
+			comment, _, err := parseVorbisComment(block.data)
+			if err != nil {
+				return Tags{}, err
+			}
+			return comment.tags(), nil
+		}
+	}
+	return Tags{}, nil
+}
@@ -0,0 +1,404 @@
+package tagging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// ID3v2 header flags
+const (
+	id3Unsynchronised = 0x80
+	id3ExtendedHeader = 0x40
+	id3Footer         = 0x10
+)
+
+// id3Padding is the space left after the frames of a new tag so later edits
+// can be written in place
+const id3Padding = 1024
+
+// Identifiers MusicBrainz Picard uses for its IDs in ID3
+const (
+	musicBrainzAlbumIDDescription = "MusicBrainz Album Id"
+	musicBrainzDiscIDDescription  = "MusicBrainz Disc Id"
+	musicBrainzOwner              = "http://musicbrainz.org"
+)
+
+// cddbDiscIDDescription names the TXXX frame holding the CDDB disc ID
+const cddbDiscIDDescription = "CDDB DiscID"
+
+// userTexts returns the TXXX frames Tags covers by description
+func userTexts(tags Tags) []struct{ description, value string } {
+	return []struct{ description, value string }{
+		{musicBrainzAlbumIDDescription, tags.MusicBrainzAlbumID},
+		{musicBrainzDiscIDDescription, tags.MusicBrainzDiscID},
+		{cddbDiscIDDescription, tags.CDDBDiscID},
+	}
+}
+
+// ID3 text encodings
+const (
+	id3Latin1  = 0
+	id3UTF16   = 1
+	id3UTF16BE = 2
+	id3UTF8    = 3
+)
+
+var errBadID3 = errors.New("malformed ID3v2 tag")
+
+// id3Frame is a frame of an ID3v2 tag
+type id3Frame struct {
+	id    string
+	flags []byte // Kept as found so preserved frames round-trip
+	data  []byte
+}
+
+// id3Tag is an ID3v2 tag at the start of a file
+type id3Tag struct {
+	version byte // Major version: 3 or 4
+	flags   byte
+	size    int // Whole tag including headers and padding; 0 without a tag
+	frames  []id3Frame
+}
+
+// syncsafe decodes a 28-bit integer stored in the low 7 bits of 4 bytes
+func syncsafe(b []byte) int {
+	return int(b[0]&0x7f)<<21 | int(b[1]&0x7f)<<14 | int(b[2]&0x7f)<<7 | int(b[3]&0x7f)
+}
+
+// appendSyncsafe appends n as a 28-bit syncsafe integer
+func appendSyncsafe(b []byte, n int) []byte {
+	return append(b, byte(n>>21)&0x7f, byte(n>>14)&0x7f, byte(n>>7)&0x7f, byte(n)&0x7f)
+}
+
+// readID3 reads the ID3v2 tag at the start of r, if there is one
+func readID3(r io.Reader) (*id3Tag, error) {
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(r, header); err != nil || string(header[:3]) != "ID3" {
+		return &id3Tag{}, nil
+	}
+
+	tag := &id3Tag{version: header[3], flags: header[5], size: 10 + syncsafe(header[6:10])}
+	if tag.flags&id3Footer != 0 {
+		tag.size += 10
+	}
+
+	body := make([]byte, syncsafe(header[6:10]))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, errBadID3
+	}
+	if tag.version != 3 && tag.version != 4 {
+		return tag, nil // Only the size matters for older versions
+	}
+	if tag.flags&id3Unsynchronised != 0 {
+		body = bytes.ReplaceAll(body, []byte{0xff, 0x00}, []byte{0xff})
+	}
+
+	if tag.flags&id3ExtendedHeader != 0 {
+		if len(body) < 4 {
+			return nil, errBadID3
+		}
+		skip := syncsafe(body[:4])
+		if tag.version == 3 {
+			skip = int(binary.BigEndian.Uint32(body)) + 4
+		}
+		if skip > len(body) {
+			return nil, errBadID3
+		}
+		body = body[skip:]
+	}
+
+	for len(body) >= 10 && body[0] != 0 {
+		size := syncsafe(body[4:8])
+		if tag.version == 3 {
+			size = int(binary.BigEndian.Uint32(body[4:8]))
+		}
+		if 10+size > len(body) {
+			return nil, errBadID3
+		}
+		tag.frames = append(tag.frames, id3Frame{
+			id:    string(body[:4]),
+			flags: append([]byte{}, body[8:10]...),
+			data:  body[10 : 10+size],
+		})
+		body = body[10+size:]
+	}
+	return tag, nil
+}
+
+// textFrame builds a UTF-8 text frame
+func textFrame(id, text string) id3Frame {
+	return id3Frame{id: id, data: append([]byte{id3UTF8}, text...)}
+}
+
+// id3Frames returns the frames for tags, leaving out empty values
+func id3Frames(tags Tags) []id3Frame {
+	texts := []struct{ id, value string }{
+		{"TIT2", tags.Title},
+		{"TPE1", tags.Artist},
+		{"TALB", tags.Album},
+		{"TPE2", tags.AlbumArtist},
+		{"TRCK", numberOf(tags.TrackNumber, tags.TrackTotal)},
+		{"TPOS", number(tags.DiscNumber)},
+		{"TDRC", tags.Year},
+		{"TCON", tags.Genre},
+		{"TSRC", tags.ISRC},
+	}
+	if tags.Compilation {
+		// iTunes' compilation flag, which most players read
+		texts = append(texts, struct{ id, value string }{"TCMP", "1"})
+	}
+
+	var frames []id3Frame
+	for _, text := range texts {
+		if text.value != "" {
+			frames = append(frames, textFrame(text.id, text.value))
+		}
+	}
+
+	for _, text := range userTexts(tags) {
+		if text.value != "" {
+			data := append([]byte{id3UTF8}, text.description...)
+			data = append(data, 0)
+			data = append(data, text.value...)
+			frames = append(frames, id3Frame{id: "TXXX", data: data})
+		}
+	}
+	if tags.MusicBrainzTrackID != "" {
+		data := append([]byte(musicBrainzOwner), 0)
+		data = append(data, tags.MusicBrainzTrackID...)
+		frames = append(frames, id3Frame{id: "UFID", data: data})
+	}
+	return frames
+}
+
+// numberOf formats n/total, leaving out an unknown total
+func numberOf(n, total int) string {
+	if n <= 0 {
+		return ""
+	}
+	if total <= 0 {
+		return strconv.Itoa(n)
+	}
+	return strconv.Itoa(n) + "/" + strconv.Itoa(total)
+}
+
+// managedFrame reports whether a frame holds a field Tags covers
+func managedFrame(frame id3Frame) bool {
+	switch frame.id {
+	case "TIT2", "TPE1", "TALB", "TPE2", "TRCK", "TPOS", "TDRC", "TCON", "TCMP", "TSRC":
+		return true
+	case "TXXX":
+		description, _ := splitText(frame.data)
+		for _, text := range userTexts(Tags{}) {
+			if strings.EqualFold(description, text.description) {
+				return true
+			}
+		}
+		return false
+	case "UFID":
+		owner, _, _ := bytes.Cut(frame.data, []byte{0})
+		return string(owner) == musicBrainzOwner
+	}
+	return false
+}
+
+// marshalID3 encodes frames as an ID3v2.4 tag of exactly size bytes, or
+// with the default padding when size is 0
+func marshalID3(frames []id3Frame, size int) []byte {
+	var body []byte
+	for _, frame := range frames {
+		body = append(body, frame.id...)
+		body = appendSyncsafe(body, len(frame.data))
+		if len(frame.flags) == 2 {
+			body = append(body, frame.flags...)
+		} else {
+			body = append(body, 0, 0)
+		}
+		body = append(body, frame.data...)
+	}
+
+	padding := id3Padding
+	if size > 0 {
+		padding = size - 10 - len(body)
+	}
+	body = append(body, make([]byte, padding)...)
+
+	tag := []byte{'I', 'D', '3', 4, 0, 0}
+	tag = appendSyncsafe(tag, len(body))
+	return append(tag, body...)
+}
+
+// writeMP3 replaces the ID3v2 tag of an MP3 file with an ID3v2.4 tag.
+// Frames of an existing v2.4 tag that Tags does not cover, such as pictures,
+// are kept. The tag is rewritten in place when the old one has room.
+func writeMP3(path string, tags Tags) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	old, err := readID3(f)
+	if err != nil {
+		return err
+	}
+
+	var frames []id3Frame
+	if old.version == 4 && old.flags&id3Unsynchronised == 0 {
+		for _, frame := range old.frames {
+			if !managedFrame(frame) && (tags.Picture == nil || !isFrontCoverFrame(frame)) {
+				frames = append(frames, frame)
+			}
+		}
+	}
+	frames = append(frames, id3Frames(tags)...)
+	if tags.Picture != nil {
+		frames = append(frames, apicFrame(tags.Picture))
+	}
+
+	// The new tag fits if the frames leave the old tag's size for padding
+	needed := len(marshalID3(frames, 0)) - id3Padding
+	if old.size > 0 && old.flags&id3Footer == 0 && needed <= old.size {
+		if _, err := f.WriteAt(marshalID3(frames, old.size), 0); err != nil {
+			return err
+		}
+		return f.Close()
+	}
+
+	return replaceFile(path, func(out *os.File) error {
+		if _, err := out.Write(marshalID3(frames, 0)); err != nil {
+			return err
+		}
+		if _, err := f.Seek(int64(old.size), io.SeekStart); err != nil {
+			return err
+		}
+		_, err := io.Copy(out, f)
+		return err
+	})
+}
+
+// readMP3 reads the ID3v2 tag of an MP3 file
+func readMP3(path string) (Tags, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Tags{}, err
+	}
+	defer f.Close()
+
+	tag, err := readID3(f)
+	if err != nil {
+		return Tags{}, err
+	}
+
+	var tags Tags
+	for _, frame := range tag.frames {
+		switch frame.id {
+		case "TXXX":
+			description, value := splitText(frame.data)
+			switch {
+			case strings.EqualFold(description, musicBrainzAlbumIDDescription):
+				tags.MusicBrainzAlbumID = value
+			case strings.EqualFold(description, musicBrainzDiscIDDescription):
+				tags.MusicBrainzDiscID = value
+			case strings.EqualFold(description, cddbDiscIDDescription):
+				tags.CDDBDiscID = value
+			}
+			continue
+		case "UFID":
+			owner, id, _ := bytes.Cut(frame.data, []byte{0})
+			if string(owner) == musicBrainzOwner {
+				tags.MusicBrainzTrackID = string(id)
+			}
+			continue
+		}
+
+		if !strings.HasPrefix(frame.id, "T") || len(frame.data) == 0 {
+			continue
+		}
+		text := decodeText(frame.data[0], frame.data[1:])
+		switch frame.id {
+		case "TIT2":
+			tags.Title = text
+		case "TPE1":
+			tags.Artist = text
+		case "TALB":
+			tags.Album = text
+		case "TPE2":
+			tags.AlbumArtist = text
+		case "TRCK":
+			n, total, _ := strings.Cut(text, "/")
+			tags.TrackNumber, _ = strconv.Atoi(n)
+			tags.TrackTotal, _ = strconv.Atoi(total)
+		case "TPOS":
+			n, _, _ := strings.Cut(text, "/")
+			tags.DiscNumber, _ = strconv.Atoi(n)
+		case "TDRC", "TYER":
+			tags.Year = text
+		case "TCON":
+			tags.Genre = text
+		case "TCMP":
+			tags.Compilation = text == "1"
+		case "TSRC":
+			tags.ISRC = text
+		}
+	}
+	return tags, nil
+}
+
+// splitText splits a TXXX frame into its description and value
+func splitText(data []byte) (string, string) {
+	if len(data) == 0 {
+		return "", ""
+	}
+	encoding, data := data[0], data[1:]
+
+	if encoding == id3UTF16 || encoding == id3UTF16BE {
+		// The terminator is aligned to the two-byte characters
+		for i := 0; i+1 < len(data); i += 2 {
+			if data[i] == 0 && data[i+1] == 0 {
+				return decodeText(encoding, data[:i]), decodeText(encoding, data[i+2:])
+			}
+		}
+		return decodeText(encoding, data), ""
+	}
+
+	description, value, _ := bytes.Cut(data, []byte{0})
+	return decodeText(encoding, description), decodeText(encoding, value)
+}
+
+// decodeText decodes ID3 text in the given encoding, dropping terminators
+func decodeText(encoding byte, data []byte) string {
+	var text string
+	switch encoding {
+	case id3Latin1:
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		text = string(runes)
+	case id3UTF16, id3UTF16BE:
+		order := binary.ByteOrder(binary.BigEndian)
+		if encoding == id3UTF16 && len(data) >= 2 {
+			if data[0] == 0xff && data[1] == 0xfe {
+				order = binary.LittleEndian
+			}
+			if (data[0] == 0xff && data[1] == 0xfe) || (data[0] == 0xfe && data[1] == 0xff) {
+				data = data[2:]
+			}
+		}
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = order.Uint16(data[2*i:])
+		}
+		text = string(utf16.Decode(units))
+	default:
+		text = string(data)
+	}
+	return strings.TrimRight(text, "\x00")
+}
@@ -0,0 +1,303 @@
+package tagging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Ogg page header flags
+const (
+	oggContinued = 0x01
+	oggFirstPage = 0x02
+)
+
+// oggMaxSegments is the most lacing values one page can hold
+const oggMaxSegments = 255
+
+var errNotOgg = errors.New("not an Ogg Vorbis or Opus file")
+
+// oggCRCTable is the lookup table for Ogg's CRC-32: polynomial 0x04c11db7,
+// not reflected, zero initial value
+var oggCRCTable = func() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		crc := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+// oggPage is a page of an Ogg stream
+type oggPage struct {
+	flags    byte
+	granule  uint64
+	serial   uint32
+	sequence uint32
+	segments []byte // Lacing values
+	data     []byte
+}
+
+// readOggPage reads the next page
+func readOggPage(r io.Reader) (*oggPage, error) {
+	header := make([]byte, 27)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if string(header[:4]) != "OggS" || header[4] != 0 {
+		return nil, errNotOgg
+	}
+
+	page := &oggPage{
+		flags:    header[5],
+		granule:  binary.LittleEndian.Uint64(header[6:]),
+		serial:   binary.LittleEndian.Uint32(header[14:]),
+		sequence: binary.LittleEndian.Uint32(header[18:]),
+		segments: make([]byte, header[26]),
+	}
+	if _, err := io.ReadFull(r, page.segments); err != nil {
+		return nil, err
+	}
+
+	size := 0
+	for _, lacing := range page.segments {
+		size += int(lacing)
+	}
+	page.data = make([]byte, size)
+	if _, err := io.ReadFull(r, page.data); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// marshal encodes the page with its checksum
+func (p *oggPage) marshal() []byte {
+	data := make([]byte, 0, 27+len(p.segments)+len(p.data))
+	data = append(data, "OggS"...)
+	data = append(data, 0, p.flags)
+	data = binary.LittleEndian.AppendUint64(data, p.granule)
+	data = binary.LittleEndian.AppendUint32(data, p.serial)
+	data = binary.LittleEndian.AppendUint32(data, p.sequence)
+	data = append(data, 0, 0, 0, 0) // Checksum, computed over the page with zeros here
+	data = append(data, byte(len(p.segments)))
+	data = append(data, p.segments...)
+	data = append(data, p.data...)
+
+	var crc uint32
+	for _, b := range data {
+		crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^b]
+	}
+	binary.LittleEndian.PutUint32(data[22:], crc)
+	return data
+}
+
+// oggCodec describes where a codec keeps its comments
+type oggCodec struct {
+	headers       int    // Header packets before the audio
+	commentPrefix []byte // Start of the comment packet
+	framingBit    bool   // Vorbis ends the comment packet with a set bit
+}
+
+var (
+	vorbisCodec = oggCodec{headers: 3, commentPrefix: []byte("\x03vorbis"), framingBit: true}
+	opusCodec   = oggCodec{headers: 2, commentPrefix: []byte("OpusTags")}
+)
+
+// oggHeaders holds the header packets of an Ogg stream
+type oggHeaders struct {
+	codec   oggCodec
+	first   *oggPage // Holds the identification header alone
+	serial  uint32
+	packets [][]byte
+	pages   int // Pages the header packets take up
+}
+
+// readOggHeaders reads the header packets at the start of a Vorbis or Opus
+// stream. The audio starts on the page after the last header.
+func readOggHeaders(r io.Reader) (*oggHeaders, error) {
+	first, err := readOggPage(r)
+	if err != nil {
+		return nil, errNotOgg
+	}
+
+	if first.flags&oggFirstPage == 0 {
+		return nil, errNotOgg
+	}
+
+	headers := &oggHeaders{first: first, serial: first.serial, pages: 1}
+	switch {
+	case bytes.HasPrefix(first.data, []byte("\x01vorbis")):
+		headers.codec = vorbisCodec
+	case bytes.HasPrefix(first.data, []byte("OpusHead")):
+		headers.codec = opusCodec
+	default:
+		return nil, errNotOgg
+	}
+	// The identification header is alone on the first page
+	headers.packets = [][]byte{first.data}
+
+	var packet []byte
+	for len(headers.packets) < headers.codec.headers {
+		page, err := readOggPage(r)
+		if err != nil {
+			return nil, fmt.Errorf("truncated headers: %w", err)
+		}
+		if page.serial != first.serial {
+			return nil, fmt.Errorf("multiplexed Ogg streams are not supported")
+		}
+		headers.pages++
+
+		offset := 0
+		for _, lacing := range page.segments {
+			packet = append(packet, page.data[offset:offset+int(lacing)]...)
+			offset += int(lacing)
+			if lacing < 255 {
+				headers.packets = append(headers.packets, packet)
+				packet = nil
+			}
+		}
+	}
+	if packet != nil || len(headers.packets) != headers.codec.headers {
+		return nil, fmt.Errorf("audio starts on a header page")
+	}
+	return headers, nil
+}
+
+// comment parses the comment packet
+func (h *oggHeaders) comment() (*vorbisComment, error) {
+	packet := h.packets[1]
+	if !bytes.HasPrefix(packet, h.codec.commentPrefix) {
+		return nil, errBadComment
+	}
+	comment, _, err := parseVorbisComment(packet[len(h.codec.commentPrefix):])
+	return comment, err
+}
+
+// setComment replaces the comment packet
+func (h *oggHeaders) setComment(comment *vorbisComment) {
+	packet := append([]byte{}, h.codec.commentPrefix...)
+	packet = append(packet, comment.marshal()...)
+	if h.codec.framingBit {
+		packet = append(packet, 1)
+	}
+	h.packets[1] = packet
+}
+
+// paginate lays out the header packets after the first on pages, starting
+// with sequence number 1
+func (h *oggHeaders) paginate() []*oggPage {
+	var pages []*oggPage
+	page := &oggPage{serial: h.serial, sequence: 1}
+
+	for _, packet := range h.packets[1:] {
+		continued := false
+		for {
+			if len(page.segments) == oggMaxSegments {
+				pages = append(pages, page)
+				page = &oggPage{serial: h.serial, sequence: page.sequence + 1}
+				if continued {
+					page.flags = oggContinued
+				}
+			}
+			lacing := min(len(packet), 255)
+			page.segments = append(page.segments, byte(lacing))
+			page.data = append(page.data, packet[:lacing]...)
+			packet = packet[lacing:]
+			continued = true
+			if lacing < 255 {
+				break
+			}
+		}
+	}
+	return append(pages, page)
+}
+
+// writeOgg replaces the comments of an Ogg Vorbis or Opus file. The comment
+// packet usually changes size, so the file is rewritten with the following
+// pages renumbered.
+func writeOgg(path string, tags Tags) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	headers, err := readOggHeaders(br)
+	if err != nil {
+		return err
+	}
+	comment, err := headers.comment()
+	if err != nil {
+		return err
+	}
+	comment.setTags(tags)
+	if tags.Picture != nil {
+		comment.setPicture(tags.Picture)
+	}
+	headers.setComment(comment)
+
+	return replaceFile(path, func(out *os.File) error {
+		w := bufio.NewWriter(out)
+
+		if _, err := w.Write(headers.first.marshal()); err != nil {
+			return err
+		}
+
+		headerPages := headers.paginate()
+		for _, page := range headerPages {
+			if _, err := w.Write(page.marshal()); err != nil {
+				return err
+			}
+		}
+
+		// Audio pages keep their contents but follow on from the new headers
+		shift := uint32(len(headerPages)+1) - uint32(headers.pages)
+		for {
+			page, err := readOggPage(br)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if page.serial == headers.serial {
+				page.sequence += shift
+			}
+			if _, err := w.Write(page.marshal()); err != nil {
+				return err
+			}
+		}
+		return w.Flush()
+	})
+}
+
+// readOgg reads the comments of an Ogg Vorbis or Opus file
+func readOgg(path string) (Tags, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Tags{}, err
+	}
+	defer f.Close()
+
+	headers, err := readOggHeaders(bufio.NewReader(f))
+	if err != nil {
+		return Tags{}, err
+	}
+	comment, err := headers.comment()
+	if err != nil {
+		return Tags{}, err
+	}
+	return comment.tags(), nil
+}
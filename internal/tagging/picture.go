@@ -0,0 +1,76 @@
+package tagging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"image"
+	_ "image/jpeg" // Registers JPEG for DecodeConfig
+)
+
+// pictureFrontCover is the picture type of a front cover, shared by FLAC
+// PICTURE blocks and ID3 APIC frames
+const pictureFrontCover = 3
+
+// pictureMIME is the type of every picture written; covers are JPEG
+const pictureMIME = "image/jpeg"
+
+// metadataBlockPicture is the Vorbis comment that holds a base64 FLAC
+// PICTURE block in Ogg files
+const metadataBlockPicture = "METADATA_BLOCK_PICTURE"
+
+// marshalPicture encodes a JPEG front cover as a FLAC PICTURE block
+func marshalPicture(picture []byte) []byte {
+	width, height := 0, 0
+	if config, _, err := image.DecodeConfig(bytes.NewReader(picture)); err == nil {
+		width, height = config.Width, config.Height
+	}
+
+	data := binary.BigEndian.AppendUint32(nil, pictureFrontCover)
+	data = binary.BigEndian.AppendUint32(data, uint32(len(pictureMIME)))
+	data = append(data, pictureMIME...)
+	data = binary.BigEndian.AppendUint32(data, 0) // No description
+	data = binary.BigEndian.AppendUint32(data, uint32(width))
+	data = binary.BigEndian.AppendUint32(data, uint32(height))
+	data = binary.BigEndian.AppendUint32(data, 24) // Colour depth
+	data = binary.BigEndian.AppendUint32(data, 0)  // Not an indexed image
+	data = binary.BigEndian.AppendUint32(data, uint32(len(picture)))
+	return append(data, picture...)
+}
+
+// isFrontCover reports whether a FLAC PICTURE block holds a front cover
+func isFrontCover(block []byte) bool {
+	return len(block) >= 4 && binary.BigEndian.Uint32(block) == pictureFrontCover
+}
+
+// setPicture replaces the front cover of the comment, leaving other pictures
+func (c *vorbisComment) setPicture(picture []byte) {
+	var kept []string
+	for _, field := range c.fields {
+		name, value, _ := bytes.Cut([]byte(field), []byte("="))
+		if string(bytes.ToUpper(name)) == metadataBlockPicture {
+			if block, err := base64.StdEncoding.DecodeString(string(value)); err != nil || isFrontCover(block) {
+				continue
+			}
+		}
+		kept = append(kept, field)
+	}
+	c.fields = append(kept, metadataBlockPicture+"="+base64.StdEncoding.EncodeToString(marshalPicture(picture)))
+}
+
+// apicFrame builds an ID3 APIC frame holding a JPEG front cover
+func apicFrame(picture []byte) id3Frame {
+	data := []byte{id3Latin1}
+	data = append(data, pictureMIME...)
+	data = append(data, 0, pictureFrontCover, 0) // MIME terminator, type, empty description
+	return id3Frame{id: "APIC", data: append(data, picture...)}
+}
+
+// isFrontCoverFrame reports whether an APIC frame holds a front cover
+func isFrontCoverFrame(frame id3Frame) bool {
+	if frame.id != "APIC" || len(frame.data) < 2 {
+		return false
+	}
+	_, rest, ok := bytes.Cut(frame.data[1:], []byte{0})
+	return ok && len(rest) > 0 && rest[0] == pictureFrontCover
+}
@@ -0,0 +1,122 @@
+// Package tagging writes track metadata and cover art into audio files
+// without external tools: Vorbis comments and pictures into FLAC, Ogg Vorbis
+// and Opus files and ID3v2.4 frames into MP3 files.
+package tagging
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnsupported is returned for files whose format cannot be tagged
+var ErrUnsupported = errors.New("unsupported file format")
+
+// Tags is the metadata written to a track's file. Empty values and zero
+// numbers are left out of the file.
+type Tags struct {
+	Title       string
+	Artist      string
+	Album       string
+	AlbumArtist string
+	TrackNumber int
+	TrackTotal  int
+	DiscNumber  int
+	Year        string
+	Genre       string
+	Compilation bool
+	ISRC        string
+
+	MusicBrainzAlbumID string // Release ID
+	MusicBrainzTrackID string // Recording ID
+	MusicBrainzDiscID  string
+	CDDBDiscID         string
+
+	// CueSheet marks the tracks of a disc image; only Vorbis comments carry it
+	CueSheet string
+
+	// Picture is a JPEG front cover replacing the file's own; nil leaves the
+	// pictures in the file alone
+	Picture []byte
+}
+
+// Supported reports whether the file at path can be tagged, judging by its
+// extension
+func Supported(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".flac", ".ogg", ".oga", ".opus", ".mp3":
+		return true
+	default:
+		return false
+	}
+}
+
+// WriteFile replaces the tags of the file at path. Fields that Tags does not
+// cover, such as encoder information, are kept.
+func WriteFile(path string, tags Tags) error {
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".flac":
+		err = writeFLAC(path, tags)
+	case ".ogg", ".oga", ".opus":
+		err = writeOgg(path, tags)
+	case ".mp3":
+		err = writeMP3(path, tags)
+	default:
+		err = ErrUnsupported
+	}
+	if err != nil {
+		return fmt.Errorf("failed to tag %s: %w", path, err)
+	}
+	return nil
+}
+
+// ReadFile reads the tags of the file at path
+func ReadFile(path string) (Tags, error) {
+	var tags Tags
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".flac":
+		tags, err = readFLAC(path)
+	case ".ogg", ".oga", ".opus":
+		tags, err = readOgg(path)
+	case ".mp3":
+		tags, err = readMP3(path)
+	default:
+		err = ErrUnsupported
+	}
+	if err != nil {
+		return Tags{}, fmt.Errorf("failed to read tags from %s: %w", path, err)
+	}
+	return tags, nil
+}
+
+// replaceFile atomically replaces path with the output of write, keeping
+// the file's permissions
+func replaceFile(path string, write func(f *os.File) error) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
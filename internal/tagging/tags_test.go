@@ -0,0 +1,403 @@
+package tagging
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The fixtures in testdata are short files with stand-in audio. Each is
+// tagged with TITLE and GENRE, which are replaced, and an encoder field,
+// which is kept: ENCODER in Vorbis comments and a TSSE frame in ID3.
+
+// testTags sets every field Tags covers apart from the picture
+var testTags = Tags{
+	Title:              "Face / Off",
+	Artist:             "Björk feat. Guest",
+	Album:              "Album",
+	AlbumArtist:        "Björk",
+	TrackNumber:        3,
+	TrackTotal:         12,
+	DiscNumber:         2,
+	Year:               "1999-05-01",
+	Genre:              "Electronic",
+	Compilation:        true,
+	ISRC:               "USABC9900001",
+	MusicBrainzAlbumID: "5b4e8a4e-0000-4000-8000-000000000001",
+	MusicBrainzTrackID: "5b4e8a4e-0000-4000-8000-000000000002",
+	MusicBrainzDiscID:  "I5l9cCSFccLKFEKS.7wqSZAorPU-",
+	CDDBDiscID:         "a70de90c",
+}
+
+// tagFormat reads the parts of a format's files that tags must not disturb
+type tagFormat struct {
+	fixture string
+	// audio returns the file's audio, which tagging must leave as it was
+	audio func(t *testing.T, path string) []byte
+	// kept returns the encoder field that tagging must preserve
+	kept func(t *testing.T, path string) string
+	// covers counts the front covers in the file
+	covers func(t *testing.T, path string) int
+}
+
+var tagFormats = []tagFormat{
+	{fixture: "short.flac", audio: flacAudio, kept: flacEncoder, covers: flacCovers},
+	{fixture: "short.opus", audio: oggAudio, kept: oggEncoder, covers: oggCovers},
+	{fixture: "short.ogg", audio: oggAudio, kept: oggEncoder, covers: oggCovers},
+	{fixture: "short.mp3", audio: mp3Audio, kept: mp3Encoder, covers: mp3Covers},
+}
+
+// copyFixture copies a fixture into a temporary directory
+func copyFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// testCover returns a JPEG cover larger than the padding in any fixture
+func testCover(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for i := range img.Pix {
+		img.Pix[i] = byte(i * 31)
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func flacAudio(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, offset, err := readFLACMetadata(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("invalid FLAC metadata: %v", err)
+	}
+	return data[offset:]
+}
+
+// flacComment returns the fields of a FLAC file's Vorbis comment
+func flacComment(t *testing.T, path string) []string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	blocks, _, err := readFLACMetadata(f)
+	if err != nil {
+		t.Fatalf("invalid FLAC metadata: %v", err)
+	}
+	comments := 0
+	var fields []string
+	for _, block := range blocks {
+		if block.blockType == flacVorbisComment {
+			comment, _, err := parseVorbisComment(block.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			comments++
+			fields = comment.fields
+		}
+	}
+	if comments != 1 {
+		t.Errorf("file has %d Vorbis comment blocks, want 1", comments)
+	}
+	return fields
+}
+
+func flacEncoder(t *testing.T, path string) string {
+	return field(flacComment(t, path), "ENCODER")
+}
+
+func flacCovers(t *testing.T, path string) int {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	blocks, _, err := readFLACMetadata(f)
+	if err != nil {
+		t.Fatalf("invalid FLAC metadata: %v", err)
+	}
+	covers := 0
+	for _, block := range blocks {
+		if block.blockType == flacPicture && isFrontCover(block.data) {
+			covers++
+		}
+	}
+	return covers
+}
+
+// oggAudio checks that every page of an Ogg file is numbered in turn and
+// has a valid checksum, and returns the pages after the headers
+func oggAudio(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers, err := readOggHeaders(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatalf("invalid Ogg headers: %v", err)
+	}
+
+	var audio []byte
+	r := bytes.NewReader(data)
+	for sequence := uint32(0); ; sequence++ {
+		start := len(data) - r.Len()
+		page, err := readOggPage(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid Ogg page %d: %v", sequence, err)
+		}
+		raw := data[start : len(data)-r.Len()]
+		if !bytes.Equal(page.marshal(), raw) {
+			t.Errorf("page %d has a bad checksum", sequence)
+		}
+		if page.sequence != sequence {
+			t.Errorf("page %d is numbered %d", sequence, page.sequence)
+		}
+		if int(sequence) >= headers.pages {
+			// The sequence number and checksum change, the rest must not
+			audio = append(audio, page.flags)
+			audio = append(audio, raw[6:14]...)
+			audio = append(audio, raw[26:]...)
+		}
+	}
+	return audio
+}
+
+func oggComment(t *testing.T, path string) []string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	headers, err := readOggHeaders(bufio.NewReader(f))
+	if err != nil {
+		t.Fatalf("invalid Ogg headers: %v", err)
+	}
+	comment, err := headers.comment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if headers.codec.framingBit && !bytes.HasSuffix(headers.packets[1], []byte{1}) {
+		t.Error("Vorbis comment packet lost its framing bit")
+	}
+	return comment.fields
+}
+
+func oggEncoder(t *testing.T, path string) string {
+	return field(oggComment(t, path), "ENCODER")
+}
+
+func oggCovers(t *testing.T, path string) int {
+	covers := 0
+	for _, f := range oggComment(t, path) {
+		if strings.HasPrefix(f, metadataBlockPicture+"=") {
+			covers++
+		}
+	}
+	return covers
+}
+
+// mp3Tag reads an MP3 file's tag, which must be ID3v2.4
+func mp3Tag(t *testing.T, path string) (*id3Tag, []byte) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag, err := readID3(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("invalid ID3 tag: %v", err)
+	}
+	if tag.version != 4 {
+		t.Errorf("ID3 version = 2.%d, want 2.4", tag.version)
+	}
+	return tag, data
+}
+
+func mp3Audio(t *testing.T, path string) []byte {
+	tag, data := mp3Tag(t, path)
+	return data[tag.size:]
+}
+
+func mp3Encoder(t *testing.T, path string) string {
+	tag, _ := mp3Tag(t, path)
+	for _, frame := range tag.frames {
+		if frame.id == "TSSE" {
+			return decodeText(frame.data[0], frame.data[1:])
+		}
+	}
+	return ""
+}
+
+func mp3Covers(t *testing.T, path string) int {
+	tag, _ := mp3Tag(t, path)
+	covers := 0
+	for _, frame := range tag.frames {
+		if isFrontCoverFrame(frame) {
+			covers++
+		}
+	}
+	return covers
+}
+
+// field returns the value of a NAME=value field
+func field(fields []string, name string) string {
+	for _, f := range fields {
+		if n, value, ok := strings.Cut(f, "="); ok && strings.EqualFold(n, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range tagFormats {
+		t.Run(format.fixture, func(t *testing.T) {
+			path := copyFixture(t, format.fixture)
+			audio := format.audio(t, path)
+
+			if err := WriteFile(path, testTags); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+			got, err := ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, testTags) {
+				t.Errorf("ReadFile() = %+v\nwant %+v", got, testTags)
+			}
+			if !bytes.Equal(format.audio(t, path), audio) {
+				t.Error("audio changed")
+			}
+			if encoder := format.kept(t, path); encoder != "fixture" {
+				t.Errorf("encoder field = %q, want it kept", encoder)
+			}
+
+			// Fields left empty are removed rather than kept from before
+			fewer := Tags{Title: "Only Title", TrackNumber: 1}
+			if err := WriteFile(path, fewer); err != nil {
+				t.Fatalf("WriteFile() again error = %v", err)
+			}
+			got, err = ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile() again error = %v", err)
+			}
+			if !reflect.DeepEqual(got, fewer) {
+				t.Errorf("ReadFile() again = %+v\nwant %+v", got, fewer)
+			}
+			if !bytes.Equal(format.audio(t, path), audio) {
+				t.Error("audio changed on the second write")
+			}
+		})
+	}
+}
+
+func TestRoundTripPicture(t *testing.T) {
+	cover := testCover(t)
+	for _, format := range tagFormats {
+		t.Run(format.fixture, func(t *testing.T) {
+			path := copyFixture(t, format.fixture)
+			audio := format.audio(t, path)
+
+			// The cover does not fit in the fixture's padding, so the file
+			// is rewritten; writing it again replaces it
+			tags := testTags
+			tags.Picture = cover
+			for i := 0; i < 2; i++ {
+				if err := WriteFile(path, tags); err != nil {
+					t.Fatalf("WriteFile() error = %v", err)
+				}
+			}
+			if covers := format.covers(t, path); covers != 1 {
+				t.Errorf("file has %d front covers, want 1", covers)
+			}
+
+			got, err := ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, testTags) {
+				t.Errorf("ReadFile() = %+v\nwant %+v", got, testTags)
+			}
+			if !bytes.Equal(format.audio(t, path), audio) {
+				t.Error("audio changed")
+			}
+
+			// Tags without a picture leave the cover in place
+			if err := WriteFile(path, Tags{Title: "Retagged"}); err != nil {
+				t.Fatalf("WriteFile() without picture error = %v", err)
+			}
+			if covers := format.covers(t, path); covers != 1 {
+				t.Errorf("retagged file has %d front covers, want 1", covers)
+			}
+		})
+	}
+}
+
+func TestRoundTripCueSheet(t *testing.T) {
+	sheet := "FILE \"Band - Album.flac\" WAVE\n  TRACK 01 AUDIO\n    INDEX 01 00:00:00\n"
+	tags := Tags{Title: "Album", CueSheet: sheet}
+	for _, format := range tagFormats {
+		t.Run(format.fixture, func(t *testing.T) {
+			path := copyFixture(t, format.fixture)
+			if err := WriteFile(path, tags); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+			got, err := ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+
+			// ID3 has no cue sheet field, so MP3 images go without one
+			want := tags
+			if filepath.Ext(path) == ".mp3" {
+				want.CueSheet = ""
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("ReadFile() = %+v\nwant %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "track.m4a")
+	if err := os.WriteFile(path, []byte("audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if Supported(path) {
+		t.Error("Supported() = true for an m4a file")
+	}
+	if err := WriteFile(path, testTags); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("WriteFile() error = %v, want ErrUnsupported", err)
+	}
+}
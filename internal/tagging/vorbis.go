@@ -0,0 +1,193 @@
+package tagging
+
+import (
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// errBadComment is returned for a truncated or malformed comment header
+var errBadComment = errors.New("malformed vorbis comment")
+
+// vorbisComment is a Vorbis comment header: the encoder's vendor string and
+// NAME=value fields
+type vorbisComment struct {
+	vendor string
+	fields []string
+}
+
+// vorbisFields maps Tags to Vorbis comment names, following the names
+// MusicBrainz Picard uses
+func vorbisFields(tags Tags) []struct{ name, value string } {
+	compilation := ""
+	if tags.Compilation {
+		compilation = "1"
+	}
+
+	return []struct{ name, value string }{
+		{"TITLE", tags.Title},
+		{"ARTIST", tags.Artist},
+		{"ALBUM", tags.Album},
+		{"ALBUMARTIST", tags.AlbumArtist},
+		{"TRACKNUMBER", number(tags.TrackNumber)},
+		{"TRACKTOTAL", number(tags.TrackTotal)},
+		{"DISCNUMBER", number(tags.DiscNumber)},
+		{"DATE", tags.Year},
+		{"GENRE", tags.Genre},
+		{"COMPILATION", compilation},
+		{"ISRC", tags.ISRC},
+		{"MUSICBRAINZ_ALBUMID", tags.MusicBrainzAlbumID},
+		{"MUSICBRAINZ_TRACKID", tags.MusicBrainzTrackID},
+		{"MUSICBRAINZ_DISCID", tags.MusicBrainzDiscID},
+		{"CDDB", tags.CDDBDiscID}, // As abcde names it
+		{"CUESHEET", tags.CueSheet},
+	}
+}
+
+// VorbisComments returns tags as NAME=value Vorbis comments, leaving out
+// empty values
+func VorbisComments(tags Tags) []string {
+	var comments []string
+	for _, field := range vorbisFields(tags) {
+		if field.value != "" {
+			comments = append(comments, field.name+"="+field.value)
+		}
+	}
+	return comments
+}
+
+// number formats a tag number, empty for zero
+func number(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// setTags replaces the fields Tags covers, keeping all others
+func (c *vorbisComment) setTags(tags Tags) {
+	managed := map[string]bool{}
+	for _, field := range vorbisFields(tags) {
+		managed[field.name] = true
+	}
+	// Alternative names other taggers use for the same fields
+	managed["TOTALTRACKS"] = true
+	managed["YEAR"] = true
+
+	var kept []string
+	for _, field := range c.fields {
+		name, _, _ := strings.Cut(field, "=")
+		if !managed[strings.ToUpper(name)] {
+			kept = append(kept, field)
+		}
+	}
+	c.fields = append(kept, VorbisComments(tags)...)
+}
+
+// tags reads the fields Tags covers
+func (c *vorbisComment) tags() Tags {
+	var tags Tags
+	for _, field := range c.fields {
+		name, value, ok := strings.Cut(field, "=")
+		if !ok {
+			continue
+		}
+		switch strings.ToUpper(name) {
+		case "TITLE":
+			tags.Title = value
+		case "ARTIST":
+			tags.Artist = value
+		case "ALBUM":
+			tags.Album = value
+		case "ALBUMARTIST":
+			tags.AlbumArtist = value
+		case "TRACKNUMBER":
+			// Some taggers write n/total
+			n, total, _ := strings.Cut(value, "/")
+			tags.TrackNumber, _ = strconv.Atoi(n)
+			if total != "" {
+				tags.TrackTotal, _ = strconv.Atoi(total)
+			}
+		case "TRACKTOTAL", "TOTALTRACKS":
+			tags.TrackTotal, _ = strconv.Atoi(value)
+		case "DISCNUMBER":
+			n, _, _ := strings.Cut(value, "/")
+			tags.DiscNumber, _ = strconv.Atoi(n)
+		case "DATE", "YEAR":
+			tags.Year = value
+		case "GENRE":
+			tags.Genre = value
+		case "COMPILATION":
+			tags.Compilation = value == "1"
+		case "ISRC":
+			tags.ISRC = value
+		case "MUSICBRAINZ_ALBUMID":
+			tags.MusicBrainzAlbumID = value
+		case "MUSICBRAINZ_TRACKID":
+			tags.MusicBrainzTrackID = value
+		case "MUSICBRAINZ_DISCID":
+			tags.MusicBrainzDiscID = value
+		case "CDDB":
+			tags.CDDBDiscID = value
+		case "CUESHEET":
+			tags.CueSheet = value
+		}
+	}
+	return tags
+}
+
+// marshal encodes the comment header without a framing bit
+func (c *vorbisComment) marshal() []byte {
+	size := 4 + len(c.vendor) + 4
+	for _, field := range c.fields {
+		size += 4 + len(field)
+	}
+
+	data := make([]byte, 0, size)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(c.vendor)))
+	data = append(data, c.vendor...)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(c.fields)))
+	for _, field := range c.fields {
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(field)))
+		data = append(data, field...)
+	}
+	return data
+}
+
+// parseVorbisComment decodes a comment header, returning the bytes after it
+func parseVorbisComment(data []byte) (*vorbisComment, []byte, error) {
+	readString := func() (string, error) {
+		if len(data) < 4 {
+			return "", errBadComment
+		}
+		n := binary.LittleEndian.Uint32(data)
+		data = data[4:]
+		if uint64(n) > uint64(len(data)) {
+			return "", errBadComment
+		}
+		s := string(data[:n])
+		data = data[n:]
+		return s, nil
+	}
+
+	vendor, err := readString()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data) < 4 {
+		return nil, nil, errBadComment
+	}
+	count := binary.LittleEndian.Uint32(data)
+	data = data[4:]
+
+	comment := &vorbisComment{vendor: vendor}
+	for i := uint32(0); i < count; i++ {
+		field, err := readString()
+		if err != nil {
+			return nil, nil, err
+		}
+		comment.fields = append(comment.fields, field)
+	}
+	return comment, data, nil
+}
@@ -8,6 +8,8 @@ set -e
 # Configuration
 OPTICAL_DEVICE="/dev/sr0"
 MOVIES_DIR="/mnt/nas/media/movies"
+# Names are cleaned by the ripper so movies are named like music
+MEDIA_RIPPER="${MEDIA_RIPPER:-media-ripper}"
 LOG_FILE="$HOME/.cache/media-ripper/simple-ripper.log"
 
 # Colors
@@ -55,6 +57,11 @@ check_requirements() {
         exit 1
     fi
     
+    if ! command -v "$MEDIA_RIPPER" >/dev/null 2>&1; then
+        error "media-ripper not found. Install it or set MEDIA_RIPPER to its path."
+        exit 1
+    fi
+    
     success "All requirements met"
 }
 
@@ -262,9 +269,10 @@ get_output_name() {
     done
 }
 
-# Clean filename
-clean_filename() {
-    echo "$1" | tr ' ' '_' | tr -cd '[:alnum:]_.-'
+# Path of a movie's file, named with the ripper's sanitisation policy and
+# kept within its length limits
+movie_path() {
+    "$MEDIA_RIPPER" sanitize -ext mkv "$MOVIES_DIR" "$1" "$1"
 }
 
 # Rip the disc
@@ -272,9 +280,13 @@ rip_disc() {
     local title="$1"
     local movie_name="$2"
     
-    local clean_name
-    clean_name=$(clean_filename "$movie_name")
-    local output_dir="$MOVIES_DIR/$clean_name"
+    local final_name
+    if ! final_name=$(movie_path "$movie_name"); then
+        error "Could not name the movie with $MEDIA_RIPPER" >&2
+        return 1
+    fi
+    local output_dir
+    output_dir=$(dirname "$final_name")
     
     echo "" >&2
     info "Rip settings:" >&2
@@ -301,8 +313,6 @@ rip_disc() {
         mkv_file=$(find "$output_dir" -name "*.mkv" -type f | head -n 1)
         
         if [ -n "$mkv_file" ] && [ -f "$mkv_file" ]; then
-            local final_name="$output_dir/${clean_name}.mkv"
-            
             if [ "$mkv_file" != "$final_name" ]; then
                 mv "$mkv_file" "$final_name"
             fi